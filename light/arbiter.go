@@ -0,0 +1,429 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// BlendMode defines how the values of several active sources are combined into the value of a single module.
+type BlendMode int
+
+const (
+	// BlendPriority uses the value of the source with the highest priority.
+	// If there are several sources with the same priority, the one that was updated last wins.
+	BlendPriority BlendMode = iota
+
+	// BlendHTP (highest takes precedence) uses the highest value of every DCS channel of all active sources, regardless of their priority.
+	// Like on a lighting console, the sources are merged: A dim red and a bright blue source result in a dim red and bright blue mix.
+	BlendHTP
+
+	// BlendLTP (latest takes precedence) uses the value of the source that was updated last, regardless of its priority.
+	BlendLTP
+)
+
+// ArbiterOptions contains the parameters of an Arbiter.
+type ArbiterOptions struct {
+	// The blend mode of every module.
+	// Modules without entry use BlendPriority.
+	Blend []BlendMode
+
+	// The duration of the transition that is used when the winning source changes because a source was released or timed out.
+	// Defaults to 1 second if zero, negative values disable the transition.
+	FallbackTransition time.Duration
+
+	// Called with errors that happen while falling back to another source, e.g. when a handle times out.
+	// Errors are ignored if this is nil.
+	ErrorHandler func(err error)
+}
+
+// Arbiter wraps a light device that is controlled by several sources at once.
+// Every source gets its own Handle, which itself implements the light interface.
+//
+// The values of all active sources are combined according to their priority and the blend mode of every module.
+// When a source releases its handle, the light falls back to the next source with a smooth transition.
+// If there is no active source left, the light keeps its last state.
+//
+// Use Close() to release all resources.
+type Arbiter struct {
+	light   Light
+	options ArbiterOptions
+
+	mutex    sync.Mutex
+	handles  map[*Handle]struct{}
+	sequence uint64               // Incremented with every update of a handle.
+	output   []emission.DCSVector // The last state that was successfully sent to the device. Nil if nothing was sent yet.
+
+	fadeCancel   context.CancelFunc   // Cancels the currently running fallback transition. Nil if there is none.
+	fadeDone     chan struct{}        // Closed when the currently running fallback transition is done.
+	fadeTarget   []emission.DCSVector // The target of the currently running fallback transition.
+	fadeRecorder *recordingLight      // Records the states that the currently running fallback transition has sent.
+}
+
+// NewArbiter returns an arbiter that wraps the given light device.
+func NewArbiter(l Light, options ArbiterOptions) *Arbiter {
+	if options.FallbackTransition == 0 {
+		options.FallbackTransition = time.Second
+	}
+
+	return &Arbiter{
+		light:   l,
+		options: options,
+		handles: make(map[*Handle]struct{}),
+	}
+}
+
+// Unwrap returns the wrapped light device.
+func (a *Arbiter) Unwrap() Light {
+	return a.light
+}
+
+// Acquire returns a new handle for a source with the given priority.
+// Higher values mean higher priority.
+//
+// If timeout is not zero, the handle is released automatically when it wasn't updated for the given duration.
+// A handle has no effect on the light until its SetColors method is called.
+func (a *Arbiter) Acquire(priority int, timeout time.Duration) *Handle {
+	h := &Handle{
+		arbiter:  a,
+		priority: priority,
+		timeout:  timeout,
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.handles[h] = struct{}{}
+
+	return h
+}
+
+// Close releases all handles and stops any running transition.
+// This will not change the state of the light device.
+func (a *Arbiter) Close() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for h := range a.handles {
+		h.stopTimer()
+		delete(a.handles, h)
+	}
+	a.stopFade()
+}
+
+// stopFade cancels the running fallback transition and waits until it is done.
+// Afterwards, the output is the last state that the transition has sent.
+// The mutex has to be held by the caller.
+func (a *Arbiter) stopFade() {
+	if a.fadeCancel != nil {
+		a.fadeCancel()
+		<-a.fadeDone
+		if a.fadeRecorder.sent != nil {
+			a.output = a.fadeRecorder.sent
+		}
+		a.fadeCancel, a.fadeDone, a.fadeTarget, a.fadeRecorder = nil, nil, nil, nil
+	}
+}
+
+// reportError passes the error to the error handler, if there is one.
+func (a *Arbiter) reportError(err error) {
+	if a.options.ErrorHandler != nil {
+		a.options.ErrorHandler(err)
+	}
+}
+
+// blendMode returns the blend mode of the module with the given index.
+func (a *Arbiter) blendMode(module int) BlendMode {
+	if module < len(a.options.Blend) {
+		return a.options.Blend[module]
+	}
+	return BlendPriority
+}
+
+// target returns the combined state of all active handles.
+// This returns nil if there is no active handle.
+// The mutex has to be held by the caller.
+func (a *Arbiter) target() []emission.DCSVector {
+	colorProfiles := a.light.ColorProfiles()
+
+	var result []emission.DCSVector
+	for module, colorProfile := range colorProfiles {
+		if a.blendMode(module) == BlendHTP {
+			var merged emission.DCSVector
+			for h := range a.handles {
+				if h.values == nil {
+					continue
+				}
+				if merged == nil {
+					merged = make(emission.DCSVector, colorProfile.Channels())
+				}
+				for i, channel := range h.values[module] {
+					if channel > merged[i] {
+						merged[i] = channel
+					}
+				}
+			}
+
+			if merged == nil {
+				return nil
+			}
+			result = append(result, merged)
+			continue
+		}
+
+		var winner *Handle
+		for h := range a.handles {
+			if h.values == nil {
+				continue
+			}
+
+			switch a.blendMode(module) {
+			case BlendLTP:
+				if winner == nil || h.sequence > winner.sequence {
+					winner = h
+				}
+			default:
+				if winner == nil || h.priority > winner.priority || h.priority == winner.priority && h.sequence > winner.sequence {
+					winner = h
+				}
+			}
+		}
+
+		if winner == nil {
+			return nil
+		}
+		result = append(result, winner.values[module])
+	}
+
+	return result
+}
+
+// update sends the combined state of all active handles to the device.
+// If transition is true, the change is done with a smooth fallback transition.
+// The mutex has to be held by the caller.
+func (a *Arbiter) update(transition bool) error {
+	target := a.target()
+	if target == nil {
+		// There is no active source, keep the last state.
+		return nil
+	}
+
+	// Compare with the state that the device will have once a running transition is done.
+	destination := a.output
+	if a.fadeCancel != nil {
+		destination = a.fadeTarget
+	}
+	if dcsVectorsEqual(target, destination) {
+		// Nothing changed, e.g. because a source with lower priority was updated.
+		return nil
+	}
+
+	// Continue from the state the transition has reached.
+	a.stopFade()
+
+	values := dcsValues(target)
+
+	if !transition || a.options.FallbackTransition < 0 || a.output == nil {
+		if err := a.light.SetColors(values...); err != nil {
+			return err
+		}
+		a.output = target
+		return nil
+	}
+
+	from := dcsValues(a.output)
+	recorder := &recordingLight{Light: a.light}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	a.fadeCancel, a.fadeDone, a.fadeTarget, a.fadeRecorder = cancel, done, target, recorder
+
+	go func() {
+		defer close(done)
+		if err := Fade(ctx, recorder, from, values, a.options.FallbackTransition, FadeOptions{}); err != nil && !errors.Is(err, context.Canceled) {
+			a.reportError(fmt.Errorf("fallback transition failed: %w", err))
+		}
+	}()
+
+	return nil
+}
+
+// dcsValues returns the vectors as list of emission values.
+func dcsValues(vectors []emission.DCSVector) []emission.Value {
+	values := make([]emission.Value, 0, len(vectors))
+	for _, vector := range vectors {
+		values = append(values, vector)
+	}
+
+	return values
+}
+
+// dcsVectorsEqual returns whether both lists of vectors are exactly equal.
+func dcsVectorsEqual(a, b []emission.DCSVector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// release removes the handle and falls back to the remaining sources.
+func (a *Arbiter) release(h *Handle) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, ok := a.handles[h]; !ok {
+		return
+	}
+
+	h.stopTimer()
+	delete(a.handles, h)
+
+	if h.values != nil {
+		if err := a.update(true); err != nil {
+			a.reportError(fmt.Errorf("failed to fall back to the remaining sources: %w", err))
+		}
+	}
+}
+
+// recordingLight wraps a light device and records the last emission values that were successfully set.
+type recordingLight struct {
+	Light
+
+	sent []emission.DCSVector // The last successfully set values of all modules.
+}
+
+// SetColors sets the emission values of the wrapped light device, and records them.
+func (r *recordingLight) SetColors(emissionValues ...emission.Value) error {
+	if err := r.Light.SetColors(emissionValues...); err != nil {
+		return err
+	}
+
+	colorProfiles := r.Light.ColorProfiles()
+	sent := make([]emission.DCSVector, 0, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		if i < len(emissionValues) {
+			sent = append(sent, emissionValues[i].IntoDCS(colorProfile))
+		} else {
+			sent = append(sent, make(emission.DCSVector, colorProfile.Channels()))
+		}
+	}
+	r.sent = sent
+
+	return nil
+}
+
+// Handle is the connection of a single source to an arbiter.
+// It implements the light interface, so it can be used everywhere a light device is expected.
+type Handle struct {
+	arbiter  *Arbiter
+	priority int
+	timeout  time.Duration
+
+	// The following fields are guarded by the mutex of the arbiter.
+	values   []emission.DCSVector // The last set state of this source. Nil if it wasn't set yet.
+	sequence uint64               // The sequence number of the last update.
+	timer    *time.Timer          // Releases the handle after the timeout.
+}
+
+// Check implementation of Light.
+var _ Light = &Handle{}
+
+// Priority returns the priority of the handle.
+func (h *Handle) Priority() int {
+	return h.priority
+}
+
+// Release removes the source from the arbiter.
+// The light falls back to the next active source.
+// Calling Release more than once has no effect.
+func (h *Handle) Release() {
+	h.arbiter.release(h)
+}
+
+// stopTimer stops the timeout timer.
+// The mutex of the arbiter has to be held by the caller.
+func (h *Handle) stopTimer() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+}
+
+// SetColors sets the emission values of all the modules of this source.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+//
+// The values are only sent to the device if this source wins against the other active sources.
+// An error is returned if the handle was released.
+func (h *Handle) SetColors(emissionValues ...emission.Value) error {
+	a := h.arbiter
+
+	colorProfiles := a.light.ColorProfiles()
+	if len(emissionValues) > len(colorProfiles) {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), len(colorProfiles))
+	}
+
+	// Transform everything into DCS, including the implicitly turned off modules.
+	vectors := make([]emission.DCSVector, 0, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		if i < len(emissionValues) {
+			vectors = append(vectors, emissionValues[i].IntoDCS(colorProfile))
+		} else {
+			vectors = append(vectors, make(emission.DCSVector, colorProfile.Channels()))
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, ok := a.handles[h]; !ok {
+		return fmt.Errorf("handle with priority %d was already released", h.priority)
+	}
+
+	a.sequence++
+	h.values, h.sequence = vectors, a.sequence
+
+	if h.timeout > 0 {
+		h.stopTimer()
+		h.timer = time.AfterFunc(h.timeout, h.Release)
+	}
+
+	return a.update(false)
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This returns the state of the device, which may be set by another source.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (h *Handle) GetColors(emissionValues ...emission.ValueReceiver) error {
+	return h.arbiter.light.GetColors(emissionValues...)
+}
+
+// Modules returns the number of modules.
+func (h *Handle) Modules() int {
+	return h.arbiter.light.Modules()
+}
+
+// ColorProfiles returns the color profiles of every module in this device.
+func (h *Handle) ColorProfiles() []emission.ColorProfile {
+	return h.arbiter.light.ColorProfiles()
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func TestArbiter(t *testing.T) {
+	l := newVirtualLight(t, 2)
+
+	arbiter := light.NewArbiter(l, light.ArbiterOptions{
+		Blend:              []light.BlendMode{light.BlendPriority, light.BlendHTP},
+		FallbackTransition: 20 * time.Millisecond,
+	})
+	defer arbiter.Close()
+
+	low, high := arbiter.Acquire(0, 0), arbiter.Acquire(10, 30*time.Millisecond)
+
+	dim, bright := emission.DCSVector{0.1, 0.1, 0.1}, emission.DCSVector{1, 1, 1}
+	if err := low.SetColors(dim, bright); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := high.SetColors(bright, dim); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	// The first module uses the source with the highest priority, the second module the brightest source.
+	vectors := l.Vectors()
+	if vectors[0][0] != 1 || vectors[1][0] != 1 {
+		t.Errorf("Light is %v, want %v", vectors, []emission.DCSVector{bright, bright})
+	}
+
+	// Updates of the losing source don't change the first module.
+	if err := low.SetColors(dim, dim); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if vectors := l.Vectors(); vectors[0][0] != 1 || vectors[1][0] != 0.1 {
+		t.Errorf("Light is %v, want %v", vectors, []emission.DCSVector{bright, dim})
+	}
+
+	// After the high priority source timed out, the light falls back to the low priority source.
+	waitFor(t, "the light to fall back to the low priority source", func() bool {
+		return math.Abs(l.Vectors()[0][0]-0.1) <= 0.001
+	})
+	if err := high.SetColors(bright); err == nil {
+		t.Errorf("SetColors() of a released handle returned no error")
+	}
+}
+
+func TestArbiterPriority(t *testing.T) {
+	l := newVirtualLight(t, 1)
+
+	arbiter := light.NewArbiter(l, light.ArbiterOptions{FallbackTransition: -1})
+	defer arbiter.Close()
+
+	low, first, second := arbiter.Acquire(0, 0), arbiter.Acquire(5, 0), arbiter.Acquire(5, 0)
+
+	if err := first.SetColors(emission.DCSVector{0.2, 0.2, 0.2}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := second.SetColors(emission.DCSVector{0.4, 0.4, 0.4}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := low.SetColors(emission.DCSVector{1, 1, 1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	// Of the sources with the same priority, the latest update wins.
+	if !vectorIs(l, emission.DCSVector{0.4, 0.4, 0.4})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{0.4, 0.4, 0.4})
+	}
+	if err := first.SetColors(emission.DCSVector{0.3, 0.3, 0.3}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if !vectorIs(l, emission.DCSVector{0.3, 0.3, 0.3})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{0.3, 0.3, 0.3})
+	}
+
+	// Without transition, the light falls back immediately.
+	first.Release()
+	if !vectorIs(l, emission.DCSVector{0.4, 0.4, 0.4})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{0.4, 0.4, 0.4})
+	}
+	second.Release()
+	if !vectorIs(l, emission.DCSVector{1, 1, 1})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{1, 1, 1})
+	}
+
+	// Releasing the last source keeps the last state.
+	low.Release()
+	if !vectorIs(l, emission.DCSVector{1, 1, 1})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{1, 1, 1})
+	}
+}
+
+func TestArbiterHTP(t *testing.T) {
+	l := newVirtualLight(t, 1)
+
+	arbiter := light.NewArbiter(l, light.ArbiterOptions{Blend: []light.BlendMode{light.BlendHTP}, FallbackTransition: -1})
+	defer arbiter.Close()
+
+	red, blue := arbiter.Acquire(10, 0), arbiter.Acquire(0, 0)
+
+	// Every channel uses the highest value of all sources, regardless of the priority.
+	if err := red.SetColors(emission.DCSVector{1, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := blue.SetColors(emission.DCSVector{0, 0, 0.5}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if !vectorIs(l, emission.DCSVector{1, 0, 0.5})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{1, 0, 0.5})
+	}
+
+	// Ties result in the same value.
+	if err := blue.SetColors(emission.DCSVector{1, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if !vectorIs(l, emission.DCSVector{1, 0, 0})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{1, 0, 0})
+	}
+
+	// Released sources don't contribute anymore.
+	if err := blue.SetColors(emission.DCSVector{0, 0.5, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	red.Release()
+	if !vectorIs(l, emission.DCSVector{0, 0.5, 0})() {
+		t.Errorf("Light is %v, want %v", l.Vectors()[0], emission.DCSVector{0, 0.5, 0})
+	}
+}
+
+func TestArbiterFallbackCancel(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{History: true}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	arbiter := light.NewArbiter(l, light.ArbiterOptions{FallbackTransition: 400 * time.Millisecond})
+	defer arbiter.Close()
+
+	low, middle, high := arbiter.Acquire(0, 0), arbiter.Acquire(1, 0), arbiter.Acquire(2, 0)
+	for _, step := range []struct {
+		handle *light.Handle
+		value  emission.DCSVector
+	}{{low, emission.DCSVector{0, 0, 0}}, {middle, emission.DCSVector{0.5, 0.5, 0.5}}, {high, emission.DCSVector{1, 1, 1}}} {
+		if err := step.handle.SetColors(step.value); err != nil {
+			t.Fatalf("SetColors() failed: %v", err)
+		}
+	}
+
+	// Release the middle source while the light falls back to it.
+	l.ClearHistory()
+	high.Release()
+	time.Sleep(100 * time.Millisecond)
+	middle.Release()
+	waitFor(t, "the light to fall back to the low priority source", vectorIs(l, emission.DCSVector{0, 0, 0}))
+
+	// The second transition starts where the first one was cancelled.
+	history := l.History()
+	if len(history) == 0 || history[0].Vectors[0][0] < 0.75 {
+		t.Fatalf("The transition didn't start at the state of the high priority source: %v", history)
+	}
+	for i := 1; i < len(history); i++ {
+		if step := math.Abs(history[i].Vectors[0][0] - history[i-1].Vectors[0][0]); step > 0.25 {
+			t.Errorf("Light jumped from %v to %v", history[i-1].Vectors[0], history[i].Vectors[0])
+		}
+	}
+}
+
+// failingLight wraps a light and fails to set colors once fail is set.
+type failingLight struct {
+	light.Light
+
+	mutex sync.Mutex
+	fail  bool
+}
+
+func (f *failingLight) SetColors(emissionValues ...emission.Value) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.fail {
+		return fmt.Errorf("device unreachable")
+	}
+	return f.Light.SetColors(emissionValues...)
+}
+
+func TestArbiterErrorHandler(t *testing.T) {
+	l := &failingLight{Light: newVirtualLight(t, 1)}
+
+	errs := make(chan error, 100)
+	arbiter := light.NewArbiter(l, light.ArbiterOptions{
+		FallbackTransition: 20 * time.Millisecond,
+		ErrorHandler:       func(err error) { errs <- err },
+	})
+	defer arbiter.Close()
+
+	low, high := arbiter.Acquire(0, 0), arbiter.Acquire(1, 0)
+	if err := low.SetColors(emission.DCSVector{0, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := high.SetColors(emission.DCSVector{1, 1, 1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	// Errors of the fallback transition are passed to the error handler.
+	l.mutex.Lock()
+	l.fail = true
+	l.mutex.Unlock()
+	high.Release()
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the error of the fallback transition")
+	}
+}
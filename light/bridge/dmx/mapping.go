@@ -0,0 +1,314 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/dmxnet"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Mode defines how the DMX channels of a module are interpreted.
+type Mode int
+
+// Supported modes.
+// The channels of every module follow each other, the modules of a mapping are consecutive.
+const (
+	ModeDCS Mode = iota // One channel per DCS channel of the module.
+	ModeRGB             // Red, green and blue channel in the sRGB color space.
+	ModeCCT             // Dimmer and correlated color temperature channel.
+	ModeXYY             // CIE 1931 x, y and luminance Y channel. Best used with 16 bit channels.
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeDCS:
+		return "DCS"
+	case ModeRGB:
+		return "RGB"
+	case ModeCCT:
+		return "CCT"
+	case ModeXYY:
+		return "xyY"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// channels returns the number of channels per module.
+func (m Mode) channels(colorProfile emission.ColorProfile) int {
+	switch m {
+	case ModeDCS:
+		return colorProfile.Channels()
+	case ModeCCT:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Mapping maps a range of DMX channels of a universe to modules of a light device.
+type Mapping struct {
+	Light    light.Light
+	Universe uint16 // The universe number, frames of Art-Net and E1.31 are both accepted.
+	Address  int    // The first DMX channel of the range, starting at 1.
+
+	FirstModule int // The index of the first module that is controlled.
+	Modules     int // The number of consecutive modules that are controlled. Defaults to all modules starting at FirstModule.
+
+	Mode Mode
+	Fine bool // Use 16 bit channels, the coarse channel comes first.
+
+	// The luminance in lumen at full level in ModeCCT and ModeXYY.
+	// Defaults to the luminance of the white point of each module.
+	Luminance float64
+
+	// The color temperature range in K that ModeCCT maps to.
+	// Defaults to the range of light.TemperatureRanger, or 2700 to 6500 K.
+	MinTemperature, MaxTemperature float64
+
+	// The maximum number of updates that are sent to the light device per second.
+	// Frames in between are coalesced, so the last look is always sent.
+	// Defaults to 20 if zero.
+	MaxFrameRate float64
+
+	// If true, the modules are turned off when the signal is lost.
+	// Otherwise the last look is held.
+	BlackoutOnLoss bool
+}
+
+// output is a light device that is shared by several mappings.
+type output struct {
+	light light.Light
+
+	mutex  sync.Mutex
+	values []emission.Value // The current values of all modules.
+}
+
+// newOutput returns an output with all modules turned off.
+func newOutput(l light.Light) *output {
+	colorProfiles := l.ColorProfiles()
+	values := make([]emission.Value, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		values[i] = make(emission.DCSVector, colorProfile.Channels())
+	}
+
+	return &output{light: l, values: values}
+}
+
+// set updates the values of the given modules and sends all values to the device.
+func (o *output) set(firstModule int, values []emission.Value) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	copy(o.values[firstModule:], values)
+	return o.light.SetColors(o.values...)
+}
+
+// mapping is the runtime state of a Mapping.
+type mapping struct {
+	Mapping
+	output        *output
+	colorProfiles []emission.ColorProfile // The color profiles of the mapped modules.
+	footprint     int                     // The number of DMX channels of all modules.
+	interval      time.Duration           // The minimum time between two updates.
+
+	mutex     sync.Mutex
+	pending   []emission.Value // Values that are waiting to be sent. Nil if there are none.
+	lastSlots []byte           // The slots of the last accepted frame.
+	lastFrame time.Time        // The time of the last accepted frame.
+	lost      bool             // The signal was lost since the last frame.
+
+	notify chan struct{}
+}
+
+// newMapping validates the mapping and applies its defaults.
+func newMapping(m Mapping, o *output) (*mapping, error) {
+	if m.Light == nil {
+		return nil, fmt.Errorf("mapping has no light device")
+	}
+	colorProfiles := m.Light.ColorProfiles()
+	if m.FirstModule < 0 || m.FirstModule >= len(colorProfiles) {
+		return nil, fmt.Errorf("first module %d out of range, the device has %d modules", m.FirstModule, len(colorProfiles))
+	}
+	if m.Modules <= 0 {
+		m.Modules = len(colorProfiles) - m.FirstModule
+	}
+	if m.FirstModule+m.Modules > len(colorProfiles) {
+		return nil, fmt.Errorf("modules %d to %d out of range, the device has %d modules", m.FirstModule, m.FirstModule+m.Modules-1, len(colorProfiles))
+	}
+	if m.Mode < ModeDCS || m.Mode > ModeXYY {
+		return nil, fmt.Errorf("unsupported mode %v", m.Mode)
+	}
+	if m.MinTemperature <= 0 || m.MaxTemperature <= 0 {
+		m.MinTemperature, m.MaxTemperature = 2700, 6500
+		if ranger, ok := m.Light.(light.TemperatureRanger); ok {
+			if min, max, ok := ranger.TemperatureRange(); ok && min > 0 && max >= min {
+				m.MinTemperature, m.MaxTemperature = min, max
+			}
+		}
+	}
+	if m.MaxFrameRate <= 0 {
+		m.MaxFrameRate = 20
+	}
+
+	mp := &mapping{
+		Mapping:       m,
+		output:        o,
+		colorProfiles: colorProfiles[m.FirstModule : m.FirstModule+m.Modules],
+		interval:      time.Duration(float64(time.Second) / m.MaxFrameRate),
+		notify:        make(chan struct{}, 1),
+	}
+	for _, colorProfile := range mp.colorProfiles {
+		mp.footprint += mp.channelBytes() * m.Mode.channels(colorProfile)
+	}
+	if m.Address < 1 || m.Address+mp.footprint-1 > dmxnet.Slots {
+		return nil, fmt.Errorf("channels %d to %d out of range", m.Address, m.Address+mp.footprint-1)
+	}
+
+	return mp, nil
+}
+
+// channelBytes returns the number of DMX slots per channel.
+func (m *mapping) channelBytes() int {
+	if m.Fine {
+		return 2
+	}
+	return 1
+}
+
+// handleFrame queues an update if the mapped slots of the frame changed.
+func (m *mapping) handleFrame(data []byte, now time.Time) {
+	slots := make([]byte, m.footprint)
+	if m.Address-1 < len(data) {
+		copy(slots, data[m.Address-1:])
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastFrame = now
+	if !m.lost && m.lastSlots != nil && string(slots) == string(m.lastSlots) {
+		return
+	}
+	m.lastSlots, m.lost = slots, false
+
+	m.queue(m.values(slots))
+}
+
+// handleLoss is called when the signal of the universe was lost.
+// The mutex must be held.
+func (m *mapping) handleLoss() {
+	if m.lost {
+		return
+	}
+	m.lost = true
+
+	if m.BlackoutOnLoss {
+		values := make([]emission.Value, len(m.colorProfiles))
+		for i, colorProfile := range m.colorProfiles {
+			values[i] = make(emission.DCSVector, colorProfile.Channels())
+		}
+		m.queue(values)
+	}
+}
+
+// queue replaces the pending values and notifies the worker.
+// The mutex must be held.
+func (m *mapping) queue(values []emission.Value) {
+	m.pending = values
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// values decodes the slots into emission values of all mapped modules.
+func (m *mapping) values(slots []byte) []emission.Value {
+	values := make([]emission.Value, len(m.colorProfiles))
+
+	offset := 0
+	level := func() float64 {
+		var l float64
+		if m.Fine {
+			l = float64(uint16(slots[offset])<<8|uint16(slots[offset+1])) / 65535
+		} else {
+			l = float64(slots[offset]) / 255
+		}
+		offset += m.channelBytes()
+		return l
+	}
+
+	for i, colorProfile := range m.colorProfiles {
+		luminance := m.Luminance
+		if luminance <= 0 {
+			luminance = colorProfile.WhitePoint().Y
+		}
+
+		switch m.Mode {
+		case ModeDCS:
+			v := make(emission.DCSVector, colorProfile.Channels())
+			for j := range v {
+				v[j] = level()
+			}
+			values[i] = v
+
+		case ModeRGB:
+			values[i] = emission.StandardRGB{R: level(), G: level(), B: level()}
+
+		case ModeCCT:
+			dimmer, cct := level(), level()
+			values[i] = emission.BlackBodyFixed{
+				Temperature: m.MinTemperature + cct*(m.MaxTemperature-m.MinTemperature),
+				Luminance:   dimmer * luminance,
+			}
+
+		case ModeXYY:
+			x, y, lum := level(), level(), level()
+			if y <= 0 {
+				values[i] = make(emission.DCSVector, colorProfile.Channels())
+				continue
+			}
+			values[i] = emission.CIE1931xyYAbs{X: x, Y: y, LuminanceY: lum * luminance}
+		}
+	}
+
+	return values
+}
+
+// run sends pending values to the light device, at most with the maximum frame rate.
+func (m *mapping) run(stop <-chan struct{}, errorHandler func(m Mapping, err error)) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.notify:
+		}
+
+		m.mutex.Lock()
+		values := m.pending
+		m.pending = nil
+		m.mutex.Unlock()
+		if values == nil {
+			continue
+		}
+
+		if err := m.output.set(m.FirstModule, values); err != nil && errorHandler != nil {
+			errorHandler(m.Mapping, err)
+		}
+
+		// Limit the frame rate, updates in between are coalesced.
+		select {
+		case <-stop:
+			return
+		case <-time.After(m.interval):
+		}
+	}
+}
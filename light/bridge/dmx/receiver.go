@@ -0,0 +1,283 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package dmx receives DMX512 universes via Art-Net or E1.31 (sACN) and maps their channels to light devices.
+// This lets lighting desks cue light devices like stage fixtures.
+package dmx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/dmxnet"
+)
+
+// Options contains the parameters of a Receiver.
+type Options struct {
+	// The time without frames after which the signal of a universe is considered lost.
+	// Defaults to 2.5 seconds if zero, as defined by E1.31.
+	LossTimeout time.Duration
+
+	// Called with errors that happen while sending values to light devices.
+	// Errors are ignored if this is nil.
+	ErrorHandler func(m Mapping, err error)
+}
+
+// sourceKey identifies a stream of frames of one source.
+type sourceKey struct {
+	protocol dmxnet.Protocol
+	universe uint16
+	cid      [16]byte
+}
+
+// universeState tracks the active E1.31 priority of a universe.
+type universeState struct {
+	priority uint8
+	lastSeen time.Time
+}
+
+// Receiver maps DMX512 frames to light devices.
+//
+// Frames of several E1.31 sources are merged by priority, the source with the highest priority wins.
+// Out of order frames are discarded.
+type Receiver struct {
+	options  Options
+	mappings []*mapping
+
+	mutex     sync.Mutex
+	sequences map[sourceKey]uint8
+	universes map[uint16]*universeState
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewReceiver returns a receiver with the given mappings.
+// Several mappings can use the same light device, as long as they control different modules.
+//
+// Use Close() to stop the receiver.
+func NewReceiver(options Options, mappings ...Mapping) (*Receiver, error) {
+	if options.LossTimeout <= 0 {
+		options.LossTimeout = 2500 * time.Millisecond
+	}
+
+	r := &Receiver{
+		options:   options,
+		sequences: make(map[sourceKey]uint8),
+		universes: make(map[uint16]*universeState),
+		stop:      make(chan struct{}),
+	}
+
+	outputs := make(map[light.Light]*output)
+	for i, m := range mappings {
+		o, ok := outputs[m.Light]
+		if !ok && m.Light != nil {
+			o = newOutput(m.Light)
+			outputs[m.Light] = o
+		}
+
+		mp, err := newMapping(m, o)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping %d: %w", i, err)
+		}
+		r.mappings = append(r.mappings, mp)
+	}
+
+	for _, mp := range r.mappings {
+		r.wg.Add(1)
+		go func(mp *mapping) {
+			defer r.wg.Done()
+			mp.run(r.stop, options.ErrorHandler)
+		}(mp)
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.watchLoss()
+	}()
+
+	return r, nil
+}
+
+// Universes returns the numbers of all mapped universes.
+func (r *Receiver) Universes() []uint16 {
+	var universes []uint16
+	seen := make(map[uint16]struct{})
+	for _, m := range r.mappings {
+		if _, ok := seen[m.Universe]; !ok {
+			seen[m.Universe] = struct{}{}
+			universes = append(universes, m.Universe)
+		}
+	}
+
+	return universes
+}
+
+// HandleFrame applies a DMX512 frame to all mappings of its universe.
+func (r *Receiver) HandleFrame(f dmxnet.Frame) {
+	now := time.Now()
+
+	if !r.accept(f, now) {
+		return
+	}
+
+	for _, m := range r.mappings {
+		if m.Universe != f.Universe {
+			continue
+		}
+		if f.Terminated {
+			m.mutex.Lock()
+			m.handleLoss()
+			m.mutex.Unlock()
+			continue
+		}
+		m.handleFrame(f.Data, now)
+	}
+}
+
+// accept returns whether the frame is in order and has the highest priority.
+func (r *Receiver) accept(f dmxnet.Frame, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Art-Net uses a sequence number of 0 to disable sequence checking.
+	if f.Protocol == dmxnet.ProtocolE131 || f.Sequence != 0 {
+		key := sourceKey{protocol: f.Protocol, universe: f.Universe, cid: f.CID}
+		if last, ok := r.sequences[key]; ok && !dmxnet.SequenceNewer(last, f.Sequence) {
+			return false
+		}
+		r.sequences[key] = f.Sequence
+	}
+
+	if f.Protocol == dmxnet.ProtocolE131 {
+		u, ok := r.universes[f.Universe]
+		if !ok {
+			u = &universeState{}
+			r.universes[f.Universe] = u
+		}
+		if f.Priority < u.priority && now.Sub(u.lastSeen) < r.options.LossTimeout {
+			return false
+		}
+		if f.Terminated {
+			delete(r.universes, f.Universe)
+			return true
+		}
+		u.priority, u.lastSeen = f.Priority, now
+	}
+
+	return true
+}
+
+// watchLoss regularly checks all mappings for signal loss.
+func (r *Receiver) watchLoss() {
+	ticker := time.NewTicker(r.options.LossTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			for _, m := range r.mappings {
+				m.mutex.Lock()
+				if !m.lastFrame.IsZero() && now.Sub(m.lastFrame) >= r.options.LossTimeout {
+					m.handleLoss()
+				}
+				m.mutex.Unlock()
+			}
+		}
+	}
+}
+
+// Serve reads Art-Net and E1.31 packets from conn until the context is cancelled or reading fails.
+// The connection is closed when Serve returns.
+//
+// Serve can be called for several connections at the same time.
+func (r *Receiver) Serve(ctx context.Context, conn net.PacketConn) error {
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		f, err := dmxnet.Decode(buf[:n])
+		if err != nil {
+			continue // Ignore any other traffic on the port.
+		}
+		r.HandleFrame(f)
+	}
+}
+
+// ListenAndServe listens for Art-Net packets on the default port, and for E1.31 packets on the multicast groups of all mapped universes.
+// It blocks until the context is cancelled or any listener fails.
+func (r *Receiver) ListenAndServe(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var conns []net.PacketConn
+	artNetConn, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", dmxnet.ArtNetPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for Art-Net packets: %w", err)
+	}
+	conns = append(conns, artNetConn)
+
+	for _, universe := range r.Universes() {
+		if universe < 1 || universe > 63999 {
+			continue // Not a valid E1.31 universe.
+		}
+		conn, err := net.ListenMulticastUDP("udp4", nil, dmxnet.E131MulticastAddress(universe))
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return fmt.Errorf("failed to listen for E1.31 packets of universe %d: %w", universe, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	errs := make(chan error, len(conns))
+	for _, conn := range conns {
+		go func(conn net.PacketConn) {
+			errs <- r.Serve(ctx, conn)
+		}(conn)
+	}
+
+	// Return the first error, and wait for all other listeners to stop.
+	err = <-errs
+	cancel()
+	for i := 1; i < len(conns); i++ {
+		<-errs
+	}
+	return err
+}
+
+// Close stops the receiver.
+// Values that weren't sent yet are discarded.
+func (r *Receiver) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+}
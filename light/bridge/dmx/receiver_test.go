@@ -0,0 +1,218 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/dmxnet"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// newTestLight returns a virtual light with the given number of sRGB modules.
+func newTestLight(t *testing.T, modules int, history bool) *virtual.Light {
+	colorProfiles := make([]emission.ColorProfile, modules)
+	for i := range colorProfiles {
+		colorProfiles[i] = virtual.DefaultColorProfile
+	}
+
+	l, err := virtual.NewLight(virtual.Options{History: history}, colorProfiles...)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	return l
+}
+
+// waitForVectors waits until the light has DCS vectors that are accepted by want.
+func waitForVectors(t *testing.T, l *virtual.Light, want func([]emission.DCSVector) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if want(l.Vectors()) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Light has DCS vectors %v, which are not as expected", l.Vectors())
+}
+
+// vectorNear returns whether all channels of a and b are almost equal.
+func vectorNear(a, b emission.DCSVector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 0.01 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestModes(t *testing.T) {
+	l := newTestLight(t, 4, false)
+
+	r, err := NewReceiver(Options{},
+		Mapping{Light: l, Universe: 1, Address: 1, FirstModule: 0, Modules: 1, Mode: ModeDCS},
+		Mapping{Light: l, Universe: 1, Address: 4, FirstModule: 1, Modules: 1, Mode: ModeRGB},
+		Mapping{Light: l, Universe: 1, Address: 7, FirstModule: 2, Modules: 1, Mode: ModeCCT, MinTemperature: 2000, MaxTemperature: 10000},
+		Mapping{Light: l, Universe: 1, Address: 9, FirstModule: 3, Modules: 1, Mode: ModeXYY, Fine: true, Luminance: 100},
+	)
+	if err != nil {
+		t.Fatalf("NewReceiver() failed: %v", err)
+	}
+	defer r.Close()
+
+	d65 := emission.StandardIlluminantD65.CIE1931xyYRel()
+	data := []byte{
+		255, 128, 0, // DCS.
+		0, 255, 0, // RGB.
+		255, 0, // CCT.
+		byte(uint16(d65.X*65535) >> 8), byte(uint16(d65.X * 65535)), byte(uint16(d65.Y*65535) >> 8), byte(uint16(d65.Y * 65535)), 255, 255, // xyY.
+	}
+	r.HandleFrame(dmxnet.Frame{Protocol: dmxnet.ProtocolArtNet, Universe: 1, Data: data})
+
+	waitForVectors(t, l, func(vectors []emission.DCSVector) bool {
+		return vectorNear(vectors[0], emission.DCSVector{1, 128.0 / 255, 0}) &&
+			vectorNear(vectors[1], emission.DCSVector{0, 1, 0}) &&
+			vectors[2][0] > vectors[2][2] && // 2000 K is reddish.
+			vectors[3][0] > 0 && math.Abs(vectors[3][0]-vectors[3][2]) < 0.01 // D65 is white.
+	})
+
+	// The xyY mapping uses the given luminance.
+	var dcs0, dcs1, dcs2 emission.DCSVector
+	var xyY emission.CIE1931xyYAbs
+	if err := l.GetColors(&dcs0, &dcs1, &dcs2, &xyY); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+	if math.Abs(xyY.LuminanceY-100) > 1 {
+		t.Errorf("Module has a luminance of %v lm, want 100 lm", xyY.LuminanceY)
+	}
+}
+
+func TestInvalidMappings(t *testing.T) {
+	l := newTestLight(t, 2, false)
+
+	tests := []Mapping{
+		{Universe: 1, Address: 1},
+		{Light: l, Universe: 1, Address: 0},
+		{Light: l, Universe: 1, Address: 510, Mode: ModeRGB},
+		{Light: l, Universe: 1, Address: 1, FirstModule: 2},
+		{Light: l, Universe: 1, Address: 1, FirstModule: 1, Modules: 2},
+	}
+
+	for _, m := range tests {
+		if r, err := NewReceiver(Options{}, m); err == nil {
+			r.Close()
+			t.Errorf("NewReceiver() with mapping %+v succeeded, want error", m)
+		}
+	}
+}
+
+func TestFrameRateLimit(t *testing.T) {
+	l := newTestLight(t, 1, true)
+
+	r, err := NewReceiver(Options{}, Mapping{Light: l, Universe: 1, Address: 1, Mode: ModeDCS, MaxFrameRate: 5})
+	if err != nil {
+		t.Fatalf("NewReceiver() failed: %v", err)
+	}
+	defer r.Close()
+
+	for i := 1; i <= 50; i++ {
+		r.HandleFrame(dmxnet.Frame{Protocol: dmxnet.ProtocolArtNet, Universe: 1, Data: []byte{byte(i), 0, 0}})
+	}
+
+	// The last frame is sent eventually.
+	waitForVectors(t, l, func(vectors []emission.DCSVector) bool {
+		return vectorNear(vectors[0], emission.DCSVector{50.0 / 255, 0, 0})
+	})
+	if n := len(l.History()); n > 2 {
+		t.Errorf("Light got %d updates, want at most 2", n)
+	}
+}
+
+func TestSignalLoss(t *testing.T) {
+	held, blackedOut := newTestLight(t, 1, false), newTestLight(t, 1, false)
+
+	r, err := NewReceiver(Options{LossTimeout: 50 * time.Millisecond},
+		Mapping{Light: held, Universe: 1, Address: 1, Mode: ModeDCS},
+		Mapping{Light: blackedOut, Universe: 1, Address: 1, Mode: ModeDCS, BlackoutOnLoss: true},
+	)
+	if err != nil {
+		t.Fatalf("NewReceiver() failed: %v", err)
+	}
+	defer r.Close()
+
+	r.HandleFrame(dmxnet.Frame{Protocol: dmxnet.ProtocolArtNet, Universe: 1, Data: []byte{255, 255, 255}})
+	waitForVectors(t, blackedOut, func(vectors []emission.DCSVector) bool {
+		return vectorNear(vectors[0], emission.DCSVector{1, 1, 1})
+	})
+
+	waitForVectors(t, blackedOut, func(vectors []emission.DCSVector) bool {
+		return vectorNear(vectors[0], emission.DCSVector{0, 0, 0})
+	})
+	if vectors := held.Vectors(); !vectorNear(vectors[0], emission.DCSVector{1, 1, 1}) {
+		t.Errorf("Light has DCS vector %v after signal loss, want last look %v", vectors[0], emission.DCSVector{1, 1, 1})
+	}
+}
+
+func TestE131Arbitration(t *testing.T) {
+	l := newTestLight(t, 1, false)
+
+	r, err := NewReceiver(Options{}, Mapping{Light: l, Universe: 7, Address: 1, Mode: ModeDCS, MaxFrameRate: 1000})
+	if err != nil {
+		t.Fatalf("NewReceiver() failed: %v", err)
+	}
+	defer r.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Serve(ctx, conn)
+
+	sender, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer sender.Close()
+
+	send := func(f dmxnet.Frame) {
+		t.Helper()
+		f.Protocol, f.Universe = dmxnet.ProtocolE131, 7
+		packet, err := f.Encode()
+		if err != nil {
+			t.Fatalf("Encode() failed: %v", err)
+		}
+		if _, err := sender.Write(packet); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	backup, main := [16]byte{1}, [16]byte{2}
+
+	send(dmxnet.Frame{CID: main, Priority: 150, Sequence: 10, Data: []byte{255, 0, 0}})
+	waitForVectors(t, l, func(vectors []emission.DCSVector) bool { return vectorNear(vectors[0], emission.DCSVector{1, 0, 0}) })
+
+	// Frames with lower priority and out of order frames are ignored.
+	send(dmxnet.Frame{CID: backup, Priority: 100, Sequence: 1, Data: []byte{0, 255, 0}})
+	send(dmxnet.Frame{CID: main, Priority: 150, Sequence: 9, Data: []byte{0, 0, 255}})
+	send(dmxnet.Frame{CID: main, Priority: 150, Sequence: 11, Data: []byte{255, 255, 0}})
+	waitForVectors(t, l, func(vectors []emission.DCSVector) bool { return vectorNear(vectors[0], emission.DCSVector{1, 1, 0}) })
+
+	// After the main source terminated its stream, the backup takes over.
+	send(dmxnet.Frame{CID: main, Priority: 150, Sequence: 12, Terminated: true})
+	send(dmxnet.Frame{CID: backup, Priority: 100, Sequence: 2, Data: []byte{0, 255, 0}})
+	waitForVectors(t, l, func(vectors []emission.DCSVector) bool { return vectorNear(vectors[0], emission.DCSVector{0, 1, 0}) })
+}
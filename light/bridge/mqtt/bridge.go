@@ -0,0 +1,573 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package mqtt publishes light devices to an MQTT broker, so they can be controlled by Home Assistant and other home automation systems.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Options contains the parameters of a Bridge.
+type Options struct {
+	Prefix          string // The topic prefix of all device topics. Defaults to "d3iot".
+	DiscoveryPrefix string // The topic prefix of Home Assistant discovery documents. Defaults to "homeassistant".
+	NodeID          string // Identifies this bridge in discovery topics and unique IDs. Defaults to "d3iot".
+
+	DisableDiscovery bool // Don't publish Home Assistant discovery documents.
+
+	// Called with errors that happen while handling commands.
+	// Errors are ignored if this is nil.
+	ErrorHandler func(name string, err error)
+}
+
+// withDefaults returns a copy of the options with all defaults applied.
+func (o Options) withDefaults() Options {
+	if o.Prefix == "" {
+		o.Prefix = "d3iot"
+	}
+	if o.DiscoveryPrefix == "" {
+		o.DiscoveryPrefix = "homeassistant"
+	}
+	if o.NodeID == "" {
+		o.NodeID = "d3iot"
+	}
+	return o
+}
+
+// availabilityTopic returns the topic that contains "online" or "offline".
+func (o Options) availabilityTopic() string {
+	return o.Prefix + "/status"
+}
+
+// Will returns the last will message that marks all devices of the bridge as unavailable.
+// Pass it to ClientOptions when dialing the broker.
+func (o Options) Will() *Will {
+	return &Will{Topic: o.withDefaults().availabilityTopic(), Payload: []byte("offline"), Retain: true}
+}
+
+// Supported color modes of Home Assistant.
+const (
+	colorModeBrightness = "brightness"
+	colorModeColorTemp  = "color_temp"
+	colorModeXY         = "xy"
+)
+
+// Limits of the color temperature in K, if the device doesn't specify a range.
+const (
+	defaultMinTemperature = 2000
+	defaultMaxTemperature = 6500
+)
+
+// xy is a chromaticity in the CIE 1931 xyY color space.
+type xy struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// state is the state of a device in the JSON schema of Home Assistant.
+type state struct {
+	State      string `json:"state"`                // "ON" or "OFF".
+	Brightness int    `json:"brightness"`           // In the range of [0, 255].
+	ColorMode  string `json:"color_mode,omitempty"` // The color mode that was used last.
+	ColorTemp  int    `json:"color_temp,omitempty"` // In mired.
+	Color      *xy    `json:"color,omitempty"`
+	Effect     string `json:"effect,omitempty"` // The running scene.
+}
+
+// command is a command in the JSON schema of Home Assistant.
+// All fields are optional.
+type command struct {
+	State      string   `json:"state"`
+	Brightness *int     `json:"brightness"`
+	ColorTemp  *int     `json:"color_temp"`
+	Color      *xy      `json:"color"`
+	Effect     string   `json:"effect"`
+	Transition *float64 `json:"transition"` // In seconds.
+}
+
+// device is a light device that is published by the bridge.
+type device struct {
+	bridge *Bridge
+	name   string
+	light  light.Light
+
+	colorModes       []string
+	minTemp, maxTemp float64 // Supported color temperature range in K.
+
+	mutex      sync.Mutex
+	state      state
+	fadeCancel context.CancelFunc // Cancels the currently running transition. Nil if there is none.
+	fadeDone   chan struct{}      // Closed when the currently running transition doesn't write to the device anymore.
+}
+
+// Bridge publishes light devices to an MQTT broker and accepts commands for them.
+//
+// For every device the following topics are used:
+//
+//	<prefix>/<name>/state                                 The state of the device, retained.
+//	<prefix>/<name>/set                                   Accepts commands.
+//	<prefix>/status                                       "online" or "offline", retained.
+//	<discovery prefix>/light/<node ID>/<name>/config      Home Assistant discovery document, retained.
+//
+// States and commands use the JSON schema of the Home Assistant MQTT light integration.
+// Devices with several modules are controlled as a single light, all modules get the same color.
+type Bridge struct {
+	client  Client
+	options Options
+
+	mutex   sync.Mutex
+	devices map[string]*device
+}
+
+// NewBridge returns a bridge that uses the given MQTT client.
+// The bridge marks itself as available, but doesn't close the client on Close().
+func NewBridge(client Client, options Options) (*Bridge, error) {
+	b := &Bridge{
+		client:  client,
+		options: options.withDefaults(),
+		devices: make(map[string]*device),
+	}
+
+	if err := client.Publish(b.options.availabilityTopic(), []byte("online"), true); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Add publishes the given light device under the given name and subscribes to its commands.
+// Names must be unique and must not contain slashes or MQTT wildcards.
+func (b *Bridge) Add(name string, l light.Light) error {
+	if name == "" || strings.ContainsAny(name, "/+#") {
+		return fmt.Errorf("invalid device name %q", name)
+	}
+
+	d := &device{
+		bridge:     b,
+		name:       name,
+		light:      l,
+		colorModes: supportedColorModes(l.ColorProfiles()),
+		minTemp:    defaultMinTemperature,
+		maxTemp:    defaultMaxTemperature,
+	}
+	if ranger, ok := l.(light.TemperatureRanger); ok {
+		if min, max, ok := ranger.TemperatureRange(); ok && min > 0 && max >= min {
+			d.minTemp, d.maxTemp = min, max
+		}
+	}
+
+	b.mutex.Lock()
+	if _, ok := b.devices[name]; ok {
+		b.mutex.Unlock()
+		return fmt.Errorf("device %q already exists", name)
+	}
+	b.devices[name] = d
+	b.mutex.Unlock()
+
+	d.state = d.queryState()
+
+	if err := b.register(d); err != nil {
+		b.mutex.Lock()
+		delete(b.devices, name)
+		b.mutex.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// register publishes the discovery document and the state of a new device, and subscribes to its commands.
+func (b *Bridge) register(d *device) error {
+	if !b.options.DisableDiscovery {
+		if err := b.publishDiscovery(d); err != nil {
+			return err
+		}
+	}
+	if err := b.publishState(d, d.state); err != nil {
+		return err
+	}
+
+	return b.client.Subscribe(b.topic(d.name, "set"), func(topic string, payload []byte) {
+		if err := b.handleCommand(d, payload); err != nil {
+			b.reportError(d.name, err)
+		}
+	})
+}
+
+// reportError passes the error to the error handler, if there is one.
+func (b *Bridge) reportError(name string, err error) {
+	if b.options.ErrorHandler != nil {
+		b.options.ErrorHandler(name, err)
+	}
+}
+
+// Close stops all running transitions and marks the bridge as unavailable.
+func (b *Bridge) Close() error {
+	b.mutex.Lock()
+	for _, d := range b.devices {
+		d.mutex.Lock()
+		d.stopFade()
+		d.mutex.Unlock()
+	}
+	b.mutex.Unlock()
+
+	return b.client.Publish(b.options.availabilityTopic(), []byte("offline"), true)
+}
+
+// topic returns the topic of a device with the given suffix.
+func (b *Bridge) topic(name, suffix string) string {
+	return b.options.Prefix + "/" + name + "/" + suffix
+}
+
+// publishState publishes the given state of a device as retained message.
+func (b *Bridge) publishState(d *device, s state) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	return b.client.Publish(b.topic(d.name, "state"), payload, true)
+}
+
+// publishDiscovery publishes the Home Assistant discovery document of a device.
+func (b *Bridge) publishDiscovery(d *device) error {
+	uniqueID := b.options.NodeID + "_" + d.name
+
+	deviceInfo := map[string]interface{}{
+		"identifiers": []string{uniqueID},
+		"name":        d.name,
+	}
+	if infoProvider, ok := d.light.(light.InfoProvider); ok {
+		if info, err := infoProvider.DeviceInfo(); err == nil {
+			if info.Vendor != "" {
+				deviceInfo["manufacturer"] = info.Vendor
+			}
+			if info.Model != "" {
+				deviceInfo["model"] = info.Model
+			}
+			if info.Firmware != "" {
+				deviceInfo["sw_version"] = info.Firmware
+			}
+			if info.MAC != "" {
+				deviceInfo["connections"] = [][]string{{"mac", info.MAC}}
+			}
+		}
+	}
+
+	config := map[string]interface{}{
+		"name":                  d.name,
+		"unique_id":             uniqueID,
+		"schema":                "json",
+		"state_topic":           b.topic(d.name, "state"),
+		"command_topic":         b.topic(d.name, "set"),
+		"availability_topic":    b.options.availabilityTopic(),
+		"brightness":            true,
+		"supported_color_modes": d.colorModes,
+		"device":                deviceInfo,
+	}
+	if containsString(d.colorModes, colorModeColorTemp) {
+		config["min_mireds"] = int(math.Floor(1e6 / d.maxTemp))
+		config["max_mireds"] = int(math.Ceil(1e6 / d.minTemp))
+	}
+	if sceneProvider, ok := d.light.(light.SceneProvider); ok {
+		if scenes := sceneProvider.Scenes(); len(scenes) > 0 {
+			config["effect"] = true
+			config["effect_list"] = scenes
+		}
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery document: %w", err)
+	}
+
+	topic := b.options.DiscoveryPrefix + "/light/" + b.options.NodeID + "/" + d.name + "/config"
+	return b.client.Publish(topic, payload, true)
+}
+
+// handleCommand applies a command to a device and publishes the resulting state.
+func (b *Bridge) handleCommand(d *device, payload []byte) error {
+	var c command
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return fmt.Errorf("failed to unmarshal command %q: %w", payload, err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.stopFade()
+
+	s, err := d.apply(c)
+	if err != nil {
+		return err
+	}
+	d.state = s
+
+	return b.publishState(d, s)
+}
+
+// apply sends the command to the device and returns the new state.
+// The device mutex must be held.
+func (d *device) apply(c command) (state, error) {
+	s := d.state
+
+	switch strings.ToUpper(c.State) {
+	case "", "ON":
+	case "OFF":
+		s.State, s.Effect = "OFF", ""
+		if switcher, ok := d.light.(light.Switcher); ok && c.Transition == nil {
+			return s, switcher.SetPower(false)
+		}
+		off := s
+		off.Brightness = 0
+		return s, d.send(d.values(off), c.Transition)
+	default:
+		return s, fmt.Errorf("unknown state %q", c.State)
+	}
+
+	if c.Effect != "" {
+		sceneProvider, ok := d.light.(light.SceneProvider)
+		if !ok {
+			return s, fmt.Errorf("device doesn't support effects")
+		}
+		s.State, s.Effect = "ON", c.Effect
+		return s, sceneProvider.SetScene(c.Effect)
+	}
+
+	// Resume a running scene when the device is only switched on.
+	if s.State == "OFF" && s.Effect != "" && c.Brightness == nil && c.ColorTemp == nil && c.Color == nil {
+		if switcher, ok := d.light.(light.Switcher); ok {
+			s.State = "ON"
+			return s, switcher.SetPower(true)
+		}
+	}
+
+	s.State, s.Effect = "ON", ""
+	if c.Brightness != nil {
+		s.Brightness = clampInt(*c.Brightness, 0, 255)
+	}
+	if c.ColorTemp != nil && containsString(d.colorModes, colorModeColorTemp) {
+		minMired, maxMired := int(math.Floor(1e6/d.maxTemp)), int(math.Ceil(1e6/d.minTemp))
+		s.ColorMode, s.ColorTemp, s.Color = colorModeColorTemp, clampInt(*c.ColorTemp, minMired, maxMired), nil
+	}
+	if c.Color != nil && containsString(d.colorModes, colorModeXY) {
+		s.ColorMode, s.Color = colorModeXY, &xy{X: c.Color.X, Y: c.Color.Y}
+	}
+
+	return s, d.send(d.values(s), c.Transition)
+}
+
+// values returns the emission values of all modules for the given state.
+func (d *device) values(s state) []emission.Value {
+	chromaticity := d.chromaticity(s)
+
+	colorProfiles := d.light.ColorProfiles()
+	values := make([]emission.Value, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		c := chromaticity
+		c.LuminanceY = float64(s.Brightness) / 255 * maxLuminance(colorProfile, chromaticity)
+		values[i] = c
+	}
+
+	return values
+}
+
+// chromaticity returns the chromaticity of the given state.
+func (d *device) chromaticity(s state) emission.CIE1931xyYAbs {
+	switch {
+	case s.ColorMode == colorModeXY && s.Color != nil:
+		return emission.CIE1931xyYAbs{X: s.Color.X, Y: s.Color.Y}
+	case s.ColorMode == colorModeColorTemp && s.ColorTemp > 0:
+		return emission.BlackBodyFixed{Temperature: 1e6 / float64(s.ColorTemp), Luminance: 1}.CIE1931xyYAbs()
+	}
+
+	// Fall back to the white point of the first module.
+	c := d.light.ColorProfiles()[0].WhitePoint().CIE1931xyYAbs()
+	c.LuminanceY = 0
+	return c
+}
+
+// send sets the emission values, either immediately or with a transition in the background.
+// The device mutex must be held.
+//
+// If the transition fails, the error is reported and the actual state of the device is published.
+func (d *device) send(values []emission.Value, transition *float64) error {
+	if transition == nil || *transition <= 0 {
+		return d.light.SetColors(values...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	d.fadeCancel, d.fadeDone = cancel, done
+	duration := time.Duration(*transition * float64(time.Second))
+	go func() {
+		defer cancel()
+
+		err := light.Fade(ctx, d.light, nil, values, duration, light.FadeOptions{})
+		close(done)
+		if err == nil || errors.Is(err, context.Canceled) {
+			return
+		}
+
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+
+		// Don't touch the state if the transition was replaced in the meantime.
+		if ctx.Err() != nil {
+			return
+		}
+		d.fadeCancel, d.fadeDone = nil, nil
+
+		d.bridge.reportError(d.name, fmt.Errorf("transition failed: %w", err))
+
+		// The published state is the target of the transition, which the device may not have reached.
+		d.state = d.queryState()
+		if err := d.bridge.publishState(d, d.state); err != nil {
+			d.bridge.reportError(d.name, err)
+		}
+	}()
+
+	return nil
+}
+
+// stopFade cancels the currently running transition, if there is one.
+// It waits until the transition doesn't write to the device anymore.
+// The device mutex must be held.
+func (d *device) stopFade() {
+	if d.fadeCancel != nil {
+		d.fadeCancel()
+		<-d.fadeDone
+		d.fadeCancel, d.fadeDone = nil, nil
+	}
+}
+
+// queryState returns the current state of the device.
+// If the device can't be queried, it's assumed to be off.
+func (d *device) queryState() state {
+	s := state{State: "OFF", Brightness: 255}
+	if containsString(d.colorModes, colorModeColorTemp) {
+		s.ColorMode, s.ColorTemp = colorModeColorTemp, int(math.Round(1e6/clampFloat(2700, d.minTemp, d.maxTemp)))
+	} else {
+		s.ColorMode = colorModeBrightness
+	}
+
+	var current emission.CIE1931xyYAbs
+	if err := d.light.GetColors(&current); err != nil || current.LuminanceY <= 0 || math.IsNaN(current.LuminanceY) {
+		return s
+	}
+
+	if switcher, ok := d.light.(light.Switcher); ok {
+		if on, err := switcher.Power(); err == nil && !on {
+			return s
+		}
+	}
+
+	s.State = "ON"
+	if max := maxLuminance(d.light.ColorProfiles()[0], current); max > 0 {
+		s.Brightness = clampInt(int(math.Round(current.LuminanceY/max*255)), 1, 255)
+	}
+	if containsString(d.colorModes, colorModeXY) {
+		s.ColorMode, s.ColorTemp, s.Color = colorModeXY, 0, &xy{X: current.X, Y: current.Y}
+	}
+
+	return s
+}
+
+// supportedColorModes returns the Home Assistant color modes that all modules support.
+func supportedColorModes(colorProfiles []emission.ColorProfile) []string {
+	minChannels := math.MaxInt32
+	for _, colorProfile := range colorProfiles {
+		if channels := colorProfile.Channels(); channels < minChannels {
+			minChannels = channels
+		}
+	}
+
+	switch {
+	case minChannels >= 3:
+		return []string{colorModeColorTemp, colorModeXY}
+	case minChannels == 2:
+		return []string{colorModeColorTemp}
+	default:
+		return []string{colorModeBrightness}
+	}
+}
+
+// maxLuminance returns the highest luminance in lumen that a module can output with the given chromaticity.
+// The luminance of c is ignored.
+func maxLuminance(colorProfile emission.ColorProfile, c emission.CIE1931xyYAbs) float64 {
+	// The sum of all channels is an upper bound.
+	var upper float64
+	for _, channelPoint := range colorProfile.ChannelPoints() {
+		upper += channelPoint.Y
+	}
+
+	// Reproduced luminance for the requested luminance y.
+	reproduced := func(y float64) float64 {
+		c.LuminanceY = y
+		result, err := colorProfile.DCSToXYZ(colorProfile.XYZToDCS(c.CIE1931XYZAbs()))
+		if err != nil {
+			return 0
+		}
+		return result.Y
+	}
+
+	// Colors outside of the gamut are clipped, which changes their luminance by a constant factor.
+	// So determine that factor with a low luminance that doesn't saturate any channel.
+	reference := upper / 1000
+	factor := reproduced(reference) / reference
+	if factor <= 0 {
+		return 0
+	}
+
+	// Search for the highest luminance that still scales linearly.
+	var lower float64
+	for i := 0; i < 32; i++ {
+		mid := (lower + upper) / 2
+		if reproduced(mid) >= mid*factor*0.99 {
+			lower = mid
+		} else {
+			upper = mid
+		}
+	}
+
+	return lower
+}
+
+// containsString returns whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// clampInt limits v to the interval [min, max].
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// clampFloat limits v to the interval [min, max].
+func clampFloat(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
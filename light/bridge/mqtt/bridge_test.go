@@ -0,0 +1,370 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// sceneLight is a virtual light with built-in scenes and a color temperature range.
+type sceneLight struct {
+	*virtual.Light
+	scene string
+}
+
+func (l *sceneLight) Scenes() []string { return []string{"Ocean", "Party"} }
+
+func (l *sceneLight) SetScene(name string) error {
+	if name != "Ocean" && name != "Party" {
+		return fmt.Errorf("scene %q is not supported", name)
+	}
+	l.scene = name
+	return nil
+}
+
+func (l *sceneLight) TemperatureRange() (min, max float64, ok bool) { return 2200, 6500, true }
+
+// waitForState waits for the next state message that is accepted by want.
+func waitForState(t *testing.T, states <-chan state, want func(state) bool) state {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-states:
+			if want(s) {
+				return s
+			}
+		case <-timeout:
+			t.Fatalf("Timeout while waiting for state")
+			return state{}
+		}
+	}
+}
+
+// waitForRetained waits until the broker has the given retained message.
+func waitForRetained(t *testing.T, broker *testBroker, topic, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if payload, _ := broker.Retained(topic); string(payload) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	payload, _ := broker.Retained(topic)
+	t.Errorf("Retained message of %q is %q, want %q", topic, payload, want)
+}
+
+func TestBridge(t *testing.T) {
+	broker := newTestBroker(t)
+
+	virtualLight, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	l := &sceneLight{Light: virtualLight}
+
+	options := Options{}
+	client, err := Dial(broker.Address(), ClientOptions{ClientID: "bridge", Will: options.Will()})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer client.Close()
+
+	bridge, err := NewBridge(client, options)
+	if err != nil {
+		t.Fatalf("NewBridge() failed: %v", err)
+	}
+	if err := bridge.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := bridge.Add("desk", l); err == nil {
+		t.Errorf("Adding a device twice succeeded, want error")
+	}
+
+	// Check the discovery document.
+	waitForRetained(t, broker, "d3iot/desk/state", `{"state":"OFF","brightness":255,"color_mode":"color_temp","color_temp":370}`)
+	payload, ok := broker.Retained("homeassistant/light/d3iot/desk/config")
+	if !ok {
+		t.Fatalf("No discovery document published")
+	}
+	var config struct {
+		Schema       string   `json:"schema"`
+		CommandTopic string   `json:"command_topic"`
+		StateTopic   string   `json:"state_topic"`
+		ColorModes   []string `json:"supported_color_modes"`
+		MinMireds    int      `json:"min_mireds"`
+		MaxMireds    int      `json:"max_mireds"`
+		EffectList   []string `json:"effect_list"`
+	}
+	if err := json.Unmarshal(payload, &config); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if config.Schema != "json" || config.CommandTopic != "d3iot/desk/set" || config.StateTopic != "d3iot/desk/state" {
+		t.Errorf("Discovery document has unexpected topics: %s", payload)
+	}
+	if len(config.ColorModes) != 2 || config.MinMireds != 153 || config.MaxMireds != 455 || len(config.EffectList) != 2 {
+		t.Errorf("Discovery document has unexpected capabilities: %s", payload)
+	}
+	waitForRetained(t, broker, "d3iot/status", "online")
+
+	// Connect a second client that acts like Home Assistant.
+	controller, err := Dial(broker.Address(), ClientOptions{ClientID: "controller"})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer controller.Close()
+
+	states := make(chan state, 16)
+	err = controller.Subscribe("d3iot/+/state", func(topic string, payload []byte) {
+		var s state
+		if err := json.Unmarshal(payload, &s); err == nil {
+			states <- s
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	waitForState(t, states, func(s state) bool { return s.State == "OFF" }) // Retained initial state.
+
+	send := func(command string) {
+		t.Helper()
+		if err := controller.Publish("d3iot/desk/set", []byte(command), false); err != nil {
+			t.Fatalf("Publish() failed: %v", err)
+		}
+	}
+
+	// Set a color with half brightness.
+	send(`{"state": "ON", "brightness": 128, "color": {"x": 0.3, "y": 0.6}}`)
+	waitForState(t, states, func(s state) bool { return s.State == "ON" && s.ColorMode == "xy" && s.Brightness == 128 })
+	var xyY emission.CIE1931xyYAbs
+	if err := l.GetColors(&xyY); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+	if xyY.X < 0.29 || xyY.X > 0.31 || xyY.Y < 0.59 || xyY.Y > 0.61 {
+		t.Errorf("Light has chromaticity (%v, %v), want (0.3, 0.6)", xyY.X, xyY.Y)
+	}
+	halfLuminance := xyY.LuminanceY
+
+	// Full brightness keeps the color.
+	send(`{"brightness": 255}`)
+	waitForState(t, states, func(s state) bool { return s.Brightness == 255 && s.ColorMode == "xy" })
+	if err := l.GetColors(&xyY); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+	if ratio := xyY.LuminanceY / halfLuminance; ratio < 1.95 || ratio > 2.05 {
+		t.Errorf("Luminance ratio of full and half brightness is %v, want 2", ratio)
+	}
+
+	// Color temperatures are limited to the supported range.
+	send(`{"color_temp": 1000}`)
+	s := waitForState(t, states, func(s state) bool { return s.ColorMode == "color_temp" })
+	if s.ColorTemp != 455 {
+		t.Errorf("Color temperature is %d mired, want 455", s.ColorTemp)
+	}
+
+	// Start a scene.
+	send(`{"effect": "Ocean"}`)
+	waitForState(t, states, func(s state) bool { return s.Effect == "Ocean" })
+	if l.scene != "Ocean" {
+		t.Errorf("Light runs scene %q, want %q", l.scene, "Ocean")
+	}
+
+	// Turn the light off.
+	send(`{"state": "OFF"}`)
+	waitForState(t, states, func(s state) bool { return s.State == "OFF" })
+	for _, channel := range l.Vectors()[0] {
+		if channel > 0.01 {
+			t.Errorf("Light has DCS vector %v after turning off, want black", l.Vectors()[0])
+			break
+		}
+	}
+
+	// Closing the bridge marks it as unavailable.
+	if err := bridge.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	waitForRetained(t, broker, "d3iot/status", "offline")
+}
+
+func TestWill(t *testing.T) {
+	broker := newTestBroker(t)
+
+	options := Options{Prefix: "lights"}
+	client, err := Dial(broker.Address(), ClientOptions{Will: options.Will()})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	if _, err := NewBridge(client, options); err != nil {
+		t.Fatalf("NewBridge() failed: %v", err)
+	}
+
+	// Drop the connection without disconnecting gracefully.
+	client.terminate(fmt.Errorf("test"))
+
+	waitForRetained(t, broker, "lights/status", "offline")
+}
+
+// newTestBridge returns a bridge that publishes the given light as "desk", and a channel that receives its states.
+func newTestBridge(t *testing.T, l light.Light, options Options) (send func(command string), states <-chan state) {
+	broker := newTestBroker(t)
+
+	client, err := Dial(broker.Address(), ClientOptions{ClientID: "bridge"})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	bridge, err := NewBridge(client, options)
+	if err != nil {
+		t.Fatalf("NewBridge() failed: %v", err)
+	}
+	t.Cleanup(func() { bridge.Close() })
+	if err := bridge.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	controller, err := Dial(broker.Address(), ClientOptions{ClientID: "controller"})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	t.Cleanup(func() { controller.Close() })
+
+	stateChan := make(chan state, 16)
+	err = controller.Subscribe("d3iot/desk/state", func(topic string, payload []byte) {
+		var s state
+		if err := json.Unmarshal(payload, &s); err == nil {
+			stateChan <- s
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	waitForState(t, stateChan, func(s state) bool { return true }) // Retained initial state.
+
+	send = func(command string) {
+		t.Helper()
+		if err := controller.Publish("d3iot/desk/set", []byte(command), false); err != nil {
+			t.Fatalf("Publish() failed: %v", err)
+		}
+	}
+
+	return send, stateChan
+}
+
+func TestBridgeTransitionReplaced(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{Latency: time.Millisecond}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	send, states := newTestBridge(t, l, Options{DisableDiscovery: true})
+
+	for i := 0; i < 5; i++ {
+		send(`{"state": "ON", "brightness": 255, "transition": 10}`)
+		waitForState(t, states, func(s state) bool { return s.State == "ON" })
+		time.Sleep(5 * time.Millisecond)
+		send(`{"state": "OFF"}`)
+		waitForState(t, states, func(s state) bool { return s.State == "OFF" })
+
+		// The replaced transition must not write anything after the light was turned off.
+		time.Sleep(5 * time.Millisecond)
+		for _, channel := range l.Vectors()[0] {
+			if channel != 0 {
+				t.Fatalf("Light has DCS vector %v after the transition was replaced, want black", l.Vectors()[0])
+			}
+		}
+	}
+}
+
+// unreadableLight is a virtual light whose state can't be queried once it's broken.
+type unreadableLight struct {
+	*virtual.Light
+
+	mutex  sync.Mutex
+	broken bool
+}
+
+func (l *unreadableLight) GetColors(emissionValues ...emission.ValueReceiver) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.broken {
+		return fmt.Errorf("can't read state")
+	}
+	return l.Light.GetColors(emissionValues...)
+}
+
+func TestBridgeTransitionError(t *testing.T) {
+	virtualLight, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	l := &unreadableLight{Light: virtualLight}
+
+	errs := make(chan error, 4)
+	send, states := newTestBridge(t, l, Options{DisableDiscovery: true, ErrorHandler: func(name string, err error) { errs <- err }})
+
+	send(`{"state": "ON", "brightness": 255}`)
+	waitForState(t, states, func(s state) bool { return s.State == "ON" })
+
+	// The transition can't start, as the current state can't be read.
+	l.mutex.Lock()
+	l.broken = true
+	l.mutex.Unlock()
+	send(`{"brightness": 128, "transition": 1}`)
+	waitForState(t, states, func(s state) bool { return s.Brightness == 128 })
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Failed transition wasn't reported")
+	}
+
+	// The actual state is published again, instead of the target of the transition.
+	waitForState(t, states, func(s state) bool { return s.Brightness != 128 })
+}
+
+func TestBridgeAddSubscribeFailed(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.rejectFilter = "d3iot/desk/set"
+
+	client, err := Dial(broker.Address(), ClientOptions{ClientID: "bridge"})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer client.Close()
+
+	bridge, err := NewBridge(client, Options{DisableDiscovery: true})
+	if err != nil {
+		t.Fatalf("NewBridge() failed: %v", err)
+	}
+
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	if err := bridge.Add("desk", l); err == nil {
+		t.Fatalf("Add() with a rejected subscription succeeded, want error")
+	}
+
+	// The device can be added again, once the broker accepts the subscription.
+	broker.mutex.Lock()
+	broker.rejectFilter = ""
+	broker.mutex.Unlock()
+	if err := bridge.Add("desk", l); err != nil {
+		t.Errorf("Add() after a failed Add() failed: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+)
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker that supports QoS 0, retained messages and will messages.
+type testBroker struct {
+	listener net.Listener
+
+	mutex        sync.Mutex
+	retained     map[string][]byte
+	sessions     map[*brokerSession]struct{}
+	rejectFilter string // Subscriptions with this filter are rejected.
+	ignorePings  bool   // PINGREQ packets are not answered.
+}
+
+// brokerSession is a client connection of the test broker.
+type brokerSession struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+	filters    []string
+}
+
+// newTestBroker starts a broker on a local TCP port.
+// The broker is stopped when the test finishes.
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+
+	b := &testBroker{
+		listener: listener,
+		retained: make(map[string][]byte),
+		sessions: make(map[*brokerSession]struct{}),
+	}
+
+	go b.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		b.mutex.Lock()
+		for s := range b.sessions {
+			s.conn.Close()
+		}
+		b.mutex.Unlock()
+	})
+
+	return b
+}
+
+// Address returns the address the broker is listening on.
+func (b *testBroker) Address() string {
+	return b.listener.Addr().String()
+}
+
+// Retained returns the retained message of the given topic.
+func (b *testBroker) Retained(topic string) ([]byte, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	payload, ok := b.retained[topic]
+	return payload, ok
+}
+
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+// handle serves a single client connection.
+func (b *testBroker) handle(conn net.Conn) {
+	s := &brokerSession{conn: conn}
+	reader := bufio.NewReader(conn)
+
+	// Read the CONNECT packet and its will message.
+	p, err := readPacket(reader)
+	if err != nil || p.kind != packetConnect {
+		conn.Close()
+		return
+	}
+	will := parseWill(p.body)
+
+	b.mutex.Lock()
+	b.sessions[s] = struct{}{}
+	b.mutex.Unlock()
+
+	s.write(packet{kind: packetConnAck, body: []byte{0, 0}})
+
+	graceful := false
+	defer func() {
+		conn.Close()
+		b.mutex.Lock()
+		delete(b.sessions, s)
+		b.mutex.Unlock()
+		if !graceful && will != nil {
+			b.publish(will.Topic, will.Payload, will.Retain)
+		}
+	}()
+
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch p.kind {
+		case packetPublish:
+			topic, payload, retain, err := parsePublish(p)
+			if err != nil {
+				return
+			}
+			b.publish(topic, payload, retain)
+
+		case packetSubscribe:
+			id := p.body[:2]
+			filter, _, err := readString(p.body[2:])
+			if err != nil {
+				return
+			}
+
+			b.mutex.Lock()
+			if filter == b.rejectFilter {
+				b.mutex.Unlock()
+				s.write(packet{kind: packetSubAck, body: []byte{id[0], id[1], 0x80}})
+				continue
+			}
+			s.filters = append(s.filters, filter)
+			var retained []packet
+			for topic, payload := range b.retained {
+				if topicMatches(filter, topic) {
+					retained = append(retained, publishPacket(topic, payload, true))
+				}
+			}
+			b.mutex.Unlock()
+
+			s.write(packet{kind: packetSubAck, body: []byte{id[0], id[1], 0}})
+			for _, r := range retained {
+				s.write(r)
+			}
+
+		case packetPingReq:
+			b.mutex.Lock()
+			ignore := b.ignorePings
+			b.mutex.Unlock()
+			if !ignore {
+				s.write(packet{kind: packetPingResp})
+			}
+
+		case packetDisconnect:
+			graceful = true
+			return
+		}
+	}
+}
+
+// publish stores retained messages and forwards the message to all matching sessions.
+func (b *testBroker) publish(topic string, payload []byte, retain bool) {
+	b.mutex.Lock()
+	if retain {
+		if len(payload) == 0 {
+			delete(b.retained, topic)
+		} else {
+			b.retained[topic] = append([]byte(nil), payload...)
+		}
+	}
+	var receivers []*brokerSession
+	for s := range b.sessions {
+		for _, filter := range s.filters {
+			if topicMatches(filter, topic) {
+				receivers = append(receivers, s)
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	for _, s := range receivers {
+		s.write(publishPacket(topic, payload, false))
+	}
+}
+
+func (s *brokerSession) write(p packet) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	writePacket(s.conn, p)
+}
+
+// parseWill returns the will message of a CONNECT packet body, if there is one.
+func parseWill(body []byte) *Will {
+	_, rest, err := readString(body) // Protocol name.
+	if err != nil || len(rest) < 4 {
+		return nil
+	}
+	flags := rest[1]
+	if _, rest, err = readString(rest[4:]); err != nil || flags&0x04 == 0 { // Client ID.
+		return nil
+	}
+
+	topic, rest, err := readString(rest)
+	if err != nil {
+		return nil
+	}
+	payload, _, err := readString(rest)
+	if err != nil {
+		return nil
+	}
+
+	return &Will{Topic: topic, Payload: []byte(payload), Retain: flags&0x20 != 0}
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// writeTimeout is the maximum time that sending a single packet may take.
+const writeTimeout = 5 * time.Second
+
+// MessageHandler is called for every received message that matches a subscription.
+// Handlers of the built-in client are called one after another in the order the messages were received, but not from the goroutine that reads from the connection.
+// So a slow handler delays other messages, but not the communication with the broker.
+type MessageHandler func(topic string, payload []byte)
+
+// Client is the interface to an MQTT broker that the bridge needs.
+// It can be implemented by other MQTT libraries, if the built-in client isn't sufficient.
+type Client interface {
+	// Publish sends a message with QoS 0.
+	Publish(topic string, payload []byte, retain bool) error
+
+	// Subscribe registers the handler for all messages that match the topic filter.
+	Subscribe(filter string, handler MessageHandler) error
+
+	// Close disconnects from the broker.
+	Close() error
+}
+
+// Will is a message that the broker publishes when the client disconnects unexpectedly.
+type Will struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// ClientOptions contains the connection options of the built-in client.
+type ClientOptions struct {
+	ClientID string // Defaults to "d3iot".
+	Username string
+	Password string // Can only be used together with Username.
+
+	KeepAlive time.Duration // Interval of keep alive pings. The connection is closed if the broker doesn't answer a ping within this interval. Defaults to 30 s.
+
+	Will *Will // Optional last will message.
+}
+
+// subscription is a registered topic filter and its handler.
+type subscription struct {
+	id      uint16 // Packet identifier of the SUBSCRIBE packet.
+	filter  string
+	handler MessageHandler
+}
+
+// message is a received message that waits to be passed to the handlers.
+type message struct {
+	topic    string
+	payload  []byte
+	handlers []MessageHandler
+}
+
+// Conn is a minimal MQTT 3.1.1 client that supports QoS 0 only.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+
+	mutex         sync.Mutex
+	subscriptions []subscription
+	nextID        uint16
+	pending       map[uint16]chan error // Subscriptions that wait for their SUBACK.
+	err           error                 // The error that terminated the connection.
+
+	queueMutex  sync.Mutex
+	queue       []message     // Received messages that wait to be dispatched.
+	queueSignal chan struct{} // Signals that the queue contains messages.
+
+	pingResp chan struct{} // Signals that a PINGRESP was received.
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Client = &Conn{}
+
+// Dial connects to the MQTT broker at the given TCP address.
+func Dial(address string, options ClientOptions) (*Conn, error) {
+	if options.ClientID == "" {
+		options.ClientID = "d3iot"
+	}
+	if options.KeepAlive <= 0 {
+		options.KeepAlive = 30 * time.Second
+	}
+	if options.Password != "" && options.Username == "" {
+		return nil, fmt.Errorf("a password can't be used without a username")
+	}
+
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %q: %w", address, err)
+	}
+
+	c := &Conn{
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		pending:     map[uint16]chan error{},
+		queueSignal: make(chan struct{}, 1),
+		pingResp:    make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+	}
+
+	if err := c.handshake(options); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+	go c.dispatchLoop()
+	go c.keepAlive(options.KeepAlive)
+
+	return c, nil
+}
+
+// handshake sends the CONNECT packet and waits for the CONNACK.
+func (c *Conn) handshake(options ClientOptions) error {
+	c.conn.SetDeadline(time.Now().Add(writeTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := writePacket(c.conn, connectPacket(options)); err != nil {
+		return fmt.Errorf("failed to send CONNECT packet: %w", err)
+	}
+
+	p, err := readPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK packet: %w", err)
+	}
+	if p.kind != packetConnAck || len(p.body) != 2 {
+		return fmt.Errorf("expected CONNACK packet, got packet of type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		return fmt.Errorf("broker refused connection with return code %d", code)
+	}
+
+	return nil
+}
+
+// write sends a packet to the broker.
+// The connection is terminated if the packet can't be sent, as it may have been sent partially.
+func (c *Conn) write(p packet) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := writePacket(c.conn, p); err != nil {
+		c.terminate(err)
+		return err
+	}
+
+	return nil
+}
+
+// readLoop receives packets until the connection is closed.
+func (c *Conn) readLoop() {
+	for {
+		p, err := readPacket(c.reader)
+		if err != nil {
+			c.terminate(err)
+			return
+		}
+
+		switch p.kind {
+		case packetPublish:
+			topic, payload, _, err := parsePublish(p)
+			if err != nil {
+				c.terminate(err)
+				return
+			}
+			c.mutex.Lock()
+			var handlers []MessageHandler
+			for _, s := range c.subscriptions {
+				if topicMatches(s.filter, topic) {
+					handlers = append(handlers, s.handler)
+				}
+			}
+			c.mutex.Unlock()
+			if len(handlers) > 0 {
+				c.enqueue(message{topic: topic, payload: payload, handlers: handlers})
+			}
+
+		case packetSubAck:
+			if len(p.body) < 3 {
+				c.terminate(errMalformed)
+				return
+			}
+			id := uint16(p.body[0])<<8 | uint16(p.body[1])
+			c.mutex.Lock()
+			if ch, ok := c.pending[id]; ok {
+				delete(c.pending, id)
+				if p.body[2] == 0x80 {
+					ch <- fmt.Errorf("broker rejected subscription")
+				} else {
+					ch <- nil
+				}
+			}
+			c.mutex.Unlock()
+
+		case packetPingResp:
+			select {
+			case c.pingResp <- struct{}{}:
+			default:
+				// The keep alive loop is already signaled.
+			}
+		}
+	}
+}
+
+// enqueue adds a received message to the queue of the dispatch loop.
+func (c *Conn) enqueue(m message) {
+	c.queueMutex.Lock()
+	c.queue = append(c.queue, m)
+	c.queueMutex.Unlock()
+
+	select {
+	case c.queueSignal <- struct{}{}:
+	default:
+		// The dispatch loop is already signaled.
+	}
+}
+
+// dispatchLoop passes all queued messages to their handlers until the connection is closed.
+func (c *Conn) dispatchLoop() {
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-c.queueSignal:
+		}
+
+		c.queueMutex.Lock()
+		queue := c.queue
+		c.queue = nil
+		c.queueMutex.Unlock()
+
+		for _, m := range queue {
+			for _, handler := range m.handlers {
+				handler(m.topic, m.payload)
+			}
+		}
+	}
+}
+
+// keepAlive sends pings to the broker, so it doesn't drop the connection.
+// The connection is terminated if the broker doesn't answer a ping within the given interval.
+func (c *Conn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time // Fires if the last ping wasn't answered in time. Nil if there is no unanswered ping.
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-c.pingResp:
+			timeout = nil
+		case <-timeout:
+			c.terminate(fmt.Errorf("broker didn't answer ping within %v", interval))
+			return
+		case <-ticker.C:
+			if timeout != nil {
+				// Still waiting for the answer of the last ping.
+				continue
+			}
+			if err := c.write(packet{kind: packetPingReq}); err != nil {
+				return
+			}
+			timeout = time.After(interval)
+		}
+	}
+}
+
+// terminate closes the connection and stores the error that caused it.
+func (c *Conn) terminate(err error) {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.err = err
+		for id, ch := range c.pending {
+			delete(c.pending, id)
+			ch <- fmt.Errorf("connection closed: %w", err)
+		}
+		c.mutex.Unlock()
+
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// Done returns a channel that is closed when the connection is terminated.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Err returns the error that terminated the connection, if any.
+func (c *Conn) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+// Publish implements the Client interface.
+func (c *Conn) Publish(topic string, payload []byte, retain bool) error {
+	if err := c.write(publishPacket(topic, payload, retain)); err != nil {
+		return fmt.Errorf("failed to publish to %q: %w", topic, err)
+	}
+
+	return nil
+}
+
+// Subscribe implements the Client interface.
+// It blocks until the broker acknowledged the subscription.
+func (c *Conn) Subscribe(filter string, handler MessageHandler) error {
+	ch := make(chan error, 1)
+
+	c.mutex.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mutex.Unlock()
+		return fmt.Errorf("connection closed: %w", err)
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID++
+	}
+	id := c.nextID
+	c.pending[id] = ch
+	c.subscriptions = append(c.subscriptions, subscription{id: id, filter: filter, handler: handler})
+	c.mutex.Unlock()
+
+	if err := c.write(subscribePacket(id, filter)); err != nil {
+		c.removeSubscription(id)
+		return fmt.Errorf("failed to subscribe to %q: %w", filter, err)
+	}
+
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			c.removeSubscription(id)
+			return fmt.Errorf("failed to subscribe to %q: %w", filter, err)
+		}
+		return nil
+	case <-timer.C:
+		c.removeSubscription(id)
+		return fmt.Errorf("failed to subscribe to %q: timeout", filter)
+	}
+}
+
+// removeSubscription removes the subscription and the pending SUBACK with the given packet identifier.
+func (c *Conn) removeSubscription(id uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pending, id)
+	for i, s := range c.subscriptions {
+		if s.id == id {
+			c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+			break
+		}
+	}
+}
+
+// Close implements the Client interface.
+// It disconnects gracefully, so the broker doesn't publish the will message.
+func (c *Conn) Close() error {
+	err := c.write(packet{kind: packetDisconnect})
+	c.terminate(fmt.Errorf("connection closed by client"))
+
+	return err
+}
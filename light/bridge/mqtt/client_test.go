@@ -0,0 +1,107 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDialPasswordWithoutUsername(t *testing.T) {
+	broker := newTestBroker(t)
+
+	if _, err := Dial(broker.Address(), ClientOptions{Password: "secret"}); err == nil {
+		t.Errorf("Dial() with a password but without username succeeded, want error")
+	}
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.rejectFilter = "rejected/#"
+
+	client, err := Dial(broker.Address(), ClientOptions{})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Subscribe("rejected/#", func(topic string, payload []byte) {}); err == nil {
+		t.Fatalf("Subscribe() succeeded, want error")
+	}
+
+	// The failed subscription must not leave anything behind.
+	client.mutex.Lock()
+	subscriptions, pending := len(client.subscriptions), len(client.pending)
+	client.mutex.Unlock()
+	if subscriptions != 0 || pending != 0 {
+		t.Errorf("Client has %d subscriptions and %d pending SUBACKs after a failed subscription, want none", subscriptions, pending)
+	}
+}
+
+func TestSlowHandler(t *testing.T) {
+	broker := newTestBroker(t)
+
+	client, err := Dial(broker.Address(), ClientOptions{})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer client.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	received := make(chan string, 4)
+	err = client.Subscribe("test/+", func(topic string, payload []byte) {
+		received <- topic
+		<-release
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+
+	if err := client.Publish("test/slow", nil, false); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Handler wasn't called")
+	}
+
+	// While the handler blocks, the client must still receive packets from the broker, like the SUBACK.
+	if err := client.Subscribe("other", func(topic string, payload []byte) {}); err != nil {
+		t.Errorf("Subscribe() while a handler blocks failed: %v", err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	broker := newTestBroker(t)
+
+	client, err := Dial(broker.Address(), ClientOptions{KeepAlive: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	defer client.Close()
+
+	// Answered pings keep the connection open.
+	select {
+	case <-client.Done():
+		t.Fatalf("Connection was terminated: %v", client.Err())
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Unanswered pings terminate the connection.
+	broker.mutex.Lock()
+	broker.ignorePings = true
+	broker.mutex.Unlock()
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Connection wasn't terminated without PINGRESP")
+	}
+	if client.Err() == nil {
+		t.Errorf("Err() of a terminated connection returned nil")
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1.
+const (
+	packetConnect     byte = 1
+	packetConnAck     byte = 2
+	packetPublish     byte = 3
+	packetSubscribe   byte = 8
+	packetSubAck      byte = 9
+	packetPingReq     byte = 12
+	packetPingResp    byte = 13
+	packetDisconnect  byte = 14
+	maxRemainingBytes      = 268435455
+)
+
+// packet is a single MQTT control packet.
+type packet struct {
+	kind  byte   // The control packet type.
+	flags byte   // The lower 4 bits of the fixed header.
+	body  []byte // The variable header and the payload.
+}
+
+// readPacket reads a single packet from r.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	// Decode the remaining length.
+	var length, multiplier int = 0, 1
+	for i := 0; ; i++ {
+		if i >= 4 {
+			return packet{}, fmt.Errorf("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// writePacket writes a single packet to w.
+func writePacket(w io.Writer, p packet) error {
+	length := len(p.body)
+	if length > maxRemainingBytes {
+		return fmt.Errorf("packet of %d bytes is too large", length)
+	}
+
+	buf := make([]byte, 0, 5+length)
+	buf = append(buf, p.kind<<4|p.flags&0x0f)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	buf = append(buf, p.body...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// appendUint16 appends a big endian 16 bit integer.
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// appendString appends a length prefixed UTF-8 string.
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// errMalformed is returned when a packet can't be decoded.
+var errMalformed = errors.New("malformed packet")
+
+// readString reads a length prefixed UTF-8 string and returns the rest of b.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errMalformed
+	}
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return "", nil, errMalformed
+	}
+
+	return string(b[2 : 2+length]), b[2+length:], nil
+}
+
+// connectPacket returns a CONNECT packet with the given options.
+func connectPacket(options ClientOptions) packet {
+	var flags byte = 0x02 // Clean session.
+	if options.Will != nil {
+		flags |= 0x04
+		if options.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if options.Password != "" {
+		flags |= 0x40
+	}
+	if options.Username != "" {
+		flags |= 0x80
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 = 3.1.1.
+	body = appendUint16(body, uint16(options.KeepAlive.Seconds()))
+	body = appendString(body, options.ClientID)
+	if options.Will != nil {
+		body = appendString(body, options.Will.Topic)
+		body = appendString(body, string(options.Will.Payload))
+	}
+	if options.Username != "" {
+		body = appendString(body, options.Username)
+	}
+	if options.Password != "" {
+		body = appendString(body, options.Password)
+	}
+
+	return packet{kind: packetConnect, body: body}
+}
+
+// publishPacket returns a PUBLISH packet with QoS 0.
+func publishPacket(topic string, payload []byte, retain bool) packet {
+	var flags byte
+	if retain {
+		flags |= 0x01
+	}
+
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+
+	return packet{kind: packetPublish, flags: flags, body: body}
+}
+
+// parsePublish returns the topic and payload of a PUBLISH packet.
+func parsePublish(p packet) (topic string, payload []byte, retain bool, err error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	// Skip the packet identifier of QoS 1 and 2 messages.
+	if qos := p.flags >> 1 & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, false, errMalformed
+		}
+		rest = rest[2:]
+	}
+
+	return topic, rest, p.flags&0x01 != 0, nil
+}
+
+// subscribePacket returns a SUBSCRIBE packet for a single topic filter with QoS 0.
+func subscribePacket(id uint16, filter string) packet {
+	body := appendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, 0) // Requested QoS.
+
+	return packet{kind: packetSubscribe, flags: 0x02, body: body}
+}
+
+// topicMatches returns whether the topic matches the given filter, which may contain the wildcards + and #.
+func topicMatches(filter, topic string) bool {
+	for {
+		filterLevel, filterRest, filterMore := cutLevel(filter)
+		topicLevel, topicRest, topicMore := cutLevel(topic)
+
+		switch {
+		case filterLevel == "#":
+			return true
+		case filterLevel != "+" && filterLevel != topicLevel:
+			return false
+		case !filterMore && !topicMore:
+			return true
+		case !filterMore || !topicMore:
+			// A trailing /# also matches the parent level.
+			return filterMore && filterRest == "#"
+		}
+
+		filter, topic = filterRest, topicRest
+	}
+}
+
+// cutLevel splits the first topic level from s.
+func cutLevel(s string) (level, rest string, more bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/b", "a/b/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"+/b", "a/b", true},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"#", "a/b", true},
+		{"a/+/c", "a/b/c", true},
+		{"a/+/c", "a/b/d", false},
+	}
+
+	for _, test := range tests {
+		if got := topicMatches(test.filter, test.topic); got != test.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", test.filter, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	// Use a payload that needs more than one byte of remaining length.
+	payload := bytes.Repeat([]byte("x"), 300)
+
+	var buf bytes.Buffer
+	if err := writePacket(&buf, publishPacket("d3iot/lamp/state", payload, true)); err != nil {
+		t.Fatalf("writePacket() failed: %v", err)
+	}
+
+	p, err := readPacket(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("readPacket() failed: %v", err)
+	}
+	topic, gotPayload, retain, err := parsePublish(p)
+	if err != nil {
+		t.Fatalf("parsePublish() failed: %v", err)
+	}
+	if topic != "d3iot/lamp/state" || !bytes.Equal(gotPayload, payload) || !retain {
+		t.Errorf("Got topic %q, %d bytes of payload, retain %v, want %q, %d bytes, retain true", topic, len(gotPayload), retain, "d3iot/lamp/state", len(payload))
+	}
+}
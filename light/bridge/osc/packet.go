@@ -0,0 +1,271 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Packet is either a *Message or a *Bundle.
+type Packet interface {
+	// MarshalBinary returns the packet in the OSC 1.0 binary format.
+	MarshalBinary() ([]byte, error)
+}
+
+// Message is an OSC message.
+//
+// Arguments can be of the type int32, float32, string, []byte, int64, float64, bool or nil.
+type Message struct {
+	Address   string
+	Arguments []interface{}
+}
+
+// Bundle contains several elements that are applied at the same time.
+type Bundle struct {
+	Timetag  Timetag
+	Elements []Packet
+}
+
+// Timetag is a time in the NTP format, the seconds since 1900 in the upper 32 bits and the fraction in the lower 32 bits.
+type Timetag uint64
+
+// Immediately is the timetag that means "now".
+const Immediately Timetag = 1
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970.
+const ntpEpochOffset = 2208988800
+
+// NewTimetag returns the timetag of the given time.
+func NewTimetag(t time.Time) Timetag {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / 1e9
+	return Timetag(seconds<<32 | fraction)
+}
+
+// Time returns the time of the timetag.
+// Immediately is returned as the zero time.
+func (t Timetag) Time() time.Time {
+	if t == Immediately {
+		return time.Time{}
+	}
+	seconds := int64(t>>32) - ntpEpochOffset
+	nanoseconds := int64(uint64(t&0xffffffff) * 1e9 >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
+
+var bundleID = []byte("#bundle\x00")
+
+// Parse decodes an OSC packet.
+func Parse(data []byte) (Packet, error) {
+	if bytes.HasPrefix(data, bundleID) {
+		return parseBundle(data)
+	}
+	return parseMessage(data)
+}
+
+// parseBundle decodes an OSC bundle.
+func parseBundle(data []byte) (*Bundle, error) {
+	if len(data) < 16 {
+		return nil, fmt.Errorf("bundle too short")
+	}
+
+	b := &Bundle{Timetag: Timetag(binary.BigEndian.Uint64(data[8:16]))}
+	data = data[16:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("bundle element without size")
+		}
+		size := int(binary.BigEndian.Uint32(data))
+		if size%4 != 0 || len(data) < 4+size {
+			return nil, fmt.Errorf("bundle element has invalid size %d", size)
+		}
+
+		element, err := Parse(data[4 : 4+size])
+		if err != nil {
+			return nil, err
+		}
+		b.Elements = append(b.Elements, element)
+		data = data[4+size:]
+	}
+
+	return b, nil
+}
+
+// parseMessage decodes an OSC message.
+func parseMessage(data []byte) (*Message, error) {
+	address, data, err := readString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read address: %w", err)
+	}
+	if len(address) == 0 || address[0] != '/' {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+	m := &Message{Address: address}
+
+	// Messages without type tag string are allowed by OSC 1.0, but they are not very useful.
+	if len(data) == 0 {
+		return m, nil
+	}
+	typeTags, data, err := readString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type tags: %w", err)
+	}
+	if len(typeTags) == 0 || typeTags[0] != ',' {
+		return nil, fmt.Errorf("invalid type tag string %q", typeTags)
+	}
+
+	for _, tag := range typeTags[1:] {
+		var argument interface{}
+		switch tag {
+		case 'i':
+			if len(data) < 4 {
+				return nil, fmt.Errorf("int32 argument too short")
+			}
+			argument, data = int32(binary.BigEndian.Uint32(data)), data[4:]
+		case 'f':
+			if len(data) < 4 {
+				return nil, fmt.Errorf("float32 argument too short")
+			}
+			argument, data = math.Float32frombits(binary.BigEndian.Uint32(data)), data[4:]
+		case 'h':
+			if len(data) < 8 {
+				return nil, fmt.Errorf("int64 argument too short")
+			}
+			argument, data = int64(binary.BigEndian.Uint64(data)), data[8:]
+		case 'd':
+			if len(data) < 8 {
+				return nil, fmt.Errorf("float64 argument too short")
+			}
+			argument, data = math.Float64frombits(binary.BigEndian.Uint64(data)), data[8:]
+		case 's':
+			if argument, data, err = readString(data); err != nil {
+				return nil, fmt.Errorf("failed to read string argument: %w", err)
+			}
+		case 'b':
+			if len(data) < 4 {
+				return nil, fmt.Errorf("blob argument too short")
+			}
+			size := int(binary.BigEndian.Uint32(data))
+			padded := 4 + pad(size)
+			if size < 0 || len(data) < padded {
+				return nil, fmt.Errorf("blob argument too short")
+			}
+			argument, data = append([]byte(nil), data[4:4+size]...), data[padded:]
+		case 'T':
+			argument = true
+		case 'F':
+			argument = false
+		case 'N':
+			argument = nil
+		default:
+			return nil, fmt.Errorf("unsupported argument type %q", tag)
+		}
+		m.Arguments = append(m.Arguments, argument)
+	}
+
+	return m, nil
+}
+
+// MarshalBinary implements the Packet interface.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	data := appendString(nil, m.Address)
+
+	typeTags := []byte{','}
+	var arguments []byte
+	for _, argument := range m.Arguments {
+		switch a := argument.(type) {
+		case int32:
+			typeTags = append(typeTags, 'i')
+			arguments = appendUint32(arguments, uint32(a))
+		case float32:
+			typeTags = append(typeTags, 'f')
+			arguments = appendUint32(arguments, math.Float32bits(a))
+		case int64:
+			typeTags = append(typeTags, 'h')
+			arguments = appendUint64(arguments, uint64(a))
+		case float64:
+			typeTags = append(typeTags, 'd')
+			arguments = appendUint64(arguments, math.Float64bits(a))
+		case string:
+			typeTags = append(typeTags, 's')
+			arguments = appendString(arguments, a)
+		case []byte:
+			typeTags = append(typeTags, 'b')
+			arguments = appendUint32(arguments, uint32(len(a)))
+			arguments = append(arguments, a...)
+			arguments = append(arguments, make([]byte, pad(len(a))-len(a))...)
+		case bool:
+			if a {
+				typeTags = append(typeTags, 'T')
+			} else {
+				typeTags = append(typeTags, 'F')
+			}
+		case nil:
+			typeTags = append(typeTags, 'N')
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", argument)
+		}
+	}
+
+	data = appendString(data, string(typeTags))
+	return append(data, arguments...), nil
+}
+
+// MarshalBinary implements the Packet interface.
+func (b *Bundle) MarshalBinary() ([]byte, error) {
+	data := append([]byte(nil), bundleID...)
+	data = appendUint64(data, uint64(b.Timetag))
+
+	for _, element := range b.Elements {
+		elementData, err := element.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		data = appendUint32(data, uint32(len(elementData)))
+		data = append(data, elementData...)
+	}
+
+	return data, nil
+}
+
+// pad returns n rounded up to a multiple of 4.
+func pad(n int) int {
+	return (n + 3) &^ 3
+}
+
+// readString reads a null terminated and padded string, and returns the rest of data.
+func readString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("string is not terminated")
+	}
+	padded := pad(end + 1)
+	if len(data) < padded {
+		return "", nil, fmt.Errorf("string is not padded")
+	}
+
+	return string(data[:end]), data[padded:], nil
+}
+
+// appendString appends a null terminated and padded string.
+func appendString(data []byte, s string) []byte {
+	data = append(data, s...)
+	return append(data, make([]byte, pad(len(s)+1)-len(s))...)
+}
+
+// appendUint32 appends a big endian 32 bit integer.
+func appendUint32(data []byte, v uint32) []byte {
+	return append(data, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendUint64 appends a big endian 64 bit integer.
+func appendUint64(data []byte, v uint64) []byte {
+	return appendUint32(appendUint32(data, uint32(v>>32)), uint32(v))
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package osc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	want := &Bundle{
+		Timetag: NewTimetag(time.Date(2022, 5, 1, 12, 0, 0, 500000000, time.UTC)),
+		Elements: []Packet{
+			&Message{Address: "/light/desk/0/rgb", Arguments: []interface{}{float32(1), float32(0.5), float32(0)}},
+			&Bundle{Timetag: Immediately, Elements: []Packet{
+				&Message{Address: "/a", Arguments: []interface{}{int32(-5), "text", []byte{1, 2, 3, 4, 5}, int64(1 << 40), 0.25, true, false, nil}},
+				&Message{Address: "/empty"},
+			}},
+		},
+	}
+
+	data, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() failed: %v", err)
+	}
+	if len(data)%4 != 0 {
+		t.Errorf("Packet has a size of %d bytes, want a multiple of 4", len(data))
+	}
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(MarshalBinary()) = %#v, want %#v", got, want)
+	}
+}
+
+func TestTimetag(t *testing.T) {
+	want := time.Date(2022, 5, 1, 12, 0, 0, 250000000, time.UTC)
+	if got := NewTimetag(want).Time(); got.Sub(want) > time.Microsecond || got.Sub(want) < -time.Microsecond {
+		t.Errorf("NewTimetag(%v).Time() = %v", want, got)
+	}
+	if !Immediately.Time().IsZero() {
+		t.Errorf("Immediately.Time() = %v, want zero time", Immediately.Time())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := [][]byte{
+		[]byte("/no-terminator"),
+		[]byte("noslash\x00"),
+		[]byte("/a\x00\x00,i\x00\x00"),        // Missing int32 argument.
+		[]byte("#bundle\x00\x00\x00\x00\x00"), // Bundle without timetag.
+	}
+
+	for _, data := range tests {
+		if p, err := Parse(data); err == nil {
+			t.Errorf("Parse(%q) = %v, want error", data, p)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, address string
+		want             bool
+	}{
+		{"/light/desk/0/rgb", "/light/desk/0/rgb", true},
+		{"/light/desk/0/rgb", "/light/desk/1/rgb", false},
+		{"/light/*/0/rgb", "/light/desk/0/rgb", true},
+		{"/light/*", "/light/desk/0", false},
+		{"/light/d?sk/0/rgb", "/light/desk/0/rgb", true},
+		{"/light/desk/[0-2]/rgb", "/light/desk/1/rgb", true},
+		{"/light/desk/[!0-2]/rgb", "/light/desk/1/rgb", false},
+		{"/light/{desk,shelf}/0/rgb", "/light/shelf/0/rgb", true},
+		{"/light/{desk,shelf}/0/rgb", "/light/floor/0/rgb", false},
+		{"/light/*k/0/rgb", "/light/desk/0/rgb", true},
+		{"/light/*x/0/rgb", "/light/desk/0/rgb", false},
+	}
+
+	for _, test := range tests {
+		if got := Match(test.pattern, test.address); got != test.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", test.pattern, test.address, got, test.want)
+		}
+	}
+}
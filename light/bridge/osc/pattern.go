@@ -0,0 +1,103 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package osc
+
+import "strings"
+
+// Match returns whether the OSC address pattern matches the given address.
+//
+// The pattern may contain the OSC 1.0 wildcards:
+//
+//	?           Any single character.
+//	*           Any sequence of characters, including none.
+//	[abc]       Any character in the list. Ranges like [a-z] and negations like [!0-9] are supported.
+//	{foo,bar}   Any of the comma separated strings.
+//
+// Wildcards never match the slash that separates address parts.
+func Match(pattern, address string) bool {
+	patternParts, addressParts := strings.Split(pattern, "/"), strings.Split(address, "/")
+	if len(patternParts) != len(addressParts) {
+		return false
+	}
+
+	for i := range patternParts {
+		if !matchPart(patternParts[i], addressParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPart matches a single part of an address.
+func matchPart(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Try to match the rest of the pattern at every position.
+			for i := len(s); i >= 0; i-- {
+				if matchPart(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+
+		case '[':
+			end := strings.IndexByte(pattern, ']')
+			if end < 0 || len(s) == 0 || !matchList(pattern[1:end], s[0]) {
+				return false
+			}
+			pattern, s = pattern[end+1:], s[1:]
+
+		case '{':
+			end := strings.IndexByte(pattern, '}')
+			if end < 0 {
+				return false
+			}
+			for _, alternative := range strings.Split(pattern[1:end], ",") {
+				if strings.HasPrefix(s, alternative) && matchPart(pattern[end+1:], s[len(alternative):]) {
+					return true
+				}
+			}
+			return false
+
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+
+	return len(s) == 0
+}
+
+// matchList returns whether c is in the list of a [] expression.
+func matchList(list string, c byte) bool {
+	negate := strings.HasPrefix(list, "!")
+	if negate {
+		list = list[1:]
+	}
+
+	match := false
+	for i := 0; i < len(list); i++ {
+		if i+2 < len(list) && list[i+1] == '-' {
+			if list[i] <= c && c <= list[i+2] {
+				match = true
+			}
+			i += 2
+		} else if list[i] == c {
+			match = true
+		}
+	}
+
+	return match != negate
+}
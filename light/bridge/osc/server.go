@@ -0,0 +1,446 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package osc controls light devices via Open Sound Control 1.0, as sent by VJ software or control panels like TouchOSC.
+package osc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Options contains the parameters of a Server.
+type Options struct {
+	// The maximum number of updates that are sent to a light device per second.
+	// Messages in between are coalesced, so only the newest state is sent.
+	// Defaults to 20 if zero.
+	MaxUpdateRate float64
+
+	// The maximum time that bundles can be scheduled into the future.
+	// Bundles with a timetag later than that are dropped.
+	// Defaults to 1 minute if zero.
+	MaxSchedule time.Duration
+
+	// Called with errors that happen while handling messages or sending values to light devices.
+	// Errors are ignored if this is nil.
+	ErrorHandler func(err error)
+}
+
+// colorKind is the kind of the last color that was set for a module.
+type colorKind int
+
+const (
+	colorWhitePoint colorKind = iota // The white point of the module.
+	colorRGB                         // sRGB values.
+	colorCCT                         // A black body radiator.
+	colorxyY                         // CIE 1931 xyY with relative luminance.
+)
+
+// moduleState is the state of a single module.
+type moduleState struct {
+	kind        colorKind
+	r, g, b     float64
+	temperature float64 // In K.
+	x, y, lum   float64 // Luminance relative to the white point.
+	dim         float64 // Scales the luminance, in the range of [0, 1].
+}
+
+// value returns the emission value of the module state.
+func (s moduleState) value(colorProfile emission.ColorProfile) emission.Value {
+	switch s.kind {
+	case colorRGB:
+		return emission.StandardRGB{R: s.r, G: s.g, B: s.b}.CIE1931XYZRel().Scaled(s.dim)
+	case colorCCT:
+		return emission.BlackBodyFixed{Temperature: s.temperature, Luminance: s.dim * colorProfile.WhitePoint().Y}
+	case colorxyY:
+		if s.y <= 0 {
+			return emission.CIE1931XYZRel{}
+		}
+		return emission.CIE1931xyYRel{X: s.x, Y: s.y, LuminanceY: s.lum * s.dim}
+	}
+	return colorProfile.WhitePoint().Scaled(s.dim)
+}
+
+// device is a light device that is controlled by the server.
+type device struct {
+	name   string
+	light  light.Light
+	notify chan struct{}
+
+	mutex   sync.Mutex
+	modules []moduleState
+	dirty   bool
+}
+
+// Server dispatches OSC messages to light devices.
+//
+// The following methods are supported, where name is the name of the device and module the index of the module:
+//
+//	/light/{name}/{module}/rgb    r g b       sRGB values in the range of [0, 1].
+//	/light/{name}/{module}/cct    kelvin      Color temperature of a black body radiator in K.
+//	/light/{name}/{module}/xyY    x y Y       CIE 1931 chromaticity and luminance relative to the white point.
+//	/light/{name}/{module}/dim    level       Scales the luminance of the color, in the range of [0, 1].
+//
+// Incoming addresses can contain OSC wildcards, e.g. /light/*/*/dim controls all modules of all devices.
+// Arguments can be of any numeric OSC type.
+// Bundles are applied at the time of their timetag, if it's within Options.MaxSchedule.
+type Server struct {
+	options Options
+
+	mutex   sync.RWMutex
+	devices map[string]*device
+
+	timerMutex sync.Mutex
+	timers     map[*time.Timer]struct{} // Timers of scheduled bundles.
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New returns a server without any devices.
+//
+// Use Close() to stop the server.
+func New(options Options) *Server {
+	if options.MaxUpdateRate <= 0 {
+		options.MaxUpdateRate = 20
+	}
+	if options.MaxSchedule <= 0 {
+		options.MaxSchedule = time.Minute
+	}
+
+	return &Server{
+		options: options,
+		devices: make(map[string]*device),
+		timers:  make(map[*time.Timer]struct{}),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Add makes the given light device available under the given name.
+// Names must be unique and must not contain slashes or OSC wildcards.
+// All modules start at the white point of the device, until they are set via OSC.
+func (s *Server) Add(name string, l light.Light) error {
+	if name == "" || strings.ContainsAny(name, "/ #*,?[]{}") {
+		return fmt.Errorf("invalid device name %q", name)
+	}
+
+	d := &device{
+		name:    name,
+		light:   l,
+		notify:  make(chan struct{}, 1),
+		modules: make([]moduleState, l.Modules()),
+	}
+	for i := range d.modules {
+		d.modules[i].dim = 1
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.devices[name]; ok {
+		return fmt.Errorf("device %q already exists", name)
+	}
+	s.devices[name] = d
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(d)
+	}()
+
+	return nil
+}
+
+// Close stops sending updates to the light devices.
+// Scheduled bundles are dropped.
+func (s *Server) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	s.timerMutex.Lock()
+	for timer := range s.timers {
+		if timer.Stop() {
+			s.wg.Done()
+		}
+		delete(s.timers, timer)
+	}
+	s.timerMutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// run sends the newest state of a device, at most with the maximum update rate.
+func (s *Server) run(d *device) {
+	interval := time.Duration(float64(time.Second) / s.options.MaxUpdateRate)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-d.notify:
+		}
+
+		d.mutex.Lock()
+		if !d.dirty {
+			d.mutex.Unlock()
+			continue
+		}
+		d.dirty = false
+		colorProfiles := d.light.ColorProfiles()
+		values := make([]emission.Value, len(d.modules))
+		for i, module := range d.modules {
+			values[i] = module.value(colorProfiles[i])
+		}
+		d.mutex.Unlock()
+
+		if err := d.light.SetColors(values...); err != nil {
+			s.handleError(fmt.Errorf("failed to set colors of %q: %w", d.name, err))
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// handleError passes the error to the error handler, if there is one.
+func (s *Server) handleError(err error) {
+	if s.options.ErrorHandler != nil {
+		s.options.ErrorHandler(err)
+	}
+}
+
+// Handle dispatches a packet.
+// Messages of bundles with a timetag in the future are dispatched at that time.
+func (s *Server) Handle(p Packet) {
+	var messages []*Message
+	s.collect(p, time.Now(), &messages)
+	s.dispatch(messages)
+}
+
+// collect gathers all messages that are due now, and schedules the others.
+func (s *Server) collect(p Packet, now time.Time, messages *[]*Message) {
+	switch p := p.(type) {
+	case *Message:
+		*messages = append(*messages, p)
+
+	case *Bundle:
+		if at := p.Timetag.Time(); at.After(now) {
+			if delay := at.Sub(now); delay > s.options.MaxSchedule {
+				s.handleError(fmt.Errorf("dropped bundle that is scheduled %v into the future, the maximum is %v", delay, s.options.MaxSchedule))
+			} else {
+				s.schedule(delay, &Bundle{Timetag: Immediately, Elements: p.Elements})
+			}
+			return
+		}
+		for _, element := range p.Elements {
+			s.collect(element, now, messages)
+		}
+	}
+}
+
+// schedule handles the packet after the given delay, unless the server is closed before.
+func (s *Server) schedule(delay time.Duration, p Packet) {
+	s.timerMutex.Lock()
+	defer s.timerMutex.Unlock()
+
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+
+	s.wg.Add(1)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		defer s.wg.Done()
+
+		s.timerMutex.Lock()
+		delete(s.timers, timer)
+		s.timerMutex.Unlock()
+
+		select {
+		case <-s.stop:
+		default:
+			s.Handle(p)
+		}
+	})
+	s.timers[timer] = struct{}{}
+}
+
+// dispatch applies all messages, and notifies the changed devices afterwards.
+// This way all messages of a bundle result in a single update per device.
+func (s *Server) dispatch(messages []*Message) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	changed := make(map[*device]struct{})
+	for _, m := range messages {
+		parts := strings.Split(m.Address, "/")
+		if len(parts) != 5 || parts[0] != "" || !matchPart(parts[1], "light") {
+			continue
+		}
+
+		for name, d := range s.devices {
+			if !matchPart(parts[2], name) {
+				continue
+			}
+			ok, err := d.apply(parts[3], parts[4], m.Arguments)
+			if err != nil {
+				s.handleError(fmt.Errorf("message %q: %w", m.Address, err))
+			}
+			if ok {
+				changed[d] = struct{}{}
+			}
+		}
+	}
+
+	for d := range changed {
+		select {
+		case d.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// apply applies a method to all modules that match the module pattern.
+// It returns whether any module was changed.
+func (d *device) apply(modulePattern, method string, arguments []interface{}) (bool, error) {
+	numbers := make([]float64, len(arguments))
+	for i, argument := range arguments {
+		var ok bool
+		if numbers[i], ok = number(argument); !ok {
+			return false, fmt.Errorf("argument %d is not a number", i)
+		}
+	}
+
+	var wantArguments int
+	switch {
+	case matchPart(method, "rgb"), matchPart(method, "xyY"):
+		wantArguments = 3
+	case matchPart(method, "cct"), matchPart(method, "dim"):
+		wantArguments = 1
+	default:
+		return false, nil // Not our method.
+	}
+	if len(numbers) != wantArguments {
+		return false, fmt.Errorf("got %d arguments, want %d", len(numbers), wantArguments)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	changed := false
+	for i := range d.modules {
+		if !matchPart(modulePattern, strconv.Itoa(i)) {
+			continue
+		}
+		module := &d.modules[i]
+
+		switch {
+		case matchPart(method, "rgb"):
+			module.kind, module.r, module.g, module.b = colorRGB, clamp01(numbers[0]), clamp01(numbers[1]), clamp01(numbers[2])
+		case matchPart(method, "xyY"):
+			module.kind, module.x, module.y, module.lum = colorxyY, numbers[0], numbers[1], clamp01(numbers[2])
+		case matchPart(method, "cct"):
+			if numbers[0] <= 0 {
+				return changed, fmt.Errorf("invalid color temperature %v", numbers[0])
+			}
+			module.kind, module.temperature = colorCCT, numbers[0]
+		case matchPart(method, "dim"):
+			module.dim = clamp01(numbers[0])
+		}
+		changed, d.dirty = true, true
+	}
+
+	return changed, nil
+}
+
+// Serve reads OSC packets from conn until the context is cancelled or reading fails.
+// The connection is closed when Serve returns.
+func (s *Server) Serve(ctx context.Context, conn net.PacketConn) error {
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		p, err := Parse(buf[:n])
+		if err != nil {
+			s.handleError(fmt.Errorf("failed to parse packet: %w", err))
+			continue
+		}
+		s.Handle(p)
+	}
+}
+
+// ListenAndServe listens for OSC packets on the given UDP address, e.g. ":8000".
+// It blocks until the context is cancelled or reading fails.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q: %w", address, err)
+	}
+
+	return s.Serve(ctx, conn)
+}
+
+// number returns the numeric value of an OSC argument.
+// Booleans are 0 or 1.
+func number(argument interface{}) (float64, bool) {
+	switch a := argument.(type) {
+	case int32:
+		return float64(a), true
+	case int64:
+		return float64(a), true
+	case float32:
+		return float64(a), true
+	case float64:
+		return a, true
+	case bool:
+		if a {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// clamp01 limits v to the interval [0, 1].
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package osc
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// waitForVectors waits until the light has DCS vectors that are accepted by want.
+func waitForVectors(t *testing.T, l *virtual.Light, want func([]emission.DCSVector) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if want(l.Vectors()) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Light has DCS vectors %v, which are not as expected", l.Vectors())
+}
+
+// vectorNear returns whether all channels of a and b are almost equal.
+func vectorNear(a, b emission.DCSVector) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 0.01 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestServer(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	s := New(Options{MaxUpdateRate: 1000})
+	defer s.Close()
+	if err := s.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := s.Add("desk", l); err == nil {
+		t.Errorf("Adding a device twice succeeded, want error")
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Serve(ctx, conn)
+
+	sender, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() failed: %v", err)
+	}
+	defer sender.Close()
+
+	send := func(p Packet) {
+		t.Helper()
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() failed: %v", err)
+		}
+		if _, err := sender.Write(data); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	send(&Message{Address: "/light/desk/0/rgb", Arguments: []interface{}{float32(1), float32(0), float32(0)}})
+	waitForVectors(t, l, func(v []emission.DCSVector) bool {
+		return vectorNear(v[0], emission.DCSVector{1, 0, 0}) && vectorNear(v[1], emission.DCSVector{1, 1, 1})
+	})
+
+	// Dim all modules via wildcard. Integer arguments are accepted too.
+	send(&Message{Address: "/light/*/*/dim", Arguments: []interface{}{int32(0)}})
+	waitForVectors(t, l, func(v []emission.DCSVector) bool {
+		return vectorNear(v[0], emission.DCSVector{0, 0, 0}) && vectorNear(v[1], emission.DCSVector{0, 0, 0})
+	})
+
+	// A bundle in the future is applied at its time, all at once.
+	at := time.Now().Add(200 * time.Millisecond)
+	send(&Bundle{Timetag: NewTimetag(at), Elements: []Packet{
+		&Message{Address: "/light/desk/1/cct", Arguments: []interface{}{float32(2000)}},
+		&Message{Address: "/light/desk/{0,1}/dim", Arguments: []interface{}{float32(1)}},
+	}})
+	time.Sleep(50 * time.Millisecond)
+	if v := l.Vectors(); !vectorNear(v[1], emission.DCSVector{0, 0, 0}) {
+		t.Errorf("Bundle was applied before its timetag")
+	}
+	waitForVectors(t, l, func(v []emission.DCSVector) bool {
+		return vectorNear(v[0], emission.DCSVector{1, 0, 0}) && v[1][0] > 0.9 && v[1][0] > v[1][2]
+	})
+	if time.Now().Before(at) {
+		t.Errorf("Bundle was applied before its timetag")
+	}
+
+	// xyY with the chromaticity of D65 results in white.
+	d65 := emission.StandardIlluminantD65.CIE1931xyYRel()
+	send(&Message{Address: "/light/desk/0/xyY", Arguments: []interface{}{d65.X, d65.Y, 1.0}})
+	waitForVectors(t, l, func(v []emission.DCSVector) bool {
+		return vectorNear(v[0], emission.DCSVector{1, 1, 1})
+	})
+}
+
+func TestServerCoalescing(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{History: true}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	s := New(Options{MaxUpdateRate: 5})
+	defer s.Close()
+	if err := s.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	// Simulate a fast fader move.
+	for i := 1; i <= 100; i++ {
+		s.Handle(&Message{Address: "/light/desk/0/rgb", Arguments: []interface{}{float32(i) / 100, float32(0), float32(0)}})
+	}
+
+	waitForVectors(t, l, func(v []emission.DCSVector) bool {
+		return vectorNear(v[0], emission.DCSVector{1, 0, 0})
+	})
+	if n := len(l.History()); n > 2 {
+		t.Errorf("Light got %d updates, want at most 2", n)
+	}
+}
+
+func TestServerSchedule(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{History: true}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	errs := make(chan error, 4)
+	s := New(Options{MaxUpdateRate: 1000, MaxSchedule: time.Second, ErrorHandler: func(err error) { errs <- err }})
+	defer s.Close()
+	if err := s.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	red := &Message{Address: "/light/desk/0/rgb", Arguments: []interface{}{float32(1), float32(0), float32(0)}}
+
+	// Bundles too far in the future are dropped.
+	s.Handle(&Bundle{Timetag: NewTimetag(time.Now().Add(time.Hour)), Elements: []Packet{red}})
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Dropped bundle wasn't reported")
+	}
+
+	// Scheduled bundles are dropped when the server is closed.
+	s.Handle(&Bundle{Timetag: NewTimetag(time.Now().Add(100 * time.Millisecond)), Elements: []Packet{red}})
+	s.Close()
+	s.timerMutex.Lock()
+	timers := len(s.timers)
+	s.timerMutex.Unlock()
+	if timers != 0 {
+		t.Errorf("Server has %d timers after Close(), want none", timers)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if n := len(l.History()); n != 0 {
+		t.Errorf("Light got %d updates, want none", n)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import "time"
+
+// The following interfaces describe optional features of light devices.
+// Use a type assertion to check if a light device supports a feature:
+//
+//	if switcher, ok := device.(light.Switcher); ok {
+//		err := switcher.SetPower(false)
+//	}
+
+// Switcher is implemented by light devices that can be turned on and off without changing their color settings.
+type Switcher interface {
+	// SetPower turns the light device on or off.
+	// Turning a device on will restore its last state.
+	SetPower(on bool) error
+
+	// Power queries the light device for its on/off state.
+	Power() (bool, error)
+}
+
+// Identifier is implemented by light devices that can signal their physical location, e.g. by blinking.
+type Identifier interface {
+	// Identify makes the light device visibly signal itself for a short moment.
+	// The device returns to its previous state afterwards.
+	Identify() error
+}
+
+// DeviceInfo contains general information about a light device.
+// Fields that are unknown are left empty.
+type DeviceInfo struct {
+	Vendor   string // The manufacturer or brand of the device.
+	Model    string // The model name or number.
+	Firmware string // The firmware version.
+	MAC      string // The MAC address.
+}
+
+// InfoProvider is implemented by light devices that can report information about themselves.
+type InfoProvider interface {
+	// DeviceInfo queries the light device for general information.
+	DeviceInfo() (DeviceInfo, error)
+}
+
+// SceneProvider is implemented by light devices that contain built-in scenes or effects.
+type SceneProvider interface {
+	// Scenes returns the names of all scenes that the light device supports.
+	Scenes() []string
+
+	// SetScene starts the scene with the given name.
+	// This returns an error if the scene is not supported by the device.
+	SetScene(name string) error
+}
+
+// TemperatureRanger is implemented by light devices that know the range of white color temperatures they are designed for.
+type TemperatureRanger interface {
+	// TemperatureRange returns the interval [min, max] of supported color temperatures in K.
+	// If the returned bool is false, the device doesn't specify a range.
+	TemperatureRange() (min, max float64, ok bool)
+}
+
+// Uptimer is implemented by light devices that can report the time since they were started.
+// A decreasing uptime means that the device was restarted, e.g. because it was power cycled by a wall switch.
+type Uptimer interface {
+	// Uptime queries the light device for the time since it was started.
+	Uptime() (time.Duration, error)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package circadian
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to a scheduler.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock returns the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// SimulatedClock is a clock that only changes when it is set or advanced.
+// This is useful for tests and to preview a schedule.
+//
+// The zero value is a clock at the zero time.
+type SimulatedClock struct {
+	mutex sync.Mutex
+	t     time.Time
+}
+
+// NewSimulatedClock returns a simulated clock that starts at the given time.
+func NewSimulatedClock(t time.Time) *SimulatedClock {
+	return &SimulatedClock{t: t}
+}
+
+// Now implements the Clock interface.
+func (c *SimulatedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.t
+}
+
+// Set sets the clock to the given time.
+func (c *SimulatedClock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.t = t
+}
+
+// Advance moves the clock forward by d.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.t = c.t.Add(d)
+}
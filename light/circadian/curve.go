@@ -0,0 +1,59 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package circadian
+
+import "sort"
+
+// CurvePoint is a single point of a curve.
+type CurvePoint struct {
+	Elevation float64 // Solar elevation in degrees.
+	Value     float64
+}
+
+// Curve maps the solar elevation to some value.
+// The points have to be sorted by elevation, values between two points are interpolated linearly.
+// Elevations outside of the curve use the value of the nearest point.
+type Curve []CurvePoint
+
+// DefaultTemperatureCurve maps the solar elevation to a color temperature in K.
+// It goes from a dim amber at night over warm white at dusk to cool daylight at noon.
+var DefaultTemperatureCurve = Curve{
+	{Elevation: -12, Value: 1900},
+	{Elevation: -6, Value: 2200},
+	{Elevation: 0, Value: 2700},
+	{Elevation: 10, Value: 4000},
+	{Elevation: 30, Value: 5500},
+	{Elevation: 50, Value: 6500},
+}
+
+// DefaultBrightnessCurve maps the solar elevation to a brightness factor in the range [0, 1].
+var DefaultBrightnessCurve = Curve{
+	{Elevation: -12, Value: 0.1},
+	{Elevation: -6, Value: 0.3},
+	{Elevation: 0, Value: 0.7},
+	{Elevation: 15, Value: 1},
+}
+
+// At returns the value of the curve at the given solar elevation.
+// An empty curve returns 0.
+func (c Curve) At(elevation float64) float64 {
+	if len(c) == 0 {
+		return 0
+	}
+
+	i := sort.Search(len(c), func(i int) bool { return c[i].Elevation > elevation })
+	switch i {
+	case 0:
+		return c[0].Value
+	case len(c):
+		return c[len(c)-1].Value
+	}
+
+	a, b := c[i-1], c[i]
+	t := (elevation - a.Elevation) / (b.Elevation - a.Elevation)
+
+	return a.Value + (b.Value-a.Value)*t
+}
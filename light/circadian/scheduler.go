@@ -0,0 +1,253 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package circadian lets light devices follow the natural daylight.
+//
+// The color temperature and brightness are computed from the position of the sun at a given location, so no network connection is needed.
+package circadian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Room is a light device that is driven by a scheduler.
+type Room struct {
+	Name  string
+	Light light.Light
+
+	// The luminance in lumen at a brightness of 1.
+	Luminance float64
+
+	// Maps the solar elevation to a brightness factor in the range [0, 1].
+	// Defaults to DefaultBrightnessCurve if nil.
+	Brightness Curve
+
+	// Maps the solar elevation to a color temperature in K.
+	// Defaults to DefaultTemperatureCurve if nil.
+	Temperature Curve
+}
+
+// Options contains the parameters of a scheduler.
+type Options struct {
+	Location Location
+
+	// The source of the current time.
+	// Defaults to the system time if nil.
+	Clock Clock
+
+	// The time between two updates.
+	// Defaults to 1 minute if zero.
+	Interval time.Duration
+
+	// The maximum allowed difference of any DCS channel between the last set and the queried state.
+	// Any bigger difference is seen as a manual override.
+	// Defaults to 0.02 if zero.
+	Tolerance float64
+
+	// The time after which a manually overridden room is driven by the scheduler again.
+	// If zero, rooms stay overridden until Resume is called.
+	OverrideTimeout time.Duration
+}
+
+// roomState contains the state of a single room.
+type roomState struct {
+	Room
+
+	lastVectors  []emission.DCSVector // The last set state in DCS. Nil if nothing was set yet.
+	overriddenAt time.Time            // The time when a manual override was detected. Zero if the room isn't overridden.
+}
+
+// Scheduler drives light devices so that they follow the natural daylight.
+//
+// If the state of a light is changed by someone else (e.g. by an app or a remote), the room is seen as manually overridden and the scheduler stops to drive it.
+type Scheduler struct {
+	options Options
+
+	mutex sync.Mutex
+	rooms []*roomState
+}
+
+// NewScheduler returns a scheduler for the given rooms.
+// Call Run or Step to actually drive the lights.
+func NewScheduler(options Options, rooms ...Room) *Scheduler {
+	if options.Clock == nil {
+		options.Clock = realClock{}
+	}
+	if options.Interval <= 0 {
+		options.Interval = time.Minute
+	}
+	if options.Tolerance <= 0 {
+		options.Tolerance = 0.02
+	}
+
+	s := &Scheduler{options: options}
+	for _, room := range rooms {
+		if room.Brightness == nil {
+			room.Brightness = DefaultBrightnessCurve
+		}
+		if room.Temperature == nil {
+			room.Temperature = DefaultTemperatureCurve
+		}
+		s.rooms = append(s.rooms, &roomState{Room: room})
+	}
+
+	return s
+}
+
+// Value returns the emission value of the given room at the given time.
+//
+// Temperatures of 4000 K and above result in standard illuminants of the D series, lower temperatures result in black body radiators.
+func (s *Scheduler) Value(room Room, t time.Time) emission.Value {
+	elevation := s.options.Location.SolarElevation(t)
+
+	brightness, temperature := room.Brightness, room.Temperature
+	if brightness == nil {
+		brightness = DefaultBrightnessCurve
+	}
+	if temperature == nil {
+		temperature = DefaultTemperatureCurve
+	}
+
+	luminance := room.Luminance * math.Min(math.Max(brightness.At(elevation), 0), 1)
+	cct := temperature.At(elevation)
+	if cct >= 4000 {
+		return emission.StandardIlluminantDSeries{Temperature: cct, Luminance: luminance}
+	}
+	return emission.BlackBodyFixed{Temperature: math.Max(cct, 1667), Luminance: luminance}
+}
+
+// Run updates all rooms regularly until ctx is cancelled.
+// Errors of single updates are ignored, as the device may be temporarily offline.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Step()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step updates all rooms once.
+// All rooms are updated even if some of them fail, the first error is returned.
+func (s *Scheduler) Step() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.options.Clock.Now()
+
+	var firstErr error
+	for _, room := range s.rooms {
+		if err := s.step(room, now); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to update room %q: %w", room.Name, err)
+		}
+	}
+
+	return firstErr
+}
+
+// step updates a single room.
+func (s *Scheduler) step(room *roomState, now time.Time) error {
+	if !room.overriddenAt.IsZero() {
+		if s.options.OverrideTimeout <= 0 || now.Sub(room.overriddenAt) < s.options.OverrideTimeout {
+			return nil
+		}
+		room.overriddenAt, room.lastVectors = time.Time{}, nil
+	}
+
+	colorProfiles := room.Light.ColorProfiles()
+
+	// Check for manual changes since the last update.
+	if room.lastVectors != nil {
+		vectors := make([]emission.DCSVector, len(colorProfiles))
+		receivers := make([]emission.ValueReceiver, 0, len(vectors))
+		for i := range vectors {
+			receivers = append(receivers, &vectors[i])
+		}
+
+		err := room.Light.GetColors(receivers...)
+		if err != nil && !errors.Is(err, light.ErrNotRepresentable) {
+			return err
+		}
+		if err != nil || !s.matches(room.lastVectors, vectors) {
+			room.overriddenAt = now
+			return nil
+		}
+	}
+
+	value := s.Value(room.Room, now)
+	vectors := make([]emission.DCSVector, 0, len(colorProfiles))
+	values := make([]emission.Value, 0, len(colorProfiles))
+	for _, colorProfile := range colorProfiles {
+		vector := value.IntoDCS(colorProfile)
+		vectors, values = append(vectors, vector), append(values, vector)
+	}
+
+	if err := room.Light.SetColors(values...); err != nil {
+		return err
+	}
+	room.lastVectors = vectors
+
+	return nil
+}
+
+// matches returns whether the two lists of vectors are equal within the tolerance.
+func (s *Scheduler) matches(a, b []emission.DCSVector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Channels() != b[i].Channels() {
+			return false
+		}
+		for j := range a[i] {
+			if math.Abs(a[i][j]-b[i][j]) > s.options.Tolerance {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Overridden returns whether the room with the given name was manually overridden.
+func (s *Scheduler) Overridden(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, room := range s.rooms {
+		if room.Name == name {
+			return !room.overriddenAt.IsZero()
+		}
+	}
+
+	return false
+}
+
+// Resume lets the scheduler drive the room with the given name again, after it was manually overridden.
+// The room is updated with the next step.
+func (s *Scheduler) Resume(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, room := range s.rooms {
+		if room.Name == name {
+			room.overriddenAt, room.lastVectors = time.Time{}, nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package circadian
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func TestScheduler(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	berlin := time.FixedZone("CEST", 2*3600)
+	clock := NewSimulatedClock(time.Date(2022, 6, 21, 13, 0, 0, 0, berlin))
+	scheduler := NewScheduler(Options{
+		Location:        Location{Latitude: 52.52, Longitude: 13.405},
+		Clock:           clock,
+		OverrideTimeout: time.Hour,
+	}, Room{Name: "office", Light: l, Luminance: 500})
+
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	noon := l.Vectors()[0]
+	if noon[2] < noon[0]*0.9 {
+		t.Errorf("Light at noon is %v, want it to be cool white", noon)
+	}
+
+	clock.Set(time.Date(2022, 6, 21, 23, 30, 0, 0, berlin))
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	night := l.Vectors()[0]
+	if night[2] >= night[0]*0.5 || night.ComponentSum() >= noon.ComponentSum() {
+		t.Errorf("Light at night is %v, want it to be dimmer and warmer than %v", night, noon)
+	}
+
+	// Simulate a manual change by someone else.
+	manual := emission.DCSVector{0, 1, 0}
+	if err := l.SetColors(manual); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	if !scheduler.Overridden("office") {
+		t.Errorf("Manual change was not detected")
+	}
+	if got := l.Vectors()[0]; got[1] != 1 {
+		t.Errorf("Scheduler changed manually overridden light to %v", got)
+	}
+
+	// The override times out.
+	clock.Advance(time.Hour)
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	if scheduler.Overridden("office") {
+		t.Errorf("Override didn't time out")
+	}
+	if got := l.Vectors()[0]; got[1] == 1 {
+		t.Errorf("Scheduler didn't update the light after the override timed out")
+	}
+
+	// Resume works before the timeout.
+	if err := l.SetColors(manual); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	scheduler.Resume("office")
+	if err := scheduler.Step(); err != nil {
+		t.Fatalf("Step() failed: %v", err)
+	}
+	if scheduler.Overridden("office") {
+		t.Errorf("Resume() didn't reset the override")
+	}
+}
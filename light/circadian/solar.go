@@ -0,0 +1,45 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package circadian
+
+import (
+	"math"
+	"time"
+)
+
+// Location is a position on earth.
+type Location struct {
+	Latitude  float64 // Latitude in degrees, positive values are north of the equator.
+	Longitude float64 // Longitude in degrees, positive values are east of Greenwich.
+}
+
+// SolarElevation returns the elevation of the sun above the horizon in degrees at the given location and time.
+// Negative values mean that the sun is below the horizon.
+//
+// This uses the low precision formulas of the Astronomical Almanac, which are accurate to about 1 degree between 1950 and 2050.
+// Atmospheric refraction is not taken into account.
+func (l Location) SolarElevation(t time.Time) float64 {
+	const rad = math.Pi / 180
+
+	// Days since J2000.0.
+	n := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5 - 2451545.0
+
+	meanLongitude := math.Mod(280.460+0.9856474*n, 360)
+	meanAnomaly := math.Mod(357.528+0.9856003*n, 360) * rad
+	eclipticLongitude := (meanLongitude + 1.915*math.Sin(meanAnomaly) + 0.020*math.Sin(2*meanAnomaly)) * rad
+	obliquity := (23.439 - 0.0000004*n) * rad
+
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLongitude), math.Cos(eclipticLongitude))
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(eclipticLongitude))
+
+	siderealTime := math.Mod(18.697374558+24.06570982441908*n, 24) * 15 // Greenwich mean sidereal time in degrees.
+	hourAngle := (siderealTime+l.Longitude)*rad - rightAscension
+
+	latitude := l.Latitude * rad
+	elevation := math.Asin(math.Sin(latitude)*math.Sin(declination) + math.Cos(latitude)*math.Cos(declination)*math.Cos(hourAngle))
+
+	return elevation / rad
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package circadian
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSolarElevation(t *testing.T) {
+	tests := []struct {
+		location Location
+		time     time.Time
+		want     float64
+	}{
+		{Location{0, 0}, time.Date(2022, 3, 20, 12, 7, 0, 0, time.UTC), 89.7},                                    // Equinox, equator.
+		{Location{0, 0}, time.Date(2022, 3, 20, 0, 7, 0, 0, time.UTC), -89.7},                                    // Equinox, equator, midnight.
+		{Location{52.52, 13.405}, time.Date(2022, 6, 21, 11, 8, 0, 0, time.UTC), 60.9},                           // Berlin, summer solstice, noon.
+		{Location{52.52, 13.405}, time.Date(2022, 12, 21, 11, 8, 0, 0, time.UTC), 14.0},                          // Berlin, winter solstice, noon.
+		{Location{-33.87, 151.21}, time.Date(2022, 12, 21, 12, 53, 0, 0, time.FixedZone("AEDT", 11*3600)), 79.5}, // Sydney, summer solstice.
+	}
+
+	for i, test := range tests {
+		if got := test.location.SolarElevation(test.time); math.Abs(got-test.want) > 1 {
+			t.Errorf("Test %d: SolarElevation() returned %v, want %v", i, got, test.want)
+		}
+	}
+}
+
+func TestCurve(t *testing.T) {
+	curve := Curve{{Elevation: -10, Value: 1}, {Elevation: 10, Value: 3}}
+
+	tests := []struct {
+		elevation, want float64
+	}{
+		{-20, 1}, {-10, 1}, {0, 2}, {5, 2.5}, {10, 3}, {90, 3},
+	}
+
+	for _, test := range tests {
+		if got := curve.At(test.elevation); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("At(%v) returned %v, want %v", test.elevation, got, test.want)
+		}
+	}
+}
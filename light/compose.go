@@ -0,0 +1,189 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Composite combines several light devices into a single light device with many modules.
+// The modules of all members are flattened in order.
+//
+// Use Compose() to create a composite light.
+type Composite struct {
+	members []Light
+}
+
+// Check implementation of Light and optional interfaces.
+var (
+	_ Light        = &Composite{}
+	_ NativeStater = &Composite{}
+)
+
+// Compose returns a light device that contains all modules of the given light devices in order.
+//
+// Calls to SetColors and GetColors are split across the members, and are executed concurrently.
+// At least one light device has to be given, as a light device must have at least one module.
+//
+// This panics if no or a nil light device is given.
+//
+//	room := light.Compose(strip, bulb1, bulb2)
+//	room.Modules() // Returns strip.Modules() + 2.
+func Compose(lights ...Light) Light {
+	if len(lights) == 0 {
+		panic("light: Compose needs at least one light device")
+	}
+
+	members := make([]Light, len(lights))
+	for i, light := range lights {
+		if light == nil {
+			panic(fmt.Sprintf("light: Compose light device %d is nil", i))
+		}
+		members[i] = light
+	}
+
+	return &Composite{
+		members: members,
+	}
+}
+
+// Members returns the light devices that this composite light consists of.
+func (c *Composite) Members() []Light {
+	members := make([]Light, len(c.members))
+	copy(members, c.members)
+	return members
+}
+
+// forEachMember calls f concurrently for every member with the start index of the member's modules.
+// The first error in member order is returned.
+func (c *Composite) forEachMember(f func(member Light, start, modules int) error) error {
+	errs := make([]error, len(c.members))
+
+	var wg sync.WaitGroup
+	start := 0
+	for i, member := range c.members {
+		modules := member.Modules()
+
+		wg.Add(1)
+		go func(i int, member Light, start, modules int) {
+			defer wg.Done()
+			errs[i] = f(member, start, modules)
+		}(i, member, start, modules)
+
+		start += modules
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("member %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+func (c *Composite) SetColors(emissionValues ...emission.Value) error {
+	if modules := c.Modules(); len(emissionValues) > modules {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), modules)
+	}
+
+	return c.forEachMember(func(member Light, start, modules int) error {
+		// Members that get no value at all are turned off, as SetColors() without values does exactly that.
+		return member.SetColors(subSlice(emissionValues, start, modules)...)
+	})
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (c *Composite) GetColors(emissionValues ...emission.ValueReceiver) error {
+	if modules := c.Modules(); len(emissionValues) > modules {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), modules)
+	}
+
+	return c.forEachMember(func(member Light, start, modules int) error {
+		if start >= len(emissionValues) {
+			return nil
+		}
+		end := start + modules
+		if end > len(emissionValues) {
+			end = len(emissionValues)
+		}
+		return member.GetColors(emissionValues[start:end]...)
+	})
+}
+
+// Modules returns the number of modules.
+// This is the sum of the modules of all members.
+func (c *Composite) Modules() int {
+	modules := 0
+	for _, member := range c.members {
+		modules += member.Modules()
+	}
+	return modules
+}
+
+// ColorProfiles returns the color profiles of every module in this device.
+// This is the concatenation of the color profiles of all members.
+func (c *Composite) ColorProfiles() []emission.ColorProfile {
+	result := make([]emission.ColorProfile, 0, c.Modules())
+	for _, member := range c.members {
+		result = append(result, member.ColorProfiles()...)
+	}
+	return result
+}
+
+// subSlice returns the part of values in the interval [start, start+length), limited to the length of values.
+func subSlice(values []emission.Value, start, length int) []emission.Value {
+	if start >= len(values) {
+		return nil
+	}
+	end := start + length
+	if end > len(values) {
+		end = len(values)
+	}
+	return values[start:end]
+}
+
+// NativeState returns the captured state of all members.
+// This implements the NativeStater interface.
+func (c *Composite) NativeState() (json.RawMessage, error) {
+	states := make([]LightState, len(c.members))
+	err := forEachLight(context.Background(), c.members, func(i int, member Light) error {
+		var err error
+		states[i], err = captureLight(member)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(states)
+}
+
+// RestoreNativeState restores the state of all members.
+// This implements the NativeStater interface.
+func (c *Composite) RestoreNativeState(state json.RawMessage) error {
+	var states []LightState
+	if err := json.Unmarshal(state, &states); err != nil {
+		return err
+	}
+	if len(states) != len(c.members) {
+		return fmt.Errorf("got state of %d members, this composite light has %d", len(states), len(c.members))
+	}
+
+	return forEachLight(context.Background(), c.members, func(i int, member Light) error {
+		return states[i].restore(member)
+	})
+}
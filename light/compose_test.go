@@ -0,0 +1,94 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+// newVirtualLight returns a virtual light with the given number of modules.
+func newVirtualLight(t *testing.T, modules int) *virtual.Light {
+	colorProfiles := make([]emission.ColorProfile, modules)
+	for i := range colorProfiles {
+		colorProfiles[i] = virtual.DefaultColorProfile
+	}
+
+	l, err := virtual.NewLight(virtual.Options{}, colorProfiles...)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	return l
+}
+
+func TestComposeConformance(t *testing.T) {
+	lighttest.Run(t, func(t *testing.T) light.Light {
+		return light.Compose(newVirtualLight(t, 2), newVirtualLight(t, 1))
+	})
+}
+
+func TestComposeInvalid(t *testing.T) {
+	expectPanic(t, "light.Compose() without members", func() { light.Compose() })
+	expectPanic(t, "light.Compose() with a nil member", func() { light.Compose(newVirtualLight(t, 1), nil) })
+}
+
+func TestComposeSplit(t *testing.T) {
+	a, b := newVirtualLight(t, 2), newVirtualLight(t, 1)
+	composite := light.Compose(a, b)
+
+	red, green, blue := emission.DCSVector{1, 0, 0}, emission.DCSVector{0, 1, 0}, emission.DCSVector{0, 0, 1}
+	off := emission.DCSVector{0, 0, 0}
+
+	tests := []struct {
+		name   string
+		values []emission.Value
+		wantA  []emission.DCSVector
+		wantB  []emission.DCSVector
+	}{
+		{"All modules", []emission.Value{red, green, blue}, []emission.DCSVector{red, green}, []emission.DCSVector{blue}},
+		{"Member boundary", []emission.Value{blue, red}, []emission.DCSVector{blue, red}, []emission.DCSVector{off}},
+		{"Fewer values", []emission.Value{green}, []emission.DCSVector{green, off}, []emission.DCSVector{off}},
+		{"No values", nil, []emission.DCSVector{off, off}, []emission.DCSVector{off}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := composite.SetColors(tt.values...); err != nil {
+				t.Fatalf("SetColors() failed: %v", err)
+			}
+
+			if got := a.Vectors(); !reflect.DeepEqual(got, tt.wantA) {
+				t.Errorf("First member has %v, want %v", got, tt.wantA)
+			}
+			if got := b.Vectors(); !reflect.DeepEqual(got, tt.wantB) {
+				t.Errorf("Second member has %v, want %v", got, tt.wantB)
+			}
+
+			// Read back fewer values than there are modules, across the member boundary.
+			for n := 0; n <= composite.Modules(); n++ {
+				received := make([]emission.DCSVector, n)
+				receivers := make([]emission.ValueReceiver, n)
+				for i := range received {
+					receivers[i] = &received[i]
+				}
+				if err := composite.GetColors(receivers...); err != nil {
+					t.Fatalf("GetColors() with %d values failed: %v", n, err)
+				}
+
+				want := append(append([]emission.DCSVector{}, tt.wantA...), tt.wantB...)[:n]
+				if !reflect.DeepEqual(received, want) {
+					t.Errorf("GetColors() with %d values returned %v, want %v", n, received, want)
+				}
+			}
+		})
+	}
+}
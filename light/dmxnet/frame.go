@@ -0,0 +1,266 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package dmxnet encodes and decodes DMX512 frames that are transported over Ethernet via Art-Net or E1.31 (sACN).
+package dmxnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Default UDP ports of the protocols.
+const (
+	ArtNetPort = 6454
+	E131Port   = 5568
+)
+
+// Slots is the maximum number of channels in a DMX512 universe.
+const Slots = 512
+
+// DefaultPriority is the default E1.31 source priority.
+const DefaultPriority = 100
+
+// Protocol is a protocol that transports DMX512 frames over UDP.
+type Protocol int
+
+// Supported protocols.
+const (
+	ProtocolArtNet Protocol = iota // Art-Net 4, ArtDmx packets.
+	ProtocolE131                   // ANSI E1.31 (Streaming ACN), data packets.
+)
+
+// String returns the name of the protocol.
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolArtNet:
+		return "Art-Net"
+	case ProtocolE131:
+		return "E1.31"
+	}
+	return fmt.Sprintf("Protocol(%d)", int(p))
+}
+
+// ErrIgnored is returned by Decode for valid packets of the protocols that don't contain DMX512 data, e.g. ArtPoll or E1.31 synchronization packets.
+var ErrIgnored = errors.New("packet doesn't contain DMX512 data")
+
+// Frame is a single DMX512 frame of one universe.
+type Frame struct {
+	Protocol Protocol
+
+	// The universe number.
+	// For Art-Net this is the 15 bit port address, for E1.31 it's in the range of [1, 63999].
+	Universe uint16
+
+	// The sequence number that is used to detect out of order packets.
+	// For Art-Net 0 disables sequence checking.
+	Sequence uint8
+
+	Priority   uint8    // The E1.31 source priority in the range of [0, 200].
+	CID        [16]byte // The E1.31 component identifier of the source.
+	SourceName string   // The E1.31 source name.
+	Terminated bool     // The E1.31 source stopped sending this universe.
+
+	// The values of all slots without the start code.
+	Data []byte
+}
+
+// Art-Net header constants.
+var artNetID = []byte("Art-Net\x00")
+
+const (
+	artNetOpDmx       = 0x5000
+	artNetProtVer     = 14
+	artNetHeaderBytes = 18
+)
+
+// E1.31 header constants.
+var e131ID = []byte("ASC-E1.17\x00\x00\x00")
+
+const (
+	e131VectorRootData    = 0x00000004
+	e131VectorFramingData = 0x00000002
+	e131VectorDMP         = 0x02
+	e131HeaderBytes       = 126
+	e131OptionTerminated  = 0x40
+	e131SourceNameBytes   = 64
+)
+
+// Decode returns the DMX512 frame of an Art-Net or E1.31 packet.
+// The protocol is detected from the packet header.
+//
+// Packets of both protocols that don't contain DMX512 data result in ErrIgnored.
+func Decode(packet []byte) (Frame, error) {
+	switch {
+	case bytes.HasPrefix(packet, artNetID):
+		return decodeArtNet(packet)
+	case len(packet) >= 16 && bytes.Equal(packet[4:16], e131ID):
+		return decodeE131(packet)
+	}
+
+	return Frame{}, fmt.Errorf("unknown packet format")
+}
+
+// decodeArtNet decodes an ArtDmx packet.
+func decodeArtNet(packet []byte) (Frame, error) {
+	if len(packet) < 10 {
+		return Frame{}, fmt.Errorf("Art-Net packet too short")
+	}
+	if opCode := binary.LittleEndian.Uint16(packet[8:10]); opCode != artNetOpDmx {
+		return Frame{}, ErrIgnored
+	}
+	if len(packet) < artNetHeaderBytes {
+		return Frame{}, fmt.Errorf("ArtDmx packet too short")
+	}
+
+	length := int(binary.BigEndian.Uint16(packet[16:18]))
+	if length > Slots || len(packet) < artNetHeaderBytes+length {
+		return Frame{}, fmt.Errorf("ArtDmx packet has invalid length %d", length)
+	}
+
+	return Frame{
+		Protocol: ProtocolArtNet,
+		Universe: uint16(packet[15]&0x7f)<<8 | uint16(packet[14]),
+		Sequence: packet[12],
+		Data:     append([]byte(nil), packet[artNetHeaderBytes:artNetHeaderBytes+length]...),
+	}, nil
+}
+
+// decodeE131 decodes an E1.31 data packet.
+func decodeE131(packet []byte) (Frame, error) {
+	if len(packet) < 22 {
+		return Frame{}, fmt.Errorf("E1.31 packet too short")
+	}
+	if vector := binary.BigEndian.Uint32(packet[18:22]); vector != e131VectorRootData {
+		return Frame{}, ErrIgnored
+	}
+	if len(packet) < e131HeaderBytes {
+		return Frame{}, fmt.Errorf("E1.31 data packet too short")
+	}
+	if vector := binary.BigEndian.Uint32(packet[40:44]); vector != e131VectorFramingData {
+		return Frame{}, fmt.Errorf("unexpected E1.31 framing layer vector %#x", vector)
+	}
+	if packet[117] != e131VectorDMP || packet[118] != 0xa1 {
+		return Frame{}, fmt.Errorf("unexpected E1.31 DMP layer vector or data type")
+	}
+	if packet[125] != 0 {
+		return Frame{}, ErrIgnored // Alternate start codes don't contain levels.
+	}
+
+	count := int(binary.BigEndian.Uint16(packet[123:125]))
+	if count < 1 || count > Slots+1 || len(packet) < e131HeaderBytes-1+count {
+		return Frame{}, fmt.Errorf("E1.31 data packet has invalid property value count %d", count)
+	}
+
+	f := Frame{
+		Protocol:   ProtocolE131,
+		Universe:   binary.BigEndian.Uint16(packet[113:115]),
+		Sequence:   packet[111],
+		Priority:   packet[108],
+		SourceName: string(bytes.TrimRight(packet[44:44+e131SourceNameBytes], "\x00")),
+		Terminated: packet[112]&e131OptionTerminated != 0,
+		Data:       append([]byte(nil), packet[e131HeaderBytes:e131HeaderBytes-1+count]...),
+	}
+	copy(f.CID[:], packet[22:38])
+
+	return f, nil
+}
+
+// Encode returns the frame as Art-Net or E1.31 packet, depending on f.Protocol.
+func (f Frame) Encode() ([]byte, error) {
+	if len(f.Data) > Slots {
+		return nil, fmt.Errorf("frame contains %d slots, maximum is %d", len(f.Data), Slots)
+	}
+
+	switch f.Protocol {
+	case ProtocolArtNet:
+		return f.encodeArtNet()
+	case ProtocolE131:
+		return f.encodeE131()
+	}
+
+	return nil, fmt.Errorf("unsupported protocol %v", f.Protocol)
+}
+
+// encodeArtNet returns the frame as ArtDmx packet.
+func (f Frame) encodeArtNet() ([]byte, error) {
+	if f.Universe > 0x7fff {
+		return nil, fmt.Errorf("Art-Net port address %d out of range", f.Universe)
+	}
+
+	// The length has to be even and at least 2.
+	length := len(f.Data) + len(f.Data)%2
+	if length < 2 {
+		length = 2
+	}
+
+	packet := make([]byte, artNetHeaderBytes+length)
+	copy(packet, artNetID)
+	binary.LittleEndian.PutUint16(packet[8:10], artNetOpDmx)
+	binary.BigEndian.PutUint16(packet[10:12], artNetProtVer)
+	packet[12] = f.Sequence
+	packet[14] = byte(f.Universe)
+	packet[15] = byte(f.Universe >> 8)
+	binary.BigEndian.PutUint16(packet[16:18], uint16(length))
+	copy(packet[artNetHeaderBytes:], f.Data)
+
+	return packet, nil
+}
+
+// encodeE131 returns the frame as E1.31 data packet.
+func (f Frame) encodeE131() ([]byte, error) {
+	if f.Universe < 1 || f.Universe > 63999 {
+		return nil, fmt.Errorf("E1.31 universe %d out of range", f.Universe)
+	}
+	if len(f.SourceName) >= e131SourceNameBytes {
+		return nil, fmt.Errorf("E1.31 source name %q is too long", f.SourceName)
+	}
+
+	length := e131HeaderBytes + len(f.Data)
+	packet := make([]byte, length)
+
+	// Root layer.
+	binary.BigEndian.PutUint16(packet[0:2], 0x0010)
+	copy(packet[4:16], e131ID)
+	binary.BigEndian.PutUint16(packet[16:18], 0x7000|uint16(length-16))
+	binary.BigEndian.PutUint32(packet[18:22], e131VectorRootData)
+	copy(packet[22:38], f.CID[:])
+
+	// Framing layer.
+	binary.BigEndian.PutUint16(packet[38:40], 0x7000|uint16(length-38))
+	binary.BigEndian.PutUint32(packet[40:44], e131VectorFramingData)
+	copy(packet[44:44+e131SourceNameBytes], f.SourceName)
+	packet[108] = f.Priority
+	packet[111] = f.Sequence
+	if f.Terminated {
+		packet[112] |= e131OptionTerminated
+	}
+	binary.BigEndian.PutUint16(packet[113:115], f.Universe)
+
+	// DMP layer.
+	binary.BigEndian.PutUint16(packet[115:117], 0x7000|uint16(length-115))
+	packet[117] = e131VectorDMP
+	packet[118] = 0xa1
+	binary.BigEndian.PutUint16(packet[121:123], 1)
+	binary.BigEndian.PutUint16(packet[123:125], uint16(len(f.Data)+1))
+	copy(packet[e131HeaderBytes:], f.Data)
+
+	return packet, nil
+}
+
+// E131MulticastAddress returns the multicast address that E1.31 frames of the given universe are sent to.
+func E131MulticastAddress(universe uint16) *net.UDPAddr {
+	return &net.UDPAddr{IP: net.IPv4(239, 255, byte(universe>>8), byte(universe)), Port: E131Port}
+}
+
+// SequenceNewer returns whether the sequence number b follows a, or if the stream was restarted.
+// Packets for which this returns false should be discarded, as described in E1.31 section 6.7.2.
+func SequenceNewer(a, b uint8) bool {
+	diff := int8(b - a)
+	return diff > 0 || diff <= -20
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmxnet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	data := make([]byte, Slots)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	tests := []Frame{
+		{Protocol: ProtocolArtNet, Universe: 0x1234, Sequence: 5, Data: data},
+		{Protocol: ProtocolArtNet, Universe: 0, Data: []byte{1, 2}},
+		{Protocol: ProtocolE131, Universe: 1, Sequence: 200, Priority: DefaultPriority, CID: [16]byte{1, 2, 3}, SourceName: "desk", Data: data},
+		{Protocol: ProtocolE131, Universe: 63999, Terminated: true, Data: []byte{255}},
+	}
+
+	for _, want := range tests {
+		packet, err := want.Encode()
+		if err != nil {
+			t.Fatalf("Encode() of %v frame failed: %v", want.Protocol, err)
+		}
+		got, err := Decode(packet)
+		if err != nil {
+			t.Fatalf("Decode() of %v frame failed: %v", want.Protocol, err)
+		}
+
+		if got.Protocol != want.Protocol || got.Universe != want.Universe || got.Sequence != want.Sequence || got.Priority != want.Priority ||
+			got.CID != want.CID || got.SourceName != want.SourceName || got.Terminated != want.Terminated || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("Decode(Encode()) = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestDecodeOddArtNetLength(t *testing.T) {
+	packet, err := Frame{Protocol: ProtocolArtNet, Universe: 1, Data: []byte{1, 2, 3}}.Encode()
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	f, err := Decode(packet)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !bytes.Equal(f.Data, []byte{1, 2, 3, 0}) {
+		t.Errorf("Got data %v, want it padded to an even length", f.Data)
+	}
+}
+
+func TestDecodeIgnored(t *testing.T) {
+	artPoll := append([]byte("Art-Net\x00"), 0x00, 0x20, 0, 14, 0, 0)
+	if _, err := Decode(artPoll); !errors.Is(err, ErrIgnored) {
+		t.Errorf("Decode() of ArtPoll returned %v, want %v", err, ErrIgnored)
+	}
+
+	if _, err := Decode([]byte("garbage")); err == nil || errors.Is(err, ErrIgnored) {
+		t.Errorf("Decode() of garbage returned %v, want error", err)
+	}
+}
+
+func TestSequenceNewer(t *testing.T) {
+	tests := []struct {
+		a, b uint8
+		want bool
+	}{
+		{1, 2, true},
+		{255, 0, true},
+		{5, 5, false},
+		{5, 4, false},
+		{30, 10, true}, // Considered a restart of the stream.
+	}
+
+	for _, test := range tests {
+		if got := SequenceNewer(test.a, test.b); got != test.want {
+			t.Errorf("SequenceNewer(%d, %d) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package all registers all light drivers of this module, so that every URI scheme can be used with light.Open().
+//
+//	import _ "github.com/Dadido3/D3iot/light/drivers/all"
+//
+// New drivers should be added here, so they are available in all tools.
+package all
+
+import (
+	_ "github.com/Dadido3/D3iot/light/drivers/dmx"
+	_ "github.com/Dadido3/D3iot/light/drivers/dmx/ofl"
+	_ "github.com/Dadido3/D3iot/light/drivers/hue"
+	_ "github.com/Dadido3/D3iot/light/drivers/lifx"
+	_ "github.com/Dadido3/D3iot/light/drivers/virtual"
+	_ "github.com/Dadido3/D3iot/light/drivers/wiz"
+)
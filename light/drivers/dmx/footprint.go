@@ -0,0 +1,183 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// ChannelType defines what a DMX channel of a fixture controls.
+type ChannelType int
+
+// Supported channel types.
+const (
+	ChannelEmitter ChannelType = iota // Controls one emitter, which is a DCS channel of the color profile.
+	ChannelDimmer                     // Scales all emitters linearly.
+	ChannelFixed                      // Is always set to a fixed value, e.g. to open a shutter or to select a mode.
+)
+
+// String returns the name of the channel type.
+func (t ChannelType) String() string {
+	switch t {
+	case ChannelEmitter:
+		return "Emitter"
+	case ChannelDimmer:
+		return "Dimmer"
+	case ChannelFixed:
+		return "Fixed"
+	}
+	return fmt.Sprintf("ChannelType(%d)", int(t))
+}
+
+// Channel describes a single, or in case of 16 bit a pair of DMX channels.
+type Channel struct {
+	Type    ChannelType
+	Emitter int    // The index of the DCS channel that is controlled by a ChannelEmitter.
+	Fine    bool   // The channel has 16 bit and uses two DMX slots, the coarse one first.
+	Value   uint16 // The value of a ChannelFixed. For 8 bit channels only the lower byte is used.
+}
+
+// slots returns the number of DMX slots of the channel.
+func (c Channel) slots() int {
+	if c.Fine {
+		return 2
+	}
+	return 1
+}
+
+// Footprint is the list of DMX channels of a fixture in the order they are addressed.
+type Footprint []Channel
+
+// Slots returns the number of DMX slots that the footprint occupies.
+func (f Footprint) Slots() int {
+	var slots int
+	for _, c := range f {
+		slots += c.slots()
+	}
+	return slots
+}
+
+// Validate checks that every one of the given number of emitters is controlled by exactly one channel, and that there is at most one dimmer.
+func (f Footprint) Validate(emitters int) error {
+	controlled := make([]bool, emitters)
+	dimmers := 0
+
+	for i, c := range f {
+		switch c.Type {
+		case ChannelEmitter:
+			if c.Emitter < 0 || c.Emitter >= emitters {
+				return fmt.Errorf("channel %d controls emitter %d, but there are only %d emitters", i, c.Emitter, emitters)
+			}
+			if controlled[c.Emitter] {
+				return fmt.Errorf("emitter %d is controlled by several channels", c.Emitter)
+			}
+			controlled[c.Emitter] = true
+		case ChannelDimmer:
+			if dimmers++; dimmers > 1 {
+				return fmt.Errorf("footprint contains more than one dimmer channel")
+			}
+		case ChannelFixed:
+		default:
+			return fmt.Errorf("channel %d has unsupported type %v", i, c.Type)
+		}
+	}
+
+	for i, ok := range controlled {
+		if !ok {
+			return fmt.Errorf("emitter %d is not controlled by any channel", i)
+		}
+	}
+
+	return nil
+}
+
+// hasDimmer returns whether the footprint contains a dimmer channel.
+func (f Footprint) hasDimmer() bool {
+	for _, c := range f {
+		if c.Type == ChannelDimmer {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes the DCS vector into the DMX slots of the footprint.
+// If there is a dimmer, the emitters are scaled up so the brightest one is at full level, and the dimmer reduces them again.
+// This gives a higher resolution at low levels.
+func (f Footprint) encode(tf emission.TransferFunction, v emission.DCSVector, slots []byte) {
+	dimmer := 1.0
+	if f.hasDimmer() {
+		linV := v.ClampedAndLinearized(tf)
+		dimmer = 0
+		for _, channel := range linV {
+			dimmer = math.Max(dimmer, channel)
+		}
+		if dimmer > 0 {
+			v = linV.Scaled(1 / dimmer).ClampedAndDeLinearized(tf)
+		}
+	}
+
+	offset := 0
+	for _, c := range f {
+		var level float64
+		switch c.Type {
+		case ChannelEmitter:
+			level = v[c.Emitter]
+		case ChannelDimmer:
+			level = dimmer
+		}
+
+		switch {
+		case c.Type == ChannelFixed && c.Fine:
+			slots[offset], slots[offset+1] = byte(c.Value>>8), byte(c.Value)
+		case c.Type == ChannelFixed:
+			slots[offset] = byte(c.Value)
+		case c.Fine:
+			value := uint16(math.Round(clamp01(level) * 65535))
+			slots[offset], slots[offset+1] = byte(value>>8), byte(value)
+		default:
+			slots[offset] = byte(math.Round(clamp01(level) * 255))
+		}
+		offset += c.slots()
+	}
+}
+
+// decode returns the DCS vector that is represented by the DMX slots of the footprint.
+func (f Footprint) decode(tf emission.TransferFunction, emitters int, slots []byte) emission.DCSVector {
+	v := make(emission.DCSVector, emitters)
+	dimmer, hasDimmer := 1.0, false
+
+	offset := 0
+	for _, c := range f {
+		var level float64
+		if c.Fine {
+			level = float64(uint16(slots[offset])<<8|uint16(slots[offset+1])) / 65535
+		} else {
+			level = float64(slots[offset]) / 255
+		}
+		offset += c.slots()
+
+		switch c.Type {
+		case ChannelEmitter:
+			v[c.Emitter] = level
+		case ChannelDimmer:
+			dimmer, hasDimmer = level, true
+		}
+	}
+
+	if hasDimmer {
+		return v.ClampedAndLinearized(tf).Scaled(dimmer).ClampedAndDeLinearized(tf)
+	}
+	return v
+}
+
+// clamp01 limits v to the interval [0, 1].
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package ofl imports fixture definitions of the Open Fixture Library (https://open-fixture-library.org).
+//
+// The modes of a fixture are turned into footprints of the dmx driver, and its emitters into an initial color profile.
+// The color profile is only an estimation based on typical LED wavelengths, it can be refined by measuring the fixture with the profiler.
+package ofl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Definition is an Open Fixture Library fixture definition.
+// Only the parts that are needed to build footprints and color profiles are decoded.
+type Definition struct {
+	Name       string   `json:"name"`
+	ShortName  string   `json:"shortName"`
+	Categories []string `json:"categories"`
+
+	Physical struct {
+		Bulb struct {
+			Type             string  `json:"type"`
+			ColorTemperature float64 `json:"colorTemperature"` // In K.
+			Lumens           float64 `json:"lumens"`
+		} `json:"bulb"`
+	} `json:"physical"`
+
+	Wheels            map[string]Wheel   `json:"wheels"`
+	AvailableChannels map[string]Channel `json:"availableChannels"`
+	Modes             []ModeDefinition   `json:"modes"`
+}
+
+// Wheel is a color or gobo wheel.
+type Wheel struct {
+	Slots []struct {
+		Type string `json:"type"` // E.g. "Open", "Color", "Gobo".
+		Name string `json:"name"`
+	} `json:"slots"`
+}
+
+// Channel is a DMX channel of a fixture definition.
+type Channel struct {
+	Name               string          `json:"name"`
+	FineChannelAliases []string        `json:"fineChannelAliases"`
+	DefaultValue       json.RawMessage `json:"defaultValue"` // A DMX value or a percentage.
+	Capability         *Capability     `json:"capability"`
+	Capabilities       []Capability    `json:"capabilities"`
+}
+
+// Capability describes what a channel does in a DMX range.
+type Capability struct {
+	DMXRange      []int   `json:"dmxRange"`
+	Type          string  `json:"type"`          // E.g. "Intensity", "ColorIntensity", "ShutterStrobe", "WheelSlot".
+	Color         string  `json:"color"`         // The color of a ColorIntensity capability.
+	ShutterEffect string  `json:"shutterEffect"` // E.g. "Open", "Closed", "Strobe".
+	Wheel         string  `json:"wheel"`         // The wheel of a WheelSlot capability. Defaults to the channel name.
+	SlotNumber    float64 `json:"slotNumber"`    // The slot of a WheelSlot capability, starting at 1.
+}
+
+// ModeDefinition is a mode of a fixture definition.
+type ModeDefinition struct {
+	Name      string `json:"name"`
+	ShortName string `json:"shortName"`
+
+	// The channel keys in DMX order.
+	// Entries can be null for unused channels, or objects for matrix channel insertions.
+	Channels []json.RawMessage `json:"channels"`
+}
+
+// Load reads an Open Fixture Library fixture definition in JSON format.
+func Load(r io.Reader) (*Definition, error) {
+	var d Definition
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture definition: %w", err)
+	}
+
+	if len(d.Modes) == 0 {
+		return nil, fmt.Errorf("fixture definition %q doesn't contain any modes", d.Name)
+	}
+
+	return &d, nil
+}
+
+// LoadFile reads an Open Fixture Library fixture definition from the file at the given path.
+func LoadFile(path string) (*Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// ModeNames returns the names of all modes.
+func (d *Definition) ModeNames() []string {
+	names := make([]string, 0, len(d.Modes))
+	for _, m := range d.Modes {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// capabilities returns all capabilities of the channel.
+func (c Channel) capabilities() []Capability {
+	if c.Capability != nil {
+		return []Capability{*c.Capability}
+	}
+	return c.Capabilities
+}
+
+// defaultValue returns the default DMX value of the channel, or 0 if there is none.
+func (c Channel) defaultValue() uint16 {
+	if len(c.DefaultValue) == 0 {
+		return 0
+	}
+
+	var number float64
+	if err := json.Unmarshal(c.DefaultValue, &number); err == nil {
+		return uint16(number)
+	}
+
+	var text string
+	if err := json.Unmarshal(c.DefaultValue, &text); err == nil {
+		if percent, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64); err == nil {
+			return uint16(percent / 100 * 255)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ofl
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Emitter describes the spectrum of an emitter.
+// Either the peak wavelength and bandwidth of a narrow band emitter, or the color temperature of a white emitter is set.
+type Emitter struct {
+	Wavelength  float64 // Peak wavelength in nm.
+	Bandwidth   float64 // Full width at half maximum in nm.
+	Temperature float64 // Correlated color temperature of white emitters in K.
+}
+
+// DefaultEmitters contains typical emitters of LED fixtures for the colors of ColorIntensity capabilities.
+// White without a temperature uses the color temperature of the bulb, or 6500 K.
+var DefaultEmitters = map[string]Emitter{
+	"Red":        {Wavelength: 625, Bandwidth: 20},
+	"Green":      {Wavelength: 525, Bandwidth: 35},
+	"Blue":       {Wavelength: 465, Bandwidth: 25},
+	"Cyan":       {Wavelength: 500, Bandwidth: 30},
+	"Amber":      {Wavelength: 592, Bandwidth: 17},
+	"Yellow":     {Wavelength: 585, Bandwidth: 20},
+	"Lime":       {Wavelength: 560, Bandwidth: 100}, // Phosphor converted, so it has a broad spectrum.
+	"Indigo":     {Wavelength: 440, Bandwidth: 25},
+	"White":      {},
+	"Warm White": {Temperature: 3000},
+	"Cold White": {Temperature: 6500},
+}
+
+// primaryColors are the colors that span the gamut of a fixture, in DCS order.
+var primaryColors = []string{"Red", "Green", "Blue"}
+
+// isWhite returns whether the emitter is a white emitter.
+func (e Emitter) isWhite() bool {
+	return e.Temperature > 0 || e.Wavelength == 0
+}
+
+// color returns the color of the emitter with a luminance of 1.
+// defaultTemperature is used for white emitters without temperature.
+func (e Emitter) color(defaultTemperature float64) (emission.CIE1931XYZAbs, error) {
+	if e.isWhite() {
+		temperature := e.Temperature
+		if temperature <= 0 {
+			temperature = defaultTemperature
+		}
+		return emission.BlackBodyFixed{Temperature: temperature, Luminance: 1}.CIE1931XYZAbs(), nil
+	}
+
+	if e.Wavelength < 380 || e.Wavelength > 780 {
+		return emission.CIE1931XYZAbs{}, fmt.Errorf("wavelength %v nm is not visible", e.Wavelength)
+	}
+	bandwidth := e.Bandwidth
+	if bandwidth <= 0 {
+		bandwidth = 20
+	}
+
+	// Approximate the spectrum by a gaussian.
+	peak, sigma := e.Wavelength*1e-9, bandwidth*1e-9/(2*math.Sqrt(2*math.Ln2))
+	x, y, z := emission.StandardObserverCIE1931.IntegrateTrapezoidal(func(wavelength float64) float64 {
+		d := (wavelength - peak) / sigma
+		return math.Exp(-d * d / 2)
+	})
+	if y <= 0 {
+		return emission.CIE1931XYZAbs{}, fmt.Errorf("emitter at %v nm has no luminance", e.Wavelength)
+	}
+
+	return emission.CIE1931XYZAbs{X: x / y, Y: 1, Z: z / y}, nil
+}
+
+// colorProfile estimates the color profile of a fixture with the given primary and white emitters.
+// The primaries are balanced to the white reference, every white emitter is as bright as all primaries together.
+// Everything is scaled so that the white point has the given luminance.
+func colorProfile(primaries, whites []emission.CIE1931XYZAbs, whiteReference emission.CIE1931XYZAbs, lumens float64) (*emission.ColorProfileGeneral, error) {
+	primaryColors := emission.TransformationLinDCSToXYZ(primaries)
+	whiteColors := emission.TransformationLinDCSToXYZ(whites)
+
+	if len(primaries) > 0 {
+		inv, err := primaryColors.Inverted()
+		if err != nil {
+			return nil, fmt.Errorf("failed to invert primaries: %w", err)
+		}
+		scales := inv.Multiplied(whiteReference)
+
+		// Fall back to equal luminance, if the white reference is outside of the gamut.
+		for _, s := range scales {
+			if s <= 0 {
+				scales = emission.LinDCSVector{1.0 / 3, 1.0 / 3, 1.0 / 3}
+				break
+			}
+		}
+
+		balanced := make(emission.TransformationLinDCSToXYZ, 0, len(primaries))
+		for i, primary := range primaryColors {
+			balanced = append(balanced, primary.Scaled(scales[i]))
+		}
+		primaryColors = balanced
+	}
+
+	// Use the brighter one of the sums as white point.
+	var primarySum, whiteSum emission.CIE1931XYZAbs
+	primarySum = primarySum.Sum(primaryColors...)
+	whiteSum = whiteSum.Sum(whiteColors...)
+	whitePoint := whiteSum
+	if primarySum.Y > whiteSum.Y {
+		whitePoint = primarySum
+	}
+
+	scale := lumens / whitePoint.Y
+	profile := &emission.ColorProfileGeneral{
+		WhitePointColor: whitePoint.Scaled(scale),
+		PrimaryColors:   primaryColors.Scaled(scale),
+		WhiteColors:     whiteColors.Scaled(scale),
+	}
+	if err := profile.Init(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
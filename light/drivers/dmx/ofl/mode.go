@@ -0,0 +1,328 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ofl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Dadido3/D3iot/light/drivers/dmx"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Options contains parameters for the import of a mode.
+type Options struct {
+	// Emitters overrides the entries of DefaultEmitters, e.g. with wavelengths from a data sheet.
+	Emitters map[string]Emitter
+
+	// The luminance of the white point in lumen.
+	// Defaults to the lumens of the bulb, or 1000 if the definition doesn't contain any.
+	Lumens float64
+}
+
+// Mode is a mode of a fixture, converted into a footprint and a color profile.
+type Mode struct {
+	Name         string
+	Footprint    dmx.Footprint
+	ColorProfile *emission.ColorProfileGeneral
+
+	Emitters []string // The names of the emitters in DCS order.
+	Fixed    []string // The channels that are set to a fixed value, like shutters, wheels or unsupported emitters.
+}
+
+// Fixture returns a fixture with this mode at the given DMX address.
+func (m *Mode) Fixture(address int) dmx.Fixture {
+	return dmx.Fixture{Address: address, Footprint: m.Footprint, ColorProfile: m.ColorProfile}
+}
+
+// modeChannel is a channel of a mode with its resolved definition.
+type modeChannel struct {
+	key       string
+	channel   Channel
+	fineOf    string // The key of the coarse channel, if this is a fine channel.
+	fineLevel int    // 1 for a 16 bit fine channel, 2 for 24 bit and so on.
+}
+
+// Mode converts the mode with the given name or short name.
+//
+// Channels are converted like this:
+//
+//   - ColorIntensity channels control emitters. Red, green and blue are the primaries, other visible colors are white emitters.
+//   - The first Intensity channel is the dimmer. Without any ColorIntensity channels, it controls a single white emitter.
+//   - Shutters are fixed to open, color wheels to their open slot. Color wheels without open slot are not supported.
+//   - All other channels are fixed to their default value.
+//
+// A color profile supports at most 3 white emitters, additional emitters are turned off.
+// Matrix modes are not supported.
+func (d *Definition) Mode(name string, options Options) (*Mode, error) {
+	var definition *ModeDefinition
+	for i, m := range d.Modes {
+		if m.Name == name || (m.ShortName != "" && m.ShortName == name) {
+			definition = &d.Modes[i]
+			break
+		}
+	}
+	if definition == nil {
+		return nil, fmt.Errorf("fixture %q has no mode %q", d.Name, name)
+	}
+
+	channels, err := d.resolveChannels(definition)
+	if err != nil {
+		return nil, fmt.Errorf("mode %q: %w", definition.Name, err)
+	}
+
+	emitters := make(map[string]Emitter, len(DefaultEmitters))
+	for color, emitter := range DefaultEmitters {
+		emitters[color] = emitter
+	}
+	for color, emitter := range options.Emitters {
+		emitters[color] = emitter
+	}
+
+	m := &Mode{Name: definition.Name}
+
+	// Collect the colors of all emitters in order of appearance.
+	var colors []string
+	dimmerKey := ""
+	for _, c := range channels {
+		if c.fineOf != "" {
+			continue
+		}
+		if color, ok := colorIntensity(c.channel); ok && !containsString(colors, color) {
+			colors = append(colors, color)
+		}
+		if dimmerKey == "" && isIntensity(c.channel) {
+			dimmerKey = c.key
+		}
+	}
+	whiteDimmer := len(colors) == 0 && dimmerKey != ""
+	if whiteDimmer {
+		colors = []string{"White"}
+	}
+
+	// Sort the colors into primaries and whites, and determine their DCS index.
+	index := map[string]int{}
+	var primaries, whites []string
+	hasPrimaries := containsString(colors, "Red") && containsString(colors, "Green") && containsString(colors, "Blue")
+	if hasPrimaries {
+		primaries = primaryColors
+	}
+	for _, color := range colors {
+		if hasPrimaries && containsString(primaryColors, color) {
+			continue
+		}
+		emitter, ok := emitters[color]
+		if !ok || len(whites) >= 3 {
+			continue // Unknown or too many emitters, they will be turned off.
+		}
+		if !emitter.isWhite() && (emitter.Wavelength < 380 || emitter.Wavelength > 780) {
+			continue // Not visible, like UV.
+		}
+		whites = append(whites, color)
+	}
+	m.Emitters = append(append(m.Emitters, primaries...), whites...)
+	for i, color := range m.Emitters {
+		index[color] = i
+	}
+	if len(m.Emitters) == 0 {
+		return nil, fmt.Errorf("mode %q doesn't contain any supported emitters", definition.Name)
+	}
+
+	// Build the footprint.
+	used := map[string]bool{}
+	for i := 0; i < len(channels); i++ {
+		c := channels[i]
+
+		// Merge 16 bit channels, if the fine channel directly follows.
+		fine := i+1 < len(channels) && channels[i+1].fineOf == c.key && channels[i+1].fineLevel == 1
+
+		color, isColor := colorIntensity(c.channel)
+		switch {
+		case c.fineOf != "":
+			// A fine channel that doesn't directly follow its coarse channel, or a 24 bit channel.
+			m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelFixed})
+			m.Fixed = append(m.Fixed, c.key)
+			continue
+
+		case isColor && !used[color]:
+			if idx, ok := index[color]; ok {
+				m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelEmitter, Emitter: idx, Fine: fine})
+				used[color] = true
+			} else {
+				m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelFixed, Fine: fine})
+				m.Fixed = append(m.Fixed, c.key)
+			}
+
+		case c.key == dimmerKey && whiteDimmer:
+			m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelEmitter, Emitter: 0, Fine: fine})
+
+		case c.key == dimmerKey:
+			m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelDimmer, Fine: fine})
+
+		default:
+			value, err := d.fixedValue(c)
+			if err != nil {
+				return nil, fmt.Errorf("mode %q: %w", definition.Name, err)
+			}
+			if fine {
+				value = value<<8 | value
+			}
+			m.Footprint = append(m.Footprint, dmx.Channel{Type: dmx.ChannelFixed, Fine: fine, Value: value})
+			if c.key != "" {
+				m.Fixed = append(m.Fixed, c.key)
+			}
+		}
+
+		if fine {
+			i++
+		}
+	}
+
+	// Estimate the color profile.
+	defaultTemperature := d.Physical.Bulb.ColorTemperature
+	if defaultTemperature <= 0 {
+		defaultTemperature = 6500
+	}
+	lumens := options.Lumens
+	if lumens <= 0 {
+		lumens = d.Physical.Bulb.Lumens
+	}
+	if lumens <= 0 {
+		lumens = 1000
+	}
+
+	primaryXYZ, err := emitterColors(emitters, primaries, defaultTemperature)
+	if err != nil {
+		return nil, err
+	}
+	whiteXYZ, err := emitterColors(emitters, whites, defaultTemperature)
+	if err != nil {
+		return nil, err
+	}
+	whiteReference := emission.BlackBodyFixed{Temperature: defaultTemperature, Luminance: 1}.CIE1931XYZAbs()
+
+	if m.ColorProfile, err = colorProfile(primaryXYZ, whiteXYZ, whiteReference, lumens); err != nil {
+		return nil, fmt.Errorf("failed to estimate color profile: %w", err)
+	}
+
+	return m, nil
+}
+
+// resolveChannels returns the channels of the mode with their definitions.
+func (d *Definition) resolveChannels(definition *ModeDefinition) ([]modeChannel, error) {
+	// Map fine channel aliases to their coarse channels.
+	fineAliases := map[string]modeChannel{}
+	for key, channel := range d.AvailableChannels {
+		for i, alias := range channel.FineChannelAliases {
+			fineAliases[alias] = modeChannel{key: alias, channel: channel, fineOf: key, fineLevel: i + 1}
+		}
+	}
+
+	channels := make([]modeChannel, 0, len(definition.Channels))
+	for i, raw := range definition.Channels {
+		var key *string
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, fmt.Errorf("channel %d is not a channel key, matrix modes are not supported", i+1)
+		}
+
+		switch {
+		case key == nil:
+			channels = append(channels, modeChannel{}) // Unused channel.
+		case d.AvailableChannels[*key].Capability != nil || d.AvailableChannels[*key].Capabilities != nil:
+			channels = append(channels, modeChannel{key: *key, channel: d.AvailableChannels[*key]})
+		default:
+			fineChannel, ok := fineAliases[*key]
+			if !ok {
+				return nil, fmt.Errorf("channel %q is not defined", *key)
+			}
+			channels = append(channels, fineChannel)
+		}
+	}
+
+	return channels, nil
+}
+
+// fixedValue returns the value that a channel without emitter is fixed to.
+// Color wheels without open slot are rejected, as their colors can't be part of the color profile.
+func (d *Definition) fixedValue(c modeChannel) (uint16, error) {
+	colorWheel := ""
+	for _, capability := range c.channel.capabilities() {
+		switch {
+		case capability.Type == "ShutterStrobe" && capability.ShutterEffect == "Open":
+			return rangeValue(capability.DMXRange), nil
+
+		case capability.Type == "WheelSlot":
+			wheelName := capability.Wheel
+			if wheelName == "" {
+				wheelName = c.key
+			}
+			wheel, ok := d.Wheels[wheelName]
+			slot := int(capability.SlotNumber) - 1
+			if !ok || float64(slot+1) != capability.SlotNumber || slot < 0 || slot >= len(wheel.Slots) {
+				continue
+			}
+			switch wheel.Slots[slot].Type {
+			case "Open":
+				return rangeValue(capability.DMXRange), nil
+			case "Color":
+				colorWheel = wheelName
+			}
+		}
+	}
+
+	if colorWheel != "" {
+		return 0, fmt.Errorf("color wheel %q has no open slot, fixtures that only change their color with a wheel are not supported", colorWheel)
+	}
+
+	return c.channel.defaultValue(), nil
+}
+
+// rangeValue returns the value in the middle of a DMX range.
+// Channels with a single capability have no range, so their maximum is used.
+func rangeValue(dmxRange []int) uint16 {
+	if len(dmxRange) != 2 {
+		return 255
+	}
+	return uint16((dmxRange[0] + dmxRange[1]) / 2)
+}
+
+// emitterColors returns the colors of the given emitters with a luminance of 1.
+func emitterColors(emitters map[string]Emitter, colors []string, defaultTemperature float64) ([]emission.CIE1931XYZAbs, error) {
+	result := make([]emission.CIE1931XYZAbs, 0, len(colors))
+	for _, color := range colors {
+		xyz, err := emitters[color].color(defaultTemperature)
+		if err != nil {
+			return nil, fmt.Errorf("emitter %q: %w", color, err)
+		}
+		result = append(result, xyz)
+	}
+	return result, nil
+}
+
+// colorIntensity returns the color of a channel that controls a single emitter.
+func colorIntensity(c Channel) (string, bool) {
+	capabilities := c.capabilities()
+	if len(capabilities) != 1 || capabilities[0].Type != "ColorIntensity" {
+		return "", false
+	}
+	return capabilities[0].Color, true
+}
+
+// isIntensity returns whether the channel is a plain dimmer.
+func isIntensity(c Channel) bool {
+	capabilities := c.capabilities()
+	return len(capabilities) == 1 && capabilities[0].Type == "Intensity"
+}
+
+// containsString returns whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ofl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light/drivers/dmx"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// testDefinition is a shortened fixture definition in the format of the Open Fixture Library.
+const testDefinition = `{
+	"$schema": "https://raw.githubusercontent.com/OpenLightingProject/open-fixture-library/master/schemas/fixture.json",
+	"name": "Test Par RGBWAUV",
+	"categories": ["Color Changer"],
+	"physical": {"bulb": {"type": "LED", "colorTemperature": 6000, "lumens": 2000}},
+	"wheels": {
+		"Color Wheel": {"slots": [{"type": "Open"}, {"type": "Color", "name": "Red", "colors": ["#ff0000"]}]},
+		"Filter Wheel": {"slots": [{"type": "Color", "name": "Red", "colors": ["#ff0000"]}, {"type": "Color", "name": "Blue", "colors": ["#0000ff"]}]}
+	},
+	"availableChannels": {
+		"Dimmer": {"fineChannelAliases": ["Dimmer fine"], "capability": {"type": "Intensity"}},
+		"Red": {"capability": {"type": "ColorIntensity", "color": "Red"}},
+		"Green": {"capability": {"type": "ColorIntensity", "color": "Green"}},
+		"Blue": {"capability": {"type": "ColorIntensity", "color": "Blue"}},
+		"White": {"capability": {"type": "ColorIntensity", "color": "White"}},
+		"Amber": {"capability": {"type": "ColorIntensity", "color": "Amber"}},
+		"UV": {"capability": {"type": "ColorIntensity", "color": "UV"}},
+		"Strobe": {"capabilities": [
+			{"dmxRange": [0, 9], "type": "ShutterStrobe", "shutterEffect": "Closed"},
+			{"dmxRange": [10, 19], "type": "ShutterStrobe", "shutterEffect": "Open"},
+			{"dmxRange": [20, 255], "type": "ShutterStrobe", "shutterEffect": "Strobe"}
+		]},
+		"Color Wheel": {"capabilities": [
+			{"dmxRange": [0, 127], "type": "WheelSlot", "slotNumber": 2},
+			{"dmxRange": [128, 255], "type": "WheelSlot", "slotNumber": 1}
+		]},
+		"Filter Wheel": {"capabilities": [
+			{"dmxRange": [0, 127], "type": "WheelSlot", "slotNumber": 1},
+			{"dmxRange": [128, 255], "type": "WheelSlot", "slotNumber": 2}
+		]},
+		"Program": {"defaultValue": "50%", "capability": {"type": "Effect", "effectName": "Auto"}}
+	},
+	"modes": [
+		{"name": "10-channel", "shortName": "10ch", "channels": ["Dimmer", "Dimmer fine", "Red", "Green", "Blue", "White", "Amber", "UV", "Strobe", "Program"]},
+		{"name": "Wheel", "channels": ["Dimmer", "Color Wheel", null]},
+		{"name": "Filter", "channels": ["Dimmer", "Filter Wheel"]},
+		{"name": "Matrix", "channels": [{"insert": "matrixChannels", "repeatFor": "eachPixelABC", "channelOrder": "perPixel", "templateChannels": ["Red $pixelKey"]}]}
+	]
+}`
+
+func TestMode(t *testing.T) {
+	d, err := Load(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	m, err := d.Mode("10ch", Options{})
+	if err != nil {
+		t.Fatalf("Mode() failed: %v", err)
+	}
+
+	wantFootprint := dmx.Footprint{
+		{Type: dmx.ChannelDimmer, Fine: true},
+		{Emitter: 0}, {Emitter: 1}, {Emitter: 2}, {Emitter: 3}, {Emitter: 4},
+		{Type: dmx.ChannelFixed}, // UV is turned off.
+		{Type: dmx.ChannelFixed, Value: 14},
+		{Type: dmx.ChannelFixed, Value: 127},
+	}
+	if !reflect.DeepEqual(m.Footprint, wantFootprint) {
+		t.Errorf("Got footprint %v, want %v", m.Footprint, wantFootprint)
+	}
+	if want := []string{"Red", "Green", "Blue", "White", "Amber"}; !reflect.DeepEqual(m.Emitters, want) {
+		t.Errorf("Got emitters %v, want %v", m.Emitters, want)
+	}
+	if err := m.Footprint.Validate(m.ColorProfile.Channels()); err != nil {
+		t.Errorf("Footprint doesn't match color profile: %v", err)
+	}
+
+	// Check the estimated profile.
+	if y := m.ColorProfile.WhitePoint().Y; y < 1999 || y > 2001 {
+		t.Errorf("White point has a luminance of %v lm, want 2000 lm", y)
+	}
+	red := m.ColorProfile.ChannelPoints()[0].CIE1931xyYAbs()
+	if red.X < 0.68 || red.Y > 0.32 {
+		t.Errorf("Red emitter has chromaticity (%v, %v), want a saturated red", red.X, red.Y)
+	}
+	white := emission.BlackBodyFixed{Temperature: 6000, Luminance: 200}
+	var result emission.CIE1931xyYAbs
+	if err := result.FromDCS(m.ColorProfile, white.IntoDCS(m.ColorProfile)); err != nil {
+		t.Fatalf("FromDCS() failed: %v", err)
+	}
+	want := white.CIE1931XYZAbs().Relative(1000)
+	if distance := result.CIE1931XYZAbs().Relative(1000).CIE1976LABDistance(want, emission.StandardIlluminantD65); distance > 1 {
+		t.Errorf("Profile reproduces %v with a ΔE* of %v", white, distance)
+	}
+}
+
+func TestModeWithColorWheel(t *testing.T) {
+	d, err := Load(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	m, err := d.Mode("Wheel", Options{Emitters: map[string]Emitter{"White": {Temperature: 7500}}})
+	if err != nil {
+		t.Fatalf("Mode() failed: %v", err)
+	}
+
+	// The dimmer controls the lamp, the wheel is fixed to its open slot.
+	wantFootprint := dmx.Footprint{{Emitter: 0}, {Type: dmx.ChannelFixed, Value: 191}, {Type: dmx.ChannelFixed}}
+	if !reflect.DeepEqual(m.Footprint, wantFootprint) {
+		t.Errorf("Got footprint %v, want %v", m.Footprint, wantFootprint)
+	}
+
+	white := m.ColorProfile.WhitePoint().CIE1931xyYAbs()
+	want := emission.BlackBodyFixed{Temperature: 7500, Luminance: 1}.CIE1931xyYAbs()
+	if diff := white.X - want.X + white.Y - want.Y; diff > 0.001 || diff < -0.001 {
+		t.Errorf("White point has chromaticity (%v, %v), want (%v, %v)", white.X, white.Y, want.X, want.Y)
+	}
+}
+
+func TestUnsupportedModes(t *testing.T) {
+	d, err := Load(strings.NewReader(testDefinition))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	for _, name := range []string{"Matrix", "Filter", "Missing"} {
+		if _, err := d.Mode(name, Options{}); err == nil {
+			t.Errorf("Mode(%q) succeeded, want error", name)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ofl
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/Dadido3/D3iot/light/drivers/dmx"
+)
+
+func init() {
+	dmx.RegisterFixtureFormat("ofl", loadFixture)
+}
+
+// loadFixture reads an Open Fixture Library definition for the artnet and sacn URI schemes of the dmx driver.
+// The URI parameters are
+//
+//	?fixture=path/to/fixture.json&mode=name[&lumens=1000]
+//
+// The mode defaults to the first one.
+func loadFixture(path string, query url.Values) (dmx.Fixture, error) {
+	definition, err := LoadFile(path)
+	if err != nil {
+		return dmx.Fixture{}, err
+	}
+
+	modeName := query.Get("mode")
+	if modeName == "" && len(definition.Modes) > 0 {
+		modeName = definition.Modes[0].Name
+	}
+
+	var options Options
+	if value := query.Get("lumens"); value != "" {
+		if options.Lumens, err = strconv.ParseFloat(value, 64); err != nil {
+			return dmx.Fixture{}, fmt.Errorf("failed to parse lumens %q: %w", value, err)
+		}
+	}
+
+	mode, err := definition.Mode(modeName, options)
+	if err != nil {
+		return dmx.Fixture{}, err
+	}
+
+	return mode.Fixture(1), nil
+}
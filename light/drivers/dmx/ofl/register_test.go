@@ -0,0 +1,39 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package ofl
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+func TestOpenURI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "par.json")
+	if err := os.WriteFile(path, []byte(testDefinition), 0644); err != nil {
+		t.Fatalf("os.WriteFile() failed: %v", err)
+	}
+
+	node, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	defer node.Close()
+
+	uri := "artnet://" + node.LocalAddr().String() + "/1?mode=10-channel&address=5&fixture=" + path
+	l, err := light.Open(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("light.Open(%q) failed: %v", uri, err)
+	}
+
+	if channels := l.ColorProfiles()[0].Channels(); l.Modules() != 1 || channels != 5 {
+		t.Errorf("Light has %d modules with %d channels, want 1 module with 5 channels", l.Modules(), channels)
+	}
+}
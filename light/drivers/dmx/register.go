@@ -0,0 +1,159 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/dmxnet"
+)
+
+func init() {
+	light.Register("artnet", openURI)
+	light.Register("sacn", openURI)
+}
+
+// FixtureLoader reads the fixture definition file at the given path.
+// The query contains the parameters of the URI, for format specific options like the mode of the fixture.
+// The address of the returned fixture is ignored.
+type FixtureLoader func(path string, query url.Values) (Fixture, error)
+
+var (
+	fixtureLoadersMutex sync.RWMutex
+	fixtureLoaders      = map[string]FixtureLoader{}
+)
+
+var (
+	openUniversesMutex sync.Mutex
+	openUniverses      = map[string]bool{} // The universes that are opened via URI, by protocol, node address and universe number.
+)
+
+// RegisterFixtureFormat makes a fixture definition format available to the artnet and sacn URI schemes.
+// Packages that implement a format usually call this in their init function.
+//
+// This panics if the loader is nil, or if RegisterFixtureFormat is called twice with the same format.
+func RegisterFixtureFormat(format string, loader FixtureLoader) {
+	fixtureLoadersMutex.Lock()
+	defer fixtureLoadersMutex.Unlock()
+
+	if loader == nil {
+		panic("dmx: RegisterFixtureFormat loader is nil")
+	}
+	if _, ok := fixtureLoaders[format]; ok {
+		panic(fmt.Sprintf("dmx: RegisterFixtureFormat called twice for format %q", format))
+	}
+
+	fixtureLoaders[format] = loader
+}
+
+// FixtureFormats returns a sorted list of all registered fixture definition formats.
+func FixtureFormats() []string {
+	fixtureLoadersMutex.RLock()
+	defer fixtureLoadersMutex.RUnlock()
+
+	formats := make([]string, 0, len(fixtureLoaders))
+	for format := range fixtureLoaders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// openURI creates a DMX fixture from an URI in the form of
+//
+//	artnet://host[:port]/universe?fixture=path/to/fixture.json[&format=ofl&address=1]
+//	sacn://[host[:port]]/universe?fixture=path/to/fixture.json[&format=ofl&address=1]
+//
+// The fixture definition is read by the loader of the format, see RegisterFixtureFormat().
+// The format defaults to ofl, the Open Fixture Library.
+// For E1.31 an empty host sends to the multicast group of the universe.
+//
+// Every URI opens its own universe, which sends frames with all slots of the universe.
+// Therefore a universe can only be opened once at a time, use NewUniverse() for several fixtures in one universe.
+func openURI(ctx context.Context, uri *url.URL) (light.Light, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	options := Options{Address: uri.Host}
+	switch uri.Scheme {
+	case "artnet":
+		options.Protocol = dmxnet.ProtocolArtNet
+	case "sacn":
+		options.Protocol = dmxnet.ProtocolE131
+	}
+
+	universe, err := strconv.ParseUint(strings.TrimPrefix(uri.Path, "/"), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse universe %q: %w", uri.Path, err)
+	}
+	options.Universe = uint16(universe)
+
+	query := uri.Query()
+
+	path := query.Get("fixture")
+	if path == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a fixture definition", uri)
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "ofl"
+	}
+	fixtureLoadersMutex.RLock()
+	loader, ok := fixtureLoaders[format]
+	fixtureLoadersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown fixture format %q, the package of the format has to be imported. Available formats: %v", format, FixtureFormats())
+	}
+
+	fixture, err := loader(path, query)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture.Address = 1
+	if value := query.Get("address"); value != "" {
+		if fixture.Address, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("failed to parse address %q: %w", value, err)
+		}
+	}
+
+	address, err := nodeAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%v %s %d", options.Protocol, address, options.Universe)
+
+	openUniversesMutex.Lock()
+	defer openUniversesMutex.Unlock()
+
+	if openUniverses[key] {
+		return nil, fmt.Errorf("%v universe %d at %q is already opened, use NewUniverse() for several fixtures in one universe", options.Protocol, options.Universe, address)
+	}
+
+	u, err := NewUniverse(options, fixture)
+	if err != nil {
+		return nil, err
+	}
+	openUniverses[key] = true
+	u.onClose = func() {
+		openUniversesMutex.Lock()
+		defer openUniversesMutex.Unlock()
+
+		delete(openUniverses, key)
+	}
+
+	return u, nil
+}
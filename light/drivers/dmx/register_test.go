@@ -0,0 +1,104 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+func init() {
+	// The test format returns the test fixture with the index given by the path.
+	RegisterFixtureFormat("test", func(path string, query url.Values) (Fixture, error) {
+		for i, fixture := range testFixtures {
+			if path == fmt.Sprint(i) {
+				return fixture, nil
+			}
+		}
+		return Fixture{}, fmt.Errorf("fixture %q not found", path)
+	})
+}
+
+func TestOpenURI(t *testing.T) {
+	node := newFakeNode(t)
+
+	uri := "artnet://" + node.LocalAddr().String() + "/3?format=test&fixture=1&address=5"
+	l, err := light.Open(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("light.Open(%q) failed: %v", uri, err)
+	}
+	universe := l.(*Universe)
+	defer universe.Close()
+
+	if universe.options.Protocol.String() != "Art-Net" || universe.options.Universe != 3 {
+		t.Errorf("Got protocol %v with universe %d, want Art-Net with universe 3", universe.options.Protocol, universe.options.Universe)
+	}
+	if fixture := universe.fixtures[0]; fixture.Address != 5 || len(fixture.Footprint) != len(testFixtures[1].Footprint) {
+		t.Errorf("Got fixture at address %d with %d channels, want address 5 with %d channels", fixture.Address, len(fixture.Footprint), len(testFixtures[1].Footprint))
+	}
+}
+
+func TestOpenURITwice(t *testing.T) {
+	node := newFakeNode(t)
+
+	uri := "artnet://" + node.LocalAddr().String() + "/4?format=test&fixture=0"
+	l, err := light.Open(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("light.Open(%q) failed: %v", uri, err)
+	}
+
+	// A second fixture in the same universe would overwrite the slots of the first one.
+	other := "artnet://" + node.LocalAddr().String() + "/4?format=test&fixture=1&address=10"
+	if l, err := light.Open(context.Background(), other); err == nil {
+		l.(*Universe).Close()
+		t.Errorf("light.Open(%q) of an opened universe succeeded, want error", other)
+	}
+
+	// Other universes of the node are fine.
+	other = "artnet://" + node.LocalAddr().String() + "/5?format=test&fixture=1"
+	l2, err := light.Open(context.Background(), other)
+	if err != nil {
+		t.Fatalf("light.Open(%q) failed: %v", other, err)
+	}
+	l2.(*Universe).Close()
+
+	// The universe can be opened again once it's closed.
+	l.(*Universe).Close()
+	if l, err = light.Open(context.Background(), uri); err != nil {
+		t.Fatalf("light.Open(%q) after Close() failed: %v", uri, err)
+	}
+	l.(*Universe).Close()
+}
+
+func TestOpenURIInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"artnet://127.0.0.1/1?format=test",                       // No fixture.
+		"artnet://127.0.0.1/x?format=test&fixture=0",             // Invalid universe.
+		"artnet://127.0.0.1/1?format=test&fixture=2",             // Missing fixture.
+		"artnet://127.0.0.1/1?format=test&fixture=0&address=x",   // Invalid address.
+		"artnet://127.0.0.1/1?format=test&fixture=1&address=510", // Fixture doesn't fit into the universe.
+		"artnet://127.0.0.1/1?format=missing&fixture=0",          // Unknown format.
+	} {
+		if l, err := light.Open(context.Background(), uri); err == nil {
+			l.(*Universe).Close()
+			t.Errorf("light.Open(%q) succeeded, want error", uri)
+		}
+	}
+}
+
+func TestRegisterFixtureFormatTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterFixtureFormat() didn't panic")
+		}
+	}()
+
+	RegisterFixtureFormat("test", func(path string, query url.Values) (Fixture, error) { return Fixture{}, nil })
+}
@@ -0,0 +1,414 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package dmx implements light devices for DMX512 fixtures that are connected to an Art-Net or E1.31 (sACN) node.
+package dmx
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/dmxnet"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Fixture describes a DMX512 fixture in a universe.
+type Fixture struct {
+	Address   int       // The first DMX channel of the fixture, starting at 1.
+	Footprint Footprint // The DMX channels of the fixture.
+
+	// The color profile of the emitters of the fixture, usually an *emission.ColorProfileGeneral.
+	// The DCS channels of the profile are the emitters of the footprint.
+	ColorProfile emission.ColorProfile
+}
+
+// Options contains the parameters of a Universe.
+type Options struct {
+	Protocol dmxnet.Protocol
+	Universe uint16 // The universe number, for E1.31 in the range of [1, 63999].
+
+	// The UDP address of the node, the port defaults to the one of the protocol.
+	// For E1.31 an empty address sends to the multicast group of the universe.
+	Address string
+
+	SourceName string // The E1.31 source name. Defaults to "D3iot".
+	Priority   uint8  // The E1.31 priority. Defaults to 100.
+
+	// Interval in which the last frame is sent again, as nodes expect a continuous stream of frames.
+	// Defaults to 1 second if zero, a negative value disables refreshing.
+	RefreshInterval time.Duration
+}
+
+// Universe represents the fixtures in a DMX512 universe.
+//
+// The universe itself is a light device, every fixture is one module.
+// Setting all fixtures at once via the universe results in a single frame.
+// Single fixtures can be controlled via the light devices returned by Fixture().
+type Universe struct {
+	options  Options
+	conn     net.Conn
+	fixtures []Fixture
+	cid      [16]byte
+
+	mutex    sync.Mutex
+	data     [dmxnet.Slots]byte
+	slots    int // The number of slots that are sent, which is the highest slot used by any fixture.
+	sequence uint8
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error  // The result of the first Close call.
+	onClose   func() // Called by the first Close call, if set.
+}
+
+// Check implementation of Light.
+var _ light.Light = &Universe{}
+
+// NewUniverse returns a universe with the given fixtures.
+// Fixtures must not overlap.
+// The fixtures are copied, so the given slice can be modified afterwards.
+//
+// Use Close() to stop sending frames.
+func NewUniverse(options Options, fixtures ...Fixture) (*Universe, error) {
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("universe needs at least one fixture")
+	}
+	if options.SourceName == "" {
+		options.SourceName = "D3iot"
+	}
+	if options.Priority == 0 {
+		options.Priority = dmxnet.DefaultPriority
+	}
+	if options.RefreshInterval == 0 {
+		options.RefreshInterval = time.Second
+	}
+
+	u := &Universe{
+		options:  options,
+		fixtures: append([]Fixture(nil), fixtures...),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// Check that all fixtures fit into the universe, and that they don't overlap.
+	var used [dmxnet.Slots]bool
+	for i, fixture := range u.fixtures {
+		if fixture.ColorProfile == nil {
+			return nil, fmt.Errorf("fixture %d has no color profile", i)
+		}
+		if err := fixture.Footprint.Validate(fixture.ColorProfile.Channels()); err != nil {
+			return nil, fmt.Errorf("fixture %d has an invalid footprint: %w", i, err)
+		}
+		last := fixture.Address + fixture.Footprint.Slots() - 1
+		if fixture.Address < 1 || last > dmxnet.Slots {
+			return nil, fmt.Errorf("fixture %d occupies channels %d to %d, which are out of range", i, fixture.Address, last)
+		}
+		for slot := fixture.Address - 1; slot < last; slot++ {
+			if used[slot] {
+				return nil, fmt.Errorf("fixture %d overlaps with another fixture at channel %d", i, slot+1)
+			}
+			used[slot] = true
+		}
+		if last > u.slots {
+			u.slots = last
+		}
+
+		// Initialize fixed channels and turn everything off.
+		fixture.Footprint.encode(fixture.ColorProfile.TransferFunction(), make(emission.DCSVector, fixture.ColorProfile.Channels()), u.fixtureSlots(i))
+	}
+
+	if _, err := rand.Read(u.cid[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate CID: %w", err)
+	}
+
+	address, err := nodeAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	if u.conn, err = net.Dial("udp", address); err != nil {
+		return nil, fmt.Errorf("failed to connect to %q: %w", address, err)
+	}
+
+	go u.refresh()
+
+	return u, nil
+}
+
+// nodeAddress returns the UDP address that frames of a universe with the given options are sent to.
+func nodeAddress(options Options) (string, error) {
+	var defaultPort int
+	switch options.Protocol {
+	case dmxnet.ProtocolArtNet:
+		if options.Address == "" {
+			return "", fmt.Errorf("Art-Net needs the address of a node")
+		}
+		defaultPort = dmxnet.ArtNetPort
+	case dmxnet.ProtocolE131:
+		if options.Address == "" {
+			return dmxnet.E131MulticastAddress(options.Universe).String(), nil
+		}
+		defaultPort = dmxnet.E131Port
+	default:
+		return "", fmt.Errorf("unsupported protocol %v", options.Protocol)
+	}
+
+	if _, _, err := net.SplitHostPort(options.Address); err == nil {
+		return options.Address, nil
+	}
+	return net.JoinHostPort(options.Address, strconv.Itoa(defaultPort)), nil
+}
+
+// fixtureSlots returns the part of the universe data that belongs to the fixture with the given index.
+func (u *Universe) fixtureSlots(i int) []byte {
+	fixture := u.fixtures[i]
+	return u.data[fixture.Address-1 : fixture.Address-1+fixture.Footprint.Slots()]
+}
+
+// send sends the current data of the universe as a single frame.
+// The mutex must be held.
+func (u *Universe) send(terminated bool) error {
+	u.sequence++
+
+	packet, err := dmxnet.Frame{
+		Protocol:   u.options.Protocol,
+		Universe:   u.options.Universe,
+		Sequence:   u.sequence,
+		Priority:   u.options.Priority,
+		CID:        u.cid,
+		SourceName: u.options.SourceName,
+		Terminated: terminated,
+		Data:       u.data[:u.slots],
+	}.Encode()
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.conn.Write(packet); err != nil {
+		return fmt.Errorf("failed to send frame: %w", err)
+	}
+
+	return nil
+}
+
+// refresh sends the last frame regularly.
+func (u *Universe) refresh() {
+	defer close(u.done)
+
+	if u.options.RefreshInterval < 0 {
+		<-u.stop
+		return
+	}
+
+	ticker := time.NewTicker(u.options.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.stop:
+			return
+		case <-ticker.C:
+			u.mutex.Lock()
+			u.send(false) // Errors will be returned by the next SetColors call.
+			u.mutex.Unlock()
+		}
+	}
+}
+
+// Close stops refreshing and closes the connection.
+// For E1.31 the receivers are told that the stream has terminated.
+// The fixtures keep their last state, depending on how the node handles a signal loss.
+//
+// Calling Close more than once has no further effect, it returns the result of the first call.
+func (u *Universe) Close() error {
+	u.closeOnce.Do(func() {
+		close(u.stop)
+		<-u.done
+
+		u.mutex.Lock()
+		defer u.mutex.Unlock()
+
+		if u.options.Protocol == dmxnet.ProtocolE131 {
+			u.send(true)
+		}
+		u.closeErr = u.conn.Close()
+
+		if u.onClose != nil {
+			u.onClose()
+		}
+	})
+
+	return u.closeErr
+}
+
+// SetColors sets the emission values of all fixtures and sends them as a single frame.
+// Values which are not set are assumed to equal a turned off fixture.
+// This will return an error if you try to set more values than there are fixtures in the universe.
+//
+// This implements the light.Light interface.
+func (u *Universe) SetColors(emissionValues ...emission.Value) error {
+	if len(emissionValues) > len(u.fixtures) {
+		return fmt.Errorf("got %d emission values, this universe has only %d fixtures", len(emissionValues), len(u.fixtures))
+	}
+
+	vectors, err := u.vectors(0, emissionValues)
+	if err != nil {
+		return err
+	}
+	for i := len(vectors); i < len(u.fixtures); i++ {
+		vectors = append(vectors, make(emission.DCSVector, u.fixtures[i].ColorProfile.Channels()))
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for i, vector := range vectors {
+		fixture := u.fixtures[i]
+		fixture.Footprint.encode(fixture.ColorProfile.TransferFunction(), vector, u.fixtureSlots(i))
+	}
+
+	return u.send(false)
+}
+
+// vectors returns the emission values as DCS vectors of the fixtures starting at the given index.
+func (u *Universe) vectors(first int, emissionValues []emission.Value) ([]emission.DCSVector, error) {
+	vectors := make([]emission.DCSVector, 0, len(emissionValues))
+	for i, emissionValue := range emissionValues {
+		colorProfile := u.fixtures[first+i].ColorProfile
+		vector := emissionValue.IntoDCS(colorProfile)
+		if vector.Channels() != colorProfile.Channels() {
+			return nil, fmt.Errorf("unexpected number of channels for fixture %d. Got %d, want %d", first+i, vector.Channels(), colorProfile.Channels())
+		}
+		vectors = append(vectors, vector)
+	}
+
+	return vectors, nil
+}
+
+// GetColors returns the emission values of the last frame.
+// DMX512 is unidirectional, so the fixtures are not queried.
+// This will return an error if you try to get more values than there are fixtures in the universe.
+//
+// This implements the light.Light interface.
+func (u *Universe) GetColors(emissionValues ...emission.ValueReceiver) error {
+	if len(emissionValues) > len(u.fixtures) {
+		return fmt.Errorf("got %d emission values, this universe has only %d fixtures", len(emissionValues), len(u.fixtures))
+	}
+
+	return u.getColors(0, emissionValues)
+}
+
+// getColors writes the values of the fixtures starting at the given index into emissionValues.
+func (u *Universe) getColors(first int, emissionValues []emission.ValueReceiver) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for i, emissionValue := range emissionValues {
+		fixture := u.fixtures[first+i]
+		colorProfile := fixture.ColorProfile
+		vector := fixture.Footprint.decode(colorProfile.TransferFunction(), colorProfile.Channels(), u.fixtureSlots(first+i))
+		if err := emissionValue.FromDCS(colorProfile, vector); err != nil {
+			return fmt.Errorf("failed to convert DCS vector of fixture %d: %w", first+i, err)
+		}
+	}
+
+	return nil
+}
+
+// Modules returns the number of fixtures in the universe.
+//
+// This implements the light.Light interface.
+func (u *Universe) Modules() int {
+	return len(u.fixtures)
+}
+
+// ColorProfiles returns the color profiles of all fixtures.
+//
+// This implements the light.Light interface.
+func (u *Universe) ColorProfiles() []emission.ColorProfile {
+	colorProfiles := make([]emission.ColorProfile, 0, len(u.fixtures))
+	for _, fixture := range u.fixtures {
+		colorProfiles = append(colorProfiles, fixture.ColorProfile)
+	}
+
+	return colorProfiles
+}
+
+// Fixture returns a light device that controls only the fixture with the given index.
+// Setting its colors doesn't change the other fixtures of the universe.
+//
+// This returns an error if there is no fixture with the given index.
+func (u *Universe) Fixture(i int) (*FixtureLight, error) {
+	if i < 0 || i >= len(u.fixtures) {
+		return nil, fmt.Errorf("fixture index %d is out of range, this universe has %d fixtures", i, len(u.fixtures))
+	}
+
+	return &FixtureLight{universe: u, index: i}, nil
+}
+
+// FixtureLight is a light device with a single module, which is one fixture of a universe.
+type FixtureLight struct {
+	universe *Universe
+	index    int
+}
+
+// Check implementation of Light.
+var _ light.Light = &FixtureLight{}
+
+// SetColors sets the emission value of the fixture and sends the whole universe.
+// If no value is given, the fixture is turned off.
+//
+// This implements the light.Light interface.
+func (f *FixtureLight) SetColors(emissionValues ...emission.Value) error {
+	if len(emissionValues) > 1 {
+		return fmt.Errorf("got %d emission values, a fixture has only 1 module", len(emissionValues))
+	}
+
+	fixture := f.universe.fixtures[f.index]
+	vectors, err := f.universe.vectors(f.index, emissionValues)
+	if err != nil {
+		return err
+	}
+	vector := make(emission.DCSVector, fixture.ColorProfile.Channels())
+	if len(vectors) > 0 {
+		vector = vectors[0]
+	}
+
+	f.universe.mutex.Lock()
+	defer f.universe.mutex.Unlock()
+
+	fixture.Footprint.encode(fixture.ColorProfile.TransferFunction(), vector, f.universe.fixtureSlots(f.index))
+	return f.universe.send(false)
+}
+
+// GetColors returns the emission value of the last frame.
+//
+// This implements the light.Light interface.
+func (f *FixtureLight) GetColors(emissionValues ...emission.ValueReceiver) error {
+	if len(emissionValues) > 1 {
+		return fmt.Errorf("got %d emission values, a fixture has only 1 module", len(emissionValues))
+	}
+
+	return f.universe.getColors(f.index, emissionValues)
+}
+
+// Modules returns 1.
+//
+// This implements the light.Light interface.
+func (f *FixtureLight) Modules() int {
+	return 1
+}
+
+// ColorProfiles returns the color profile of the fixture.
+//
+// This implements the light.Light interface.
+func (f *FixtureLight) ColorProfiles() []emission.ColorProfile {
+	return []emission.ColorProfile{f.universe.fixtures[f.index].ColorProfile}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dmx
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/dmxnet"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+// testFixtures contains an 8 bit RGB fixture and a 16 bit RGB fixture with dimmer and a fixed shutter channel.
+var testFixtures = []Fixture{
+	{
+		Address:      1,
+		Footprint:    Footprint{{Emitter: 0}, {Emitter: 1}, {Emitter: 2}},
+		ColorProfile: virtual.DefaultColorProfile,
+	},
+	{
+		Address: 10,
+		Footprint: Footprint{
+			{Type: ChannelDimmer, Fine: true},
+			{Type: ChannelFixed, Value: 255},
+			{Emitter: 2, Fine: true}, {Emitter: 1, Fine: true}, {Emitter: 0, Fine: true},
+		},
+		ColorProfile: virtual.DefaultColorProfile,
+	},
+}
+
+// newFakeNode returns a UDP listener that receives frames of a node.
+// It's closed when the test finishes.
+func newFakeNode(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// receiveFrame returns the next frame that the node receives.
+func receiveFrame(t *testing.T, conn net.PacketConn) dmxnet.Frame {
+	t.Helper()
+
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() failed: %v", err)
+	}
+	f, err := dmxnet.Decode(buf[:n])
+	if err != nil {
+		t.Fatalf("dmxnet.Decode() failed: %v", err)
+	}
+
+	return f
+}
+
+func TestUniverseConformance(t *testing.T) {
+	lighttest.Run(t, func(t *testing.T) light.Light {
+		node := newFakeNode(t)
+
+		u, err := NewUniverse(Options{Protocol: dmxnet.ProtocolArtNet, Address: node.LocalAddr().String()}, testFixtures...)
+		if err != nil {
+			t.Fatalf("NewUniverse() failed: %v", err)
+		}
+		t.Cleanup(func() { u.Close() })
+
+		return u
+	})
+}
+
+func TestUniverseFrames(t *testing.T) {
+	node := newFakeNode(t)
+
+	u, err := NewUniverse(Options{Protocol: dmxnet.ProtocolE131, Universe: 3, Address: node.LocalAddr().String(), RefreshInterval: -1}, testFixtures...)
+	if err != nil {
+		t.Fatalf("NewUniverse() failed: %v", err)
+	}
+
+	// Set both fixtures at once, which results in a single frame.
+	if err := u.SetColors(emission.DCSVector{1, 0, 0}, emission.DCSVector{0.5, 0.5, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	f := receiveFrame(t, node)
+	if f.Protocol != dmxnet.ProtocolE131 || f.Universe != 3 || len(f.Data) != 18 {
+		t.Fatalf("Got frame %+v, want E1.31 frame of universe 3 with 18 slots", f)
+	}
+	if !bytes.Equal(f.Data[:3], []byte{255, 0, 0}) {
+		t.Errorf("First fixture has slots %v, want %v", f.Data[:3], []byte{255, 0, 0})
+	}
+	// The dimmer is at 0.5 in DCS, which is about 0.21 linear, and the emitters are at full level.
+	want := []byte{255, 0, 0, 255, 255, 255, 255}
+	if !bytes.Equal(f.Data[11:18], want) || f.Data[9] < 0x34 || f.Data[9] > 0x38 {
+		t.Errorf("Second fixture has slots %v, want a dimmer of about 0.21 followed by %v", f.Data[9:18], want)
+	}
+
+	// Setting a single fixture keeps the other one.
+	fixture, err := u.Fixture(1)
+	if err != nil {
+		t.Fatalf("Fixture() failed: %v", err)
+	}
+	if err := fixture.SetColors(); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	f = receiveFrame(t, node)
+	if !bytes.Equal(f.Data[:3], []byte{255, 0, 0}) || f.Data[9] != 0 || f.Data[11] != 255 {
+		t.Errorf("Got slots %v, want first fixture unchanged and second one off", f.Data)
+	}
+
+	// Closing the universe terminates the stream.
+	if err := u.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if f = receiveFrame(t, node); !f.Terminated {
+		t.Errorf("Last frame is not marked as terminated")
+	}
+	if err := u.Close(); err != nil {
+		t.Errorf("Second Close() failed: %v", err)
+	}
+}
+
+func TestUniverseFixtures(t *testing.T) {
+	node := newFakeNode(t)
+
+	fixtures := append([]Fixture(nil), testFixtures...)
+	u, err := NewUniverse(Options{Protocol: dmxnet.ProtocolArtNet, Address: node.LocalAddr().String(), RefreshInterval: -1}, fixtures...)
+	if err != nil {
+		t.Fatalf("NewUniverse() failed: %v", err)
+	}
+	defer u.Close()
+
+	// Modifying the given fixtures must not affect the universe.
+	fixtures[0].Address = 500
+	if err := u.SetColors(emission.DCSVector{1, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if f := receiveFrame(t, node); f.Data[0] != 255 {
+		t.Errorf("Got slots %v, want the first fixture at its original address", f.Data)
+	}
+
+	for _, i := range []int{-1, len(testFixtures)} {
+		if _, err := u.Fixture(i); err == nil {
+			t.Errorf("Fixture(%d) succeeded, want error", i)
+		}
+	}
+}
+
+func TestInvalidFixtures(t *testing.T) {
+	tests := [][]Fixture{
+		{},
+		{{Address: 1, Footprint: Footprint{{Emitter: 0}, {Emitter: 1}}, ColorProfile: virtual.DefaultColorProfile}},
+		{{Address: 1, Footprint: Footprint{{Emitter: 0}, {Emitter: 1}, {Emitter: 1}}, ColorProfile: virtual.DefaultColorProfile}},
+		{{Address: 511, Footprint: testFixtures[0].Footprint, ColorProfile: virtual.DefaultColorProfile}},
+		{testFixtures[0], {Address: 3, Footprint: testFixtures[0].Footprint, ColorProfile: virtual.DefaultColorProfile}},
+	}
+
+	for _, fixtures := range tests {
+		if u, err := NewUniverse(Options{Protocol: dmxnet.ProtocolArtNet, Address: "127.0.0.1"}, fixtures...); err == nil {
+			u.Close()
+			t.Errorf("NewUniverse() with fixtures %+v succeeded, want error", fixtures)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pairInterval is the time between two pairing attempts.
+var pairInterval = time.Second
+
+// Bridge represents a Hue bridge and a user that is paired with it.
+//
+// It communicates with the bridge via its local REST API, the bridge forwards commands to the lights via Zigbee.
+type Bridge struct {
+	baseURL  string // URL of the API, e.g. "http://192.168.1.2/api".
+	username string
+	client   *http.Client
+}
+
+// NewBridge returns an object that represents the Hue bridge at the given address.
+// The address is either a host like "192.168.1.2" or an URL like "http://192.168.1.2:80".
+//
+// The username has to be created once via Pair().
+// This will not communicate with the bridge.
+func NewBridge(address, username string) *Bridge {
+	return &Bridge{
+		baseURL:  apiURL(address),
+		username: username,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// apiURL returns the URL of the API of the bridge at the given address.
+func apiURL(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return strings.TrimSuffix(address, "/") + "/api"
+}
+
+// Pair creates a new user on the bridge at the given address and returns its username.
+// The deviceType identifies the application, e.g. "d3iot#living-room".
+//
+// The link button of the bridge has to be pressed to allow this.
+// Pair retries until the link button is pressed, any other error occurs, or the context is done.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	username, err := hue.Pair(ctx, "192.168.1.2", "d3iot#living-room")
+func Pair(ctx context.Context, address, deviceType string) (string, error) {
+	b := NewBridge(address, "")
+
+	ticker := time.NewTicker(pairInterval)
+	defer ticker.Stop()
+
+	for {
+		var result struct {
+			Username string `json:"username"`
+		}
+		err := b.request(ctx, http.MethodPost, b.baseURL, map[string]string{"devicetype": deviceType}, &result)
+
+		var errAPI *ErrAPI
+		switch {
+		case err == nil:
+			return result.Username, nil
+		case errors.As(err, &errAPI) && errAPI.ErrorType() == ErrorTypeLinkButtonNotPressed:
+		default:
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("link button wasn't pressed: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// resourceURL returns the URL of a resource of the paired user.
+func (b *Bridge) resourceURL(path ...string) string {
+	return b.baseURL + "/" + b.username + "/" + strings.Join(path, "/")
+}
+
+// apiResponse is a single entry of the list that the bridge returns for commands and errors.
+type apiResponse struct {
+	Success json.RawMessage `json:"success"`
+	Error   *struct {
+		Type        ErrorType `json:"type"`
+		Address     string    `json:"address"`
+		Description string    `json:"description"`
+	} `json:"error"`
+}
+
+// request sends a request with the JSON encoded body to the bridge, and decodes the response into result.
+//
+// The bridge either responds with the requested object, or with a list of success and error entries.
+// In the latter case the first error is returned, or the first success entry is decoded into result.
+// Body and result can be nil.
+func (b *Bridge) request(ctx context.Context, method, url string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hue bridge responded with %s", resp.Status)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var responses []apiResponse
+		if err := json.Unmarshal(trimmed, &responses); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		for _, response := range responses {
+			if response.Error != nil {
+				return &ErrAPI{errorType: response.Error.Type, address: response.Error.Address, description: response.Error.Description}
+			}
+		}
+		if result == nil || len(responses) == 0 {
+			return nil
+		}
+		data = responses[0].Success
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
+
+// LightInfo contains the state and properties of a light, as reported by the bridge.
+type LightInfo struct {
+	State            State             `json:"state"`
+	Type             string            `json:"type"` // E.g. "Extended color light", "Color temperature light" or "Dimmable light".
+	Name             string            `json:"name"`
+	ModelID          string            `json:"modelid"`
+	ManufacturerName string            `json:"manufacturername"`
+	ProductName      string            `json:"productname"`
+	SWVersion        string            `json:"swversion"`
+	UniqueID         string            `json:"uniqueid"` // The Zigbee address followed by the endpoint, e.g. "00:17:88:01:00:bd:c7:b9-0b".
+	Capabilities     LightCapabilities `json:"capabilities"`
+}
+
+// LightCapabilities contains the abilities of a light.
+// Older bridges don't report these, in that case the type of the light is used instead.
+type LightCapabilities struct {
+	Control struct {
+		MaxLumen       float64     `json:"maxlumen"`
+		ColorGamutType string      `json:"colorgamuttype"` // "A", "B", "C" or "other".
+		ColorGamut     *Gamut      `json:"colorgamut"`
+		CT             *MiredRange `json:"ct"`
+	} `json:"control"`
+}
+
+// MiredRange is a range of color temperatures in mired.
+type MiredRange struct {
+	Min uint16 `json:"min"`
+	Max uint16 `json:"max"`
+}
+
+// GroupInfo contains the properties and the last action of a group, as reported by the bridge.
+type GroupInfo struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"` // E.g. "Room", "Zone" or "LightGroup".
+	Lights []string `json:"lights"`
+	Action State    `json:"action"`
+}
+
+// Lights queries the bridge for all lights, mapped by their ID.
+func (b *Bridge) Lights() (map[string]LightInfo, error) {
+	var lights map[string]LightInfo
+	if err := b.request(context.Background(), http.MethodGet, b.resourceURL("lights"), nil, &lights); err != nil {
+		return nil, fmt.Errorf("failed to query lights: %w", err)
+	}
+
+	return lights, nil
+}
+
+// Groups queries the bridge for all groups, mapped by their ID.
+func (b *Bridge) Groups() (map[string]GroupInfo, error) {
+	var groups map[string]GroupInfo
+	if err := b.request(context.Background(), http.MethodGet, b.resourceURL("groups"), nil, &groups); err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// lightInfo queries the bridge for the light with the given ID.
+func (b *Bridge) lightInfo(id string) (LightInfo, error) {
+	var info LightInfo
+	if err := b.request(context.Background(), http.MethodGet, b.resourceURL("lights", id), nil, &info); err != nil {
+		return LightInfo{}, fmt.Errorf("failed to query light %q: %w", id, err)
+	}
+
+	return info, nil
+}
+
+// groupInfo queries the bridge for the group with the given ID.
+func (b *Bridge) groupInfo(id string) (GroupInfo, error) {
+	var info GroupInfo
+	if err := b.request(context.Background(), http.MethodGet, b.resourceURL("groups", id), nil, &info); err != nil {
+		return GroupInfo{}, fmt.Errorf("failed to query group %q: %w", id, err)
+	}
+
+	return info, nil
+}
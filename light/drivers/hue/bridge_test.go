@@ -0,0 +1,225 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testUsername = "test-user"
+
+// testBridge is a minimal stand-in for the local API of a Hue bridge.
+type testBridge struct {
+	*httptest.Server
+
+	mutex      sync.Mutex
+	linkButton bool
+	lights     map[string]*LightInfo
+	groups     map[string]*GroupInfo
+	alerts     []string // Resources that got an alert.
+}
+
+// newTestBridge returns a running bridge with an extended color light "1", a color temperature light "2", a dimmable light "3" and a group "1" containing all of them.
+// It's closed when the test finishes.
+func newTestBridge(t *testing.T) *testBridge {
+	b := &testBridge{
+		lights: map[string]*LightInfo{},
+		groups: map[string]*GroupInfo{},
+	}
+
+	var color, temperature, dimmable LightInfo
+	color.Type, color.Name, color.ModelID, color.ManufacturerName, color.SWVersion = "Extended color light", "Desk", "LCT015", "Signify Netherlands B.V.", "1.50.2_r30933"
+	color.UniqueID = "00:17:88:01:00:bd:c7:b9-0b"
+	color.Capabilities.Control.MaxLumen = 806
+	color.Capabilities.Control.ColorGamutType = "C"
+	color.Capabilities.Control.ColorGamut = &GamutC
+	color.Capabilities.Control.CT = &MiredRange{153, 500}
+	temperature.Type, temperature.Name = "Color temperature light", "Kitchen"
+	temperature.Capabilities.Control.MaxLumen = 806
+	temperature.Capabilities.Control.CT = &MiredRange{153, 454}
+	dimmable.Type, dimmable.Name = "Dimmable light", "Hallway" // Like older bridges, without capabilities.
+
+	b.lights["1"], b.lights["2"], b.lights["3"] = &color, &temperature, &dimmable
+	b.groups["1"] = &GroupInfo{Name: "Living room", Type: "Room", Lights: []string{"1", "2", "3"}}
+
+	b.Server = httptest.NewServer(http.HandlerFunc(b.handle))
+	t.Cleanup(b.Close)
+
+	return b
+}
+
+// pressLinkButton allows new users to be created.
+func (b *testBridge) pressLinkButton() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.linkButton = true
+}
+
+// lightState returns the current state of the given light.
+func (b *testBridge) lightState(id string) State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.lights[id].State
+}
+
+// mergeState applies all set fields of src to dst, like the bridge does.
+func mergeState(dst *State, src State) {
+	if src.On != nil {
+		dst.On = src.On
+	}
+	if src.Bri != nil {
+		dst.Bri = src.Bri
+	}
+	if src.XY != nil {
+		dst.XY, dst.ColorMode = src.XY, ColorModeXY
+	}
+	if src.CT != nil {
+		dst.CT, dst.ColorMode = src.CT, ColorModeCT
+	}
+}
+
+// writeError writes an error response with the given type.
+func writeError(w http.ResponseWriter, errorType ErrorType, address, description string) {
+	fmt.Fprintf(w, `[{"error":{"type":%d,"address":%q,"description":%q}}]`, errorType, address, description)
+}
+
+func (b *testBridge) handle(w http.ResponseWriter, r *http.Request) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "api" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 && r.Method == http.MethodPost {
+		if !b.linkButton {
+			writeError(w, ErrorTypeLinkButtonNotPressed, "", "link button not pressed")
+			return
+		}
+		fmt.Fprintf(w, `[{"success":{"username":%q}}]`, testUsername)
+		return
+	}
+
+	if len(parts) < 2 || parts[1] != testUsername {
+		writeError(w, ErrorTypeUnauthorizedUser, r.URL.Path, "unauthorized user")
+		return
+	}
+	resource := "/" + strings.Join(parts[2:], "/")
+
+	var state State
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			writeError(w, 2, resource, "body contains invalid JSON")
+			return
+		}
+	}
+
+	var result interface{}
+	switch {
+	case len(parts) == 3 && parts[2] == "lights" && r.Method == http.MethodGet:
+		result = b.lights
+	case len(parts) == 3 && parts[2] == "groups" && r.Method == http.MethodGet:
+		result = b.groups
+	case len(parts) == 4 && parts[2] == "lights" && r.Method == http.MethodGet && b.lights[parts[3]] != nil:
+		result = b.lights[parts[3]]
+	case len(parts) == 4 && parts[2] == "groups" && r.Method == http.MethodGet && b.groups[parts[3]] != nil:
+		result = b.groups[parts[3]]
+	case len(parts) == 5 && parts[2] == "lights" && parts[4] == "state" && r.Method == http.MethodPut && b.lights[parts[3]] != nil:
+		mergeState(&b.lights[parts[3]].State, state)
+		if state.Alert != "" {
+			b.alerts = append(b.alerts, resource)
+		}
+		result = []map[string]interface{}{{"success": map[string]interface{}{resource: true}}}
+	case len(parts) == 5 && parts[2] == "groups" && parts[4] == "action" && r.Method == http.MethodPut && b.groups[parts[3]] != nil:
+		group := b.groups[parts[3]]
+		mergeState(&group.Action, state)
+		for _, id := range group.Lights {
+			mergeState(&b.lights[id].State, state)
+		}
+		if state.Alert != "" {
+			b.alerts = append(b.alerts, resource)
+		}
+		result = []map[string]interface{}{{"success": map[string]interface{}{resource: true}}}
+	default:
+		writeError(w, ErrorTypeResourceNotAvailable, resource, "resource, "+resource+", not available")
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestPair(t *testing.T) {
+	tb := newTestBridge(t)
+
+	defer func(interval time.Duration) { pairInterval = interval }(pairInterval)
+	pairInterval = 10 * time.Millisecond
+
+	// Without pressing the link button, pairing times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Pair(ctx, tb.URL, "d3iot#test"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Pair() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	time.AfterFunc(50*time.Millisecond, tb.pressLinkButton)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	username, err := Pair(ctx, tb.URL, "d3iot#test")
+	if err != nil {
+		t.Fatalf("Pair() failed: %v", err)
+	}
+	if username != testUsername {
+		t.Errorf("Pair() returned username %q, want %q", username, testUsername)
+	}
+}
+
+func TestBridgeErrors(t *testing.T) {
+	tb := newTestBridge(t)
+
+	var errAPI *ErrAPI
+	if _, err := NewBridge(tb.URL, "unknown").Lights(); !errors.As(err, &errAPI) || errAPI.ErrorType() != ErrorTypeUnauthorizedUser {
+		t.Errorf("Lights() with an unknown user returned %v, want error type %d", err, ErrorTypeUnauthorizedUser)
+	}
+
+	if _, err := NewBridge(tb.URL, testUsername).Light("99"); !errors.As(err, &errAPI) || errAPI.ErrorType() != ErrorTypeResourceNotAvailable {
+		t.Errorf("Light() with an unknown ID returned %v, want error type %d", err, ErrorTypeResourceNotAvailable)
+	}
+}
+
+func TestBridgeLists(t *testing.T) {
+	tb := newTestBridge(t)
+	bridge := NewBridge(tb.URL, testUsername)
+
+	lights, err := bridge.Lights()
+	if err != nil {
+		t.Fatalf("Lights() failed: %v", err)
+	}
+	if len(lights) != 3 || lights["1"].Name != "Desk" || *lights["1"].Capabilities.Control.ColorGamut != GamutC {
+		t.Errorf("Lights() returned %v", lights)
+	}
+
+	groups, err := bridge.Groups()
+	if err != nil {
+		t.Fatalf("Groups() failed: %v", err)
+	}
+	if len(groups) != 1 || groups["1"].Name != "Living room" || len(groups["1"].Lights) != 3 {
+		t.Errorf("Groups() returned %v", groups)
+	}
+}
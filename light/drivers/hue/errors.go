@@ -0,0 +1,44 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import "fmt"
+
+// ErrorType represents the type of an error that was returned by the bridge.
+type ErrorType int
+
+const (
+	ErrorTypeUnauthorizedUser     ErrorType = 1   // The username doesn't exist or isn't paired with the bridge.
+	ErrorTypeResourceNotAvailable ErrorType = 3   // The light or group doesn't exist.
+	ErrorTypeLinkButtonNotPressed ErrorType = 101 // The link button has to be pressed before a new user can be created.
+	ErrorTypeDeviceOff            ErrorType = 201 // A parameter can't be modified, as the device is turned off.
+)
+
+// ErrAPI is returned if the bridge responds with an error.
+type ErrAPI struct {
+	errorType   ErrorType
+	address     string
+	description string
+}
+
+func (e *ErrAPI) Error() string {
+	return fmt.Sprintf("hue bridge returned error type %d for %q: %v", e.errorType, e.address, e.description)
+}
+
+// ErrorType returns the type of the error that was returned by the bridge.
+func (e *ErrAPI) ErrorType() ErrorType {
+	return e.errorType
+}
+
+// Address returns the resource that caused the error.
+func (e *ErrAPI) Address() string {
+	return e.address
+}
+
+// Description returns the error message that was returned by the bridge.
+func (e *ErrAPI) Description() string {
+	return e.description
+}
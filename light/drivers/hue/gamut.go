@@ -0,0 +1,86 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"fmt"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Gamut contains the CIE 1931 xy chromaticities of the red, green and blue primaries of a light.
+type Gamut [3][2]float64
+
+// The gamuts of the different generations of Hue lights.
+var (
+	GamutA = Gamut{{0.704, 0.296}, {0.2151, 0.7106}, {0.138, 0.08}} // LivingColors, Iris, Bloom, LightStrips.
+	GamutB = Gamut{{0.675, 0.322}, {0.409, 0.518}, {0.167, 0.04}}   // First generation of Hue bulbs.
+	GamutC = Gamut{{0.6915, 0.3083}, {0.17, 0.7}, {0.1532, 0.0475}} // Hue bulbs since 2016 and LightStrip plus.
+)
+
+// lookupGamut returns the gamut for the given gamut type.
+func lookupGamut(gamutType string) (Gamut, bool) {
+	switch gamutType {
+	case "A":
+		return GamutA, true
+	case "B":
+		return GamutB, true
+	case "C":
+		return GamutC, true
+	}
+	return Gamut{}, false
+}
+
+// ColorProfile returns a color profile with the primaries of the gamut.
+// The primaries are balanced so that they add up to a D65 white point with the given luminance in lumen.
+// If D65 is outside of the gamut, like for gamut B, the white point is moved towards the center of the gamut until it's inside.
+//
+// The DCS of the profile is linear, so its channels are proportional to the light output of the primaries.
+func (g Gamut) ColorProfile(lumens float64) (*emission.ColorProfileGeneral, error) {
+	primaries := make(emission.TransformationLinDCSToXYZ, 0, len(g))
+	var center [2]float64
+	for _, xy := range g {
+		if xy[1] <= 0 {
+			return nil, fmt.Errorf("primary %v has no luminance", xy)
+		}
+		primaries = append(primaries, emission.CIE1931xyYAbs{X: xy[0], Y: xy[1], LuminanceY: 1}.CIE1931XYZAbs())
+		center[0], center[1] = center[0]+xy[0]/3, center[1]+xy[1]/3
+	}
+
+	inv, err := primaries.Inverted()
+	if err != nil {
+		return nil, fmt.Errorf("failed to invert primaries of gamut %v: %w", g, err)
+	}
+
+	d65 := emission.StandardIlluminantD65.CIE1931xyYRel()
+	var whitePoint emission.CIE1931XYZAbs
+	var scales emission.LinDCSVector
+	for step := 0; ; step++ {
+		if step > 20 {
+			return nil, fmt.Errorf("gamut %v doesn't span an area", g)
+		}
+		t := float64(step) / 20
+		whitePoint = emission.CIE1931xyYAbs{X: d65.X*(1-t) + center[0]*t, Y: d65.Y*(1-t) + center[1]*t, LuminanceY: lumens}.CIE1931XYZAbs()
+		if scales = inv.Multiplied(whitePoint); scales[0] > 0 && scales[1] > 0 && scales[2] > 0 {
+			break
+		}
+	}
+
+	balanced := make(emission.TransformationLinDCSToXYZ, 0, len(primaries))
+	for i, primary := range primaries {
+		balanced = append(balanced, primary.Scaled(scales[i]))
+	}
+
+	profile := &emission.ColorProfileGeneral{
+		WhitePointColor: whitePoint,
+		PrimaryColors:   balanced,
+	}
+	if err := profile.Init(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Group represents a group of lights on a Hue bridge, like a room or a zone.
+//
+// All lights of the group are controlled by a single command, so they change at the same time.
+// The group behaves like a single light with one module.
+// Use the lights of the group directly to control them individually.
+type Group struct {
+	bridge *Bridge
+	id     string
+	lights []string
+	model  *model
+
+	TransitionTime time.Duration // Transition time that is sent with SetColors(). Rounded to multiples of 100 ms.
+}
+
+// Check implementation of light.Light and optional light interfaces.
+var (
+	_ light.Light             = &Group{}
+	_ light.Switcher          = &Group{}
+	_ light.Identifier        = &Group{}
+	_ light.TemperatureRanger = &Group{}
+)
+
+// Group returns an object that represents the group with the given ID.
+// The group with the ID "0" contains all lights of the bridge.
+//
+// The color profile of the group is the one of the most capable light in the group.
+// The bridge maps colors into the gamut of every single light.
+func (b *Bridge) Group(id string) (*Group, error) {
+	info, err := b.groupInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.Lights) == 0 {
+		return nil, fmt.Errorf("group %q doesn't contain any lights", id)
+	}
+
+	lights, err := b.Lights()
+	if err != nil {
+		return nil, err
+	}
+
+	var best *model
+	for _, lightID := range info.Lights {
+		lightInfo, ok := lights[lightID]
+		if !ok {
+			continue
+		}
+		m, err := newModel(lightInfo)
+		if err != nil {
+			continue // Ignore unsupported devices like plugs.
+		}
+		if best == nil || m.kind > best.kind {
+			best = m
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("group %q doesn't contain any supported lights", id)
+	}
+
+	return &Group{bridge: b, id: id, lights: info.Lights, model: best}, nil
+}
+
+// ID returns the ID of the group on the bridge.
+func (g *Group) ID() string {
+	return g.id
+}
+
+// Lights returns the IDs of the lights in the group.
+func (g *Group) Lights() []string {
+	return append([]string(nil), g.lights...)
+}
+
+// SetAction sends the given state to all lights of the group.
+func (g *Group) SetAction(state State) error {
+	return g.bridge.request(context.Background(), http.MethodPut, g.bridge.resourceURL("groups", g.id, "action"), state, nil)
+}
+
+// GetAction queries the bridge for the last action that was sent to the group.
+func (g *Group) GetAction() (State, error) {
+	info, err := g.bridge.groupInfo(g.id)
+	if err != nil {
+		return State{}, err
+	}
+
+	return info.Action, nil
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+func (g *Group) SetColors(emissionValues ...emission.Value) error {
+	state, err := setColorsState(g.model, emissionValues)
+	if err != nil {
+		return err
+	}
+	state.TransitionTime = transitionTime(g.TransitionTime)
+
+	return g.SetAction(state)
+}
+
+// GetColors returns the emission value of the last action that was sent to the group.
+// This may differ from the state of the lights, if they were changed individually.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (g *Group) GetColors(emissionValues ...emission.ValueReceiver) error {
+	switch len(emissionValues) {
+	case 0:
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("got %d emission values, this device has only 1 module", len(emissionValues))
+	}
+
+	state, err := g.GetAction()
+	if err != nil {
+		return err
+	}
+
+	return getColorsState(g.model, state, emissionValues[0])
+}
+
+// Modules returns the number of modules, which is always 1.
+func (g *Group) Modules() int {
+	return 1
+}
+
+// ColorProfiles returns the color profile of the group's only module.
+func (g *Group) ColorProfiles() []emission.ColorProfile {
+	return []emission.ColorProfile{g.model.colorProfile}
+}
+
+// SetPower turns all lights of the group on or off.
+// This implements the light.Switcher interface.
+func (g *Group) SetPower(on bool) error {
+	return g.SetAction(State{On: &on})
+}
+
+// Power returns whether the last action turned the group on.
+// This implements the light.Switcher interface.
+func (g *Group) Power() (bool, error) {
+	state, err := g.GetAction()
+	if err != nil {
+		return false, err
+	}
+
+	return state.IsOn(), nil
+}
+
+// Identify lets all lights of the group do a single breathe cycle.
+// This implements the light.Identifier interface.
+func (g *Group) Identify() error {
+	return g.SetAction(State{Alert: "select"})
+}
+
+// TemperatureRange returns the interval of color temperatures that the most capable light of the group supports.
+// This implements the light.TemperatureRanger interface.
+func (g *Group) TemperatureRange() (min, max float64, ok bool) {
+	return g.model.temperatureRange()
+}
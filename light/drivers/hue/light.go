@@ -0,0 +1,198 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Light represents a single light that is connected to a Hue bridge.
+type Light struct {
+	bridge *Bridge
+	id     string
+	model  *model
+
+	TransitionTime time.Duration // Transition time that is sent with SetColors(). Rounded to multiples of 100 ms.
+}
+
+// Check implementation of light.Light and optional light interfaces.
+var (
+	_ light.Light             = &Light{}
+	_ light.Switcher          = &Light{}
+	_ light.Identifier        = &Light{}
+	_ light.InfoProvider      = &Light{}
+	_ light.TemperatureRanger = &Light{}
+)
+
+// Light returns an object that represents the light with the given ID.
+//
+// This will query the light's properties to determine its color profile.
+//
+//	bridge := hue.NewBridge("192.168.1.2", username)
+//	l, err := bridge.Light("1")
+func (b *Bridge) Light(id string) (*Light, error) {
+	info, err := b.lightInfo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newModel(info)
+	if err != nil {
+		return nil, fmt.Errorf("light %q: %w", id, err)
+	}
+
+	return &Light{bridge: b, id: id, model: m}, nil
+}
+
+// ID returns the ID of the light on the bridge.
+func (l *Light) ID() string {
+	return l.id
+}
+
+// transitionTime returns the given duration in multiples of 100 ms.
+func transitionTime(d time.Duration) *uint16 {
+	t := uint16((d + 50*time.Millisecond) / (100 * time.Millisecond))
+	return &t
+}
+
+// SetState sends the given state to the light.
+func (l *Light) SetState(state State) error {
+	return l.bridge.request(context.Background(), http.MethodPut, l.bridge.resourceURL("lights", l.id, "state"), state, nil)
+}
+
+// GetState queries the bridge for the state of the light.
+func (l *Light) GetState() (State, error) {
+	info, err := l.bridge.lightInfo(l.id)
+	if err != nil {
+		return State{}, err
+	}
+
+	return info.State, nil
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+func (l *Light) SetColors(emissionValues ...emission.Value) error {
+	state, err := setColorsState(l.model, emissionValues)
+	if err != nil {
+		return err
+	}
+	state.TransitionTime = transitionTime(l.TransitionTime)
+
+	return l.SetState(state)
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (l *Light) GetColors(emissionValues ...emission.ValueReceiver) error {
+	switch len(emissionValues) {
+	case 0:
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("got %d emission values, this device has only 1 module", len(emissionValues))
+	}
+
+	state, err := l.GetState()
+	if err != nil {
+		return err
+	}
+
+	return getColorsState(l.model, state, emissionValues[0])
+}
+
+// Modules returns the number of modules, which is always 1.
+func (l *Light) Modules() int {
+	return 1
+}
+
+// ColorProfiles returns the color profile of the light's only module.
+func (l *Light) ColorProfiles() []emission.ColorProfile {
+	return []emission.ColorProfile{l.model.colorProfile}
+}
+
+// SetPower turns the light on or off.
+// Turning the light on restores its last state.
+// This implements the light.Switcher interface.
+func (l *Light) SetPower(on bool) error {
+	return l.SetState(State{On: &on})
+}
+
+// Power queries the light for its on/off state.
+// This implements the light.Switcher interface.
+func (l *Light) Power() (bool, error) {
+	state, err := l.GetState()
+	if err != nil {
+		return false, err
+	}
+
+	return state.IsOn(), nil
+}
+
+// Identify lets the light do a single breathe cycle.
+// This implements the light.Identifier interface.
+func (l *Light) Identify() error {
+	return l.SetState(State{Alert: "select"})
+}
+
+// DeviceInfo queries the light for general information.
+// The MAC is the Zigbee address of the light.
+// This implements the light.InfoProvider interface.
+func (l *Light) DeviceInfo() (light.DeviceInfo, error) {
+	info, err := l.bridge.lightInfo(l.id)
+	if err != nil {
+		return light.DeviceInfo{}, err
+	}
+
+	mac := info.UniqueID
+	if i := strings.IndexByte(mac, '-'); i >= 0 {
+		mac = mac[:i]
+	}
+
+	return light.DeviceInfo{
+		Vendor:   info.ManufacturerName,
+		Model:    info.ModelID,
+		Firmware: info.SWVersion,
+		MAC:      mac,
+	}, nil
+}
+
+// TemperatureRange returns the interval of color temperatures that the light supports.
+// This implements the light.TemperatureRanger interface.
+func (l *Light) TemperatureRange() (min, max float64, ok bool) {
+	return l.model.temperatureRange()
+}
+
+// setColorsState returns the state that reproduces the emission values of a single module device.
+func setColorsState(m *model, emissionValues []emission.Value) (State, error) {
+	switch len(emissionValues) {
+	case 0:
+		off := false
+		return State{On: &off}, nil
+	case 1:
+		return m.state(emissionValues[0].IntoDCS(m.colorProfile))
+	default:
+		return State{}, fmt.Errorf("got %d emission values, this device has only 1 module", len(emissionValues))
+	}
+}
+
+// getColorsState writes the color of the state into the emission value.
+func getColorsState(m *model, state State, emissionValue emission.ValueReceiver) error {
+	vector, err := m.vector(state)
+	if err != nil {
+		return err
+	}
+
+	return emissionValue.FromDCS(m.colorProfile, vector)
+}
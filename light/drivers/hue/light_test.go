@@ -0,0 +1,227 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+func TestConformance(t *testing.T) {
+	for _, id := range []string{"1", "2", "3"} {
+		id := id
+		t.Run("Light"+id, func(t *testing.T) {
+			lighttest.Run(t, func(t *testing.T) light.Light {
+				l, err := NewBridge(newTestBridge(t).URL, testUsername).Light(id)
+				if err != nil {
+					t.Fatalf("Light() failed: %v", err)
+				}
+				return l
+			})
+		})
+	}
+
+	t.Run("Group", func(t *testing.T) {
+		lighttest.Run(t, func(t *testing.T) light.Light {
+			g, err := NewBridge(newTestBridge(t).URL, testUsername).Group("1")
+			if err != nil {
+				t.Fatalf("Group() failed: %v", err)
+			}
+			return g
+		})
+	})
+}
+
+func TestColorProfiles(t *testing.T) {
+	for _, gamut := range []Gamut{GamutA, GamutB, GamutC} {
+		profile, err := gamut.ColorProfile(800)
+		if err != nil {
+			t.Fatalf("ColorProfile() of gamut %v failed: %v", gamut, err)
+		}
+
+		// All primaries at full level result in the white point.
+		color, err := profile.DCSToXYZ(emission.DCSVector{1, 1, 1})
+		if err != nil {
+			t.Fatalf("DCSToXYZ() failed: %v", err)
+		}
+		if whitePoint := profile.WhitePoint(); math.Abs(color.X-whitePoint.X) > 1e-6 || math.Abs(color.Y-whitePoint.Y) > 1e-6 || math.Abs(color.Z-whitePoint.Z) > 1e-6 {
+			t.Errorf("Gamut %v has a white point of %v, want %v", gamut, color, whitePoint)
+		}
+		if math.Abs(color.Y-800) > 1e-6 {
+			t.Errorf("Gamut %v has a white point with %v lumen, want 800", gamut, color.Y)
+		}
+
+		// D65 is used as white point, if it's inside of the gamut.
+		if gamut != GamutB {
+			want := emission.StandardIlluminantD65.Absolute(800)
+			if math.Abs(color.X-want.X) > 1e-6 || math.Abs(color.Z-want.Z) > 1e-6 {
+				t.Errorf("Gamut %v has a white point of %v, want %v", gamut, color, want)
+			}
+		}
+
+		// The primaries have the chromaticities of the gamut.
+		for i, point := range profile.ChannelPoints() {
+			xyY := point.CIE1931xyYAbs()
+			if math.Abs(xyY.X-gamut[i][0]) > 1e-9 || math.Abs(xyY.Y-gamut[i][1]) > 1e-9 {
+				t.Errorf("Primary %d of gamut %v has chromaticity %v", i, gamut, xyY)
+			}
+		}
+	}
+}
+
+func TestLightStates(t *testing.T) {
+	tb := newTestBridge(t)
+	bridge := NewBridge(tb.URL, testUsername)
+
+	color, err := bridge.Light("1")
+	if err != nil {
+		t.Fatalf("Light() failed: %v", err)
+	}
+	color.TransitionTime = time.Second
+
+	// sRGB red is inside of gamut C, so the light gets the same chromaticity.
+	if err := color.SetColors(emission.StandardRGB{R: 1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	state := tb.lightState("1")
+	if !state.IsOn() || state.XY == nil || math.Abs(state.XY[0]-0.64) > 0.001 || math.Abs(state.XY[1]-0.33) > 0.001 {
+		t.Errorf("Light has state %+v, want sRGB red", state)
+	}
+
+	// Turning the light off doesn't change its color.
+	if err := color.SetColors(); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if state := tb.lightState("1"); state.IsOn() || state.XY == nil {
+		t.Errorf("Light has state %+v, want off with the previous color", state)
+	}
+
+	// A color temperature set by the Hue app is read back as a black body.
+	ct, bri, on := uint16(370), uint8(254), true
+	if err := color.SetState(State{On: &on, Bri: &bri, CT: &ct}); err != nil {
+		t.Fatalf("SetState() failed: %v", err)
+	}
+	var xyY emission.CIE1931xyYAbs
+	if err := color.GetColors(&xyY); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+	want := emission.BlackBodyFixed{Temperature: 1e6 / 370, Luminance: 1}.CIE1931xyYAbs()
+	if math.Abs(xyY.X-want.X) > 0.001 || math.Abs(xyY.Y-want.Y) > 0.001 {
+		t.Errorf("GetColors() returned %v, want chromaticity %v", xyY, want)
+	}
+
+	temperature, err := bridge.Light("2")
+	if err != nil {
+		t.Fatalf("Light() failed: %v", err)
+	}
+	if err := temperature.SetColors(emission.DCSVector{0, 0.5}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if state := tb.lightState("2"); !state.IsOn() || state.CT == nil || *state.CT != 454 || state.Bri == nil || *state.Bri != 127 {
+		t.Errorf("Light has state %+v, want warm white at half brightness", state)
+	}
+
+	if min, max, ok := temperature.TemperatureRange(); !ok || math.Abs(min-1e6/454) > 1e-9 || math.Abs(max-1e6/153) > 1e-9 {
+		t.Errorf("TemperatureRange() returned [%v, %v] %v", min, max, ok)
+	}
+
+	dimmable, err := bridge.Light("3")
+	if err != nil {
+		t.Fatalf("Light() failed: %v", err)
+	}
+	if _, _, ok := dimmable.TemperatureRange(); ok {
+		t.Errorf("Dimmable light reports a color temperature range")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tb := newTestBridge(t)
+
+	l, err := NewBridge(tb.URL, testUsername).Light("1")
+	if err != nil {
+		t.Fatalf("Light() failed: %v", err)
+	}
+
+	info, err := l.DeviceInfo()
+	if err != nil {
+		t.Fatalf("DeviceInfo() failed: %v", err)
+	}
+	if want := (light.DeviceInfo{Vendor: "Signify Netherlands B.V.", Model: "LCT015", Firmware: "1.50.2_r30933", MAC: "00:17:88:01:00:bd:c7:b9"}); info != want {
+		t.Errorf("DeviceInfo() returned %+v, want %+v", info, want)
+	}
+
+	if err := l.SetPower(true); err != nil {
+		t.Fatalf("SetPower() failed: %v", err)
+	}
+	if on, err := l.Power(); err != nil || !on {
+		t.Errorf("Power() returned %v, %v, want true", on, err)
+	}
+
+	if err := l.Identify(); err != nil {
+		t.Fatalf("Identify() failed: %v", err)
+	}
+	tb.mutex.Lock()
+	alerts := tb.alerts
+	tb.mutex.Unlock()
+	if len(alerts) != 1 || alerts[0] != "/lights/1/state" {
+		t.Errorf("Bridge got alerts %v, want one for light 1", alerts)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	tb := newTestBridge(t)
+
+	g, err := NewBridge(tb.URL, testUsername).Group("1")
+	if err != nil {
+		t.Fatalf("Group() failed: %v", err)
+	}
+	if g.Modules() != 1 || g.ColorProfiles()[0].Channels() != 3 {
+		t.Errorf("Group doesn't use the color profile of its color light")
+	}
+
+	if err := g.SetColors(emission.StandardRGB{R: 1, G: 1, B: 1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	for _, id := range g.Lights() {
+		if state := tb.lightState(id); !state.IsOn() || state.Bri == nil || *state.Bri != 254 {
+			t.Errorf("Light %s has state %+v, want on at full brightness", id, state)
+		}
+	}
+}
+
+func TestOpenURI(t *testing.T) {
+	tb := newTestBridge(t)
+
+	l, err := light.Open(context.Background(), "hue://"+testUsername+"@"+tb.Listener.Addr().String()+"/lights/2?transition=400ms")
+	if err != nil {
+		t.Fatalf("light.Open() failed: %v", err)
+	}
+	if hl, ok := l.(*Light); !ok || hl.ID() != "2" || hl.TransitionTime != 400*time.Millisecond {
+		t.Errorf("light.Open() returned %#v", l)
+	}
+
+	if l, err := light.Open(context.Background(), "hue://"+testUsername+"@"+tb.Listener.Addr().String()+"/groups/1"); err != nil {
+		t.Errorf("light.Open() failed: %v", err)
+	} else if _, ok := l.(*Group); !ok {
+		t.Errorf("light.Open() returned %#v, want a group", l)
+	}
+
+	for _, uri := range []string{
+		"hue://" + tb.Listener.Addr().String() + "/lights/1",
+		"hue://" + testUsername + "@" + tb.Listener.Addr().String() + "/lights",
+		"hue://" + testUsername + "@" + tb.Listener.Addr().String() + "/sensors/1",
+	} {
+		if _, err := light.Open(context.Background(), uri); err == nil {
+			t.Errorf("light.Open(%q) succeeded, want error", uri)
+		}
+	}
+}
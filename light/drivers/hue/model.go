@@ -0,0 +1,242 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Defaults for lights that don't report their capabilities.
+const (
+	defaultLumens       = 800  // Typical for an A19 bulb.
+	defaultMinMired     = 153  // 6500 K.
+	defaultMaxMired     = 500  // 2000 K.
+	dimmableTemperature = 2700 // In K. The color of white only lights.
+)
+
+// lightKind describes how the color of a light is controlled.
+type lightKind int
+
+const (
+	kindDimmable    lightKind = iota // Brightness only.
+	kindTemperature                  // Brightness and color temperature.
+	kindColor                        // Brightness and xy chromaticity.
+)
+
+// model translates between the DCS of a light and its state.
+//
+// The DCS of the different kinds of lights is:
+//
+//	- Color lights: Linear red, green and blue of the gamut.
+//	- Color temperature lights: Cold white and warm white at the ends of the color temperature range.
+//	- Dimmable lights: A single white channel.
+type model struct {
+	kind         lightKind
+	colorProfile *emission.ColorProfileGeneral
+	invPrimaries emission.TransformationXYZToLinDCS // Only used by color lights.
+
+	// Color temperature range in mired.
+	// Zero for lights that don't support color temperatures.
+	minMired, maxMired uint16
+}
+
+// newModel returns the model of the light with the given properties.
+func newModel(info LightInfo) (*model, error) {
+	control := info.Capabilities.Control
+
+	lumens := control.MaxLumen
+	if lumens <= 0 {
+		lumens = defaultLumens
+	}
+
+	m := &model{}
+	if control.CT != nil && control.CT.Min > 0 && control.CT.Max > control.CT.Min {
+		m.minMired, m.maxMired = control.CT.Min, control.CT.Max
+	}
+
+	lightType := strings.ToLower(info.Type)
+	switch {
+	case control.ColorGamut != nil || strings.Contains(lightType, "color light"):
+		m.kind = kindColor
+
+		gamut, ok := lookupGamut(control.ColorGamutType)
+		switch {
+		case control.ColorGamut != nil:
+			gamut = *control.ColorGamut
+		case !ok && strings.HasPrefix(lightType, "extended"):
+			gamut = GamutC
+		case !ok:
+			gamut = GamutA
+		}
+
+		var err error
+		if m.colorProfile, err = gamut.ColorProfile(lumens); err != nil {
+			return nil, err
+		}
+		if m.invPrimaries, err = m.colorProfile.PrimaryColors.Inverted(); err != nil {
+			return nil, fmt.Errorf("failed to invert primaries: %w", err)
+		}
+
+	case control.CT != nil || strings.Contains(lightType, "temperature light"):
+		m.kind = kindTemperature
+		if m.minMired == 0 {
+			m.minMired, m.maxMired = defaultMinMired, defaultMaxMired
+		}
+
+		cold := emission.BlackBodyFixed{Temperature: 1e6 / float64(m.minMired), Luminance: lumens}.CIE1931XYZAbs()
+		warm := emission.BlackBodyFixed{Temperature: 1e6 / float64(m.maxMired), Luminance: lumens}.CIE1931XYZAbs()
+		m.colorProfile = &emission.ColorProfileGeneral{
+			WhitePointColor: cold.Sum(warm).Scaled(0.5),
+			WhiteColors:     emission.TransformationLinDCSToXYZ{cold, warm},
+			OutputLimiter:   emission.OutputLimiterSum{Limit: 1}, // The brightness doesn't depend on the color temperature.
+		}
+		if err := m.colorProfile.Init(); err != nil {
+			return nil, err
+		}
+
+	case strings.Contains(lightType, "dimmable"):
+		m.kind = kindDimmable
+
+		white := emission.BlackBodyFixed{Temperature: dimmableTemperature, Luminance: lumens}.CIE1931XYZAbs()
+		m.colorProfile = &emission.ColorProfileGeneral{
+			WhitePointColor: white,
+			WhiteColors:     emission.TransformationLinDCSToXYZ{white},
+		}
+		if err := m.colorProfile.Init(); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported light type %q", info.Type)
+	}
+
+	return m, nil
+}
+
+// temperatureRange returns the range of supported color temperatures in K.
+func (m *model) temperatureRange() (min, max float64, ok bool) {
+	if m.minMired == 0 {
+		return 0, 0, false
+	}
+	return 1e6 / float64(m.maxMired), 1e6 / float64(m.minMired), true
+}
+
+// briFromLevel returns the brightness parameter for a level in the range of [0, 1].
+// The bool is false if the light should be turned off.
+func briFromLevel(level float64) (uint8, bool) {
+	bri := math.Round(level * 254)
+	if bri < 1 {
+		return 0, false
+	}
+	if bri > 254 {
+		bri = 254
+	}
+	return uint8(bri), true
+}
+
+// state returns the state that reproduces the given DCS vector.
+func (m *model) state(v emission.DCSVector) (State, error) {
+	if v.Channels() != m.colorProfile.Channels() {
+		return State{}, fmt.Errorf("unexpected number of channels. Got %d, want %d", v.Channels(), m.colorProfile.Channels())
+	}
+	linV := v.ClampedAndLinearized(nil)
+
+	on, off := true, false
+	var state State
+
+	switch m.kind {
+	case kindColor:
+		level := math.Max(linV[0], math.Max(linV[1], linV[2]))
+		bri, ok := briFromLevel(level)
+		if !ok {
+			return State{On: &off}, nil
+		}
+
+		color, err := m.colorProfile.PrimaryColors.Multiplied(linV)
+		if err != nil {
+			return State{}, err
+		}
+		sum := color.X + color.Y + color.Z
+		xy := [2]float64{math.Round(color.X/sum*1e4) / 1e4, math.Round(color.Y/sum*1e4) / 1e4}
+		state = State{On: &on, Bri: &bri, XY: &xy}
+
+	case kindTemperature:
+		level := linV[0] + linV[1]
+		bri, ok := briFromLevel(level)
+		if !ok {
+			return State{On: &off}, nil
+		}
+
+		// Interpolate linearly in mired between warm and cold white.
+		coldRatio := math.Min(linV[0]/level, 1)
+		ct := uint16(math.Round(float64(m.maxMired) - coldRatio*float64(m.maxMired-m.minMired)))
+		state = State{On: &on, Bri: &bri, CT: &ct}
+
+	default:
+		bri, ok := briFromLevel(linV[0])
+		if !ok {
+			return State{On: &off}, nil
+		}
+		state = State{On: &on, Bri: &bri}
+	}
+
+	return state, nil
+}
+
+// vector returns the DCS vector that represents the given state.
+func (m *model) vector(s State) (emission.DCSVector, error) {
+	result := make(emission.DCSVector, m.colorProfile.Channels())
+	if !s.IsOn() {
+		return result, nil
+	}
+
+	level := 1.0
+	if s.Bri != nil {
+		level = float64(*s.Bri) / 254
+	}
+
+	switch m.kind {
+	case kindColor:
+		var xy emission.CIE1931xyYAbs
+		switch {
+		case s.ColorMode == ColorModeCT && s.CT != nil && *s.CT > 0:
+			xy = emission.BlackBodyFixed{Temperature: 1e6 / float64(*s.CT), Luminance: 1}.CIE1931xyYAbs()
+		case s.XY != nil:
+			xy = emission.CIE1931xyYAbs{X: s.XY[0], Y: s.XY[1], LuminanceY: 1}
+		default:
+			return nil, fmt.Errorf("state %v doesn't contain a color", s)
+		}
+		if xy.Y <= 0 {
+			return nil, fmt.Errorf("invalid chromaticity %v", xy)
+		}
+
+		linV := m.invPrimaries.Multiplied(xy.CIE1931XYZAbs()).ClampedToPositive()
+		max := math.Max(linV[0], math.Max(linV[1], linV[2]))
+		if max <= 0 {
+			return result, nil
+		}
+		for i := range result {
+			result[i] = linV[i] / max * level
+		}
+
+	case kindTemperature:
+		coldRatio := 0.5
+		if s.CT != nil {
+			coldRatio = (float64(m.maxMired) - float64(*s.CT)) / float64(m.maxMired-m.minMired)
+			coldRatio = math.Max(0, math.Min(1, coldRatio))
+		}
+		result[0], result[1] = level*coldRatio, level*(1-coldRatio)
+
+	default:
+		result[0] = level
+	}
+
+	return result, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+func init() {
+	light.Register("hue", openURI)
+}
+
+// openURI creates a light object from an URI in the form of
+//
+//	hue://username@host[:port]/lights/id[?transition=0s]
+//	hue://username@host[:port]/groups/id[?transition=0s]
+//
+// The username has to be created once via Pair().
+func openURI(ctx context.Context, uri *url.URL) (light.Light, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if uri.Host == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a host", uri)
+	}
+	if uri.User == nil || uri.User.Username() == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a username", uri)
+	}
+	bridge := NewBridge(uri.Host, uri.User.Username())
+
+	var transition time.Duration
+	if value := uri.Query().Get("transition"); value != "" {
+		var err error
+		if transition, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("failed to parse transition %q: %w", value, err)
+		}
+	}
+
+	parts := strings.Split(strings.Trim(uri.Path, "/"), "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a light or group, e.g. /lights/1", uri)
+	}
+
+	switch parts[0] {
+	case "lights":
+		l, err := bridge.Light(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		l.TransitionTime = transition
+		return l, nil
+
+	case "groups":
+		g, err := bridge.Group(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		g.TransitionTime = transition
+		return g, nil
+	}
+
+	return nil, fmt.Errorf("unknown resource %q, want lights or groups", parts[0])
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hue
+
+// ColorMode describes which of the color parameters of a state is active.
+type ColorMode string
+
+const (
+	ColorModeXY ColorMode = "xy" // CIE 1931 xy chromaticity.
+	ColorModeCT ColorMode = "ct" // Color temperature in mired.
+	ColorModeHS ColorMode = "hs" // Hue and saturation. The bridge also reports the equivalent xy value.
+)
+
+// State contains the state of a light, or the last action of a group.
+//
+// Fields that are nil are not sent to the bridge, and are not reported by it.
+type State struct {
+	On             *bool       `json:"on,omitempty"`
+	Bri            *uint8      `json:"bri,omitempty"`            // Brightness in the range of [1, 254].
+	XY             *[2]float64 `json:"xy,omitempty"`             // CIE 1931 xy chromaticity.
+	CT             *uint16     `json:"ct,omitempty"`             // Color temperature in mired.
+	Alert          string      `json:"alert,omitempty"`          // "none", "select" or "lselect".
+	ColorMode      ColorMode   `json:"colormode,omitempty"`      // Reported by the bridge, ignored when sent.
+	TransitionTime *uint16     `json:"transitiontime,omitempty"` // In multiples of 100 ms. Not reported by the bridge.
+	Reachable      *bool       `json:"reachable,omitempty"`      // Reported by the bridge, ignored when sent.
+}
+
+// IsOn returns whether the state has the on flag set.
+func (s State) IsOn() bool {
+	return s.On != nil && *s.On
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+// Check implementation of optional light interfaces.
+var (
+	_ light.Switcher          = &Light{}
+	_ light.InfoProvider      = &Light{}
+	_ light.TemperatureRanger = &Light{}
+)
+
+// SetPower turns the light on or off.
+// Turning the light on restores its last color.
+// This implements the light.Switcher interface.
+func (l *Light) SetPower(on bool) error {
+	var level uint16
+	if on {
+		level = 0xFFFF
+	}
+
+	return l.set(MessageSetLightPower, setLightPower{Level: level})
+}
+
+// Power queries the light for its on/off state.
+// This implements the light.Switcher interface.
+func (l *Light) Power() (bool, error) {
+	var state power
+	if err := l.get(MessageGetLightPower, MessageStateLightPower, &state); err != nil {
+		return false, err
+	}
+
+	return state.Level > 0, nil
+}
+
+// Label queries the name of the light that was set in the LIFX app.
+func (l *Light) Label() (string, error) {
+	var state stateLabel
+	if err := l.get(MessageGetLabel, MessageStateLabel, &state); err != nil {
+		return "", err
+	}
+
+	return state.label(), nil
+}
+
+// DeviceInfo queries the light for general information.
+// This implements the light.InfoProvider interface.
+func (l *Light) DeviceInfo() (light.DeviceInfo, error) {
+	var firmware stateHostFirmware
+	if err := l.get(MessageGetHostFirmware, MessageStateHostFirmware, &firmware); err != nil {
+		return light.DeviceInfo{}, err
+	}
+
+	l.connMutex.Lock()
+	mac := net.HardwareAddr(l.target[:6]).String()
+	l.connMutex.Unlock()
+
+	return light.DeviceInfo{
+		Vendor:   "LIFX",
+		Model:    l.product.Name,
+		Firmware: fmt.Sprintf("%d.%d", firmware.VersionMajor, firmware.VersionMinor),
+		MAC:      mac,
+	}, nil
+}
+
+// TemperatureRange returns the interval of color temperatures that the product supports.
+// This implements the light.TemperatureRanger interface.
+func (l *Light) TemperatureRange() (min, max float64, ok bool) {
+	return float64(l.product.MinKelvin), float64(l.product.MaxKelvin), true
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// connection contains everything that is needed to communicate with a LIFX device.
+type connection struct {
+	address string
+
+	deadline  time.Duration // Default timeout duration for any communication operation (sending and receiving).
+	retries   uint          // Number of retries when the deadline got exceeded.
+	connMutex sync.Mutex    // Mutex preventing simultaneous connections to this device, and protecting the fields below.
+
+	source   uint32  // Identifies this client in responses.
+	sequence uint8   // Sequence number of the last request.
+	target   [8]byte // MAC address of the device. Zero until the device responded once.
+}
+
+// newConnection returns a connection to the device with the given address and default parameters.
+func newConnection(address string) connection {
+	return connection{
+		address:  address,
+		deadline: 200 * time.Millisecond,
+		retries:  5,
+		source:   rand.New(rand.NewSource(time.Now().UnixNano())).Uint32()%0xFFFFFFFE + 2, // 0 and 1 are reserved.
+	}
+}
+
+// query sends a message with the given payload and collects responses of the given type.
+// It waits for responses until accept returns true, or accept is nil and one response was received.
+//
+// Set messages should use MessageAcknowledgement as response type, in that case an acknowledgement is requested.
+func (c *connection) query(messageType MessageType, payload interface{}, responseType MessageType, accept func(responses []Packet) bool) ([]Packet, error) {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	payloadData, err := encodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Function that sends the request, and tries to receive all response packets.
+	sendFunc := func() ([]Packet, error) {
+		c.sequence++
+		request := Packet{
+			Header: Header{
+				Tagged:      c.target == [8]byte{},
+				Source:      c.source,
+				Target:      c.target,
+				AckRequired: responseType == MessageAcknowledgement,
+				ResRequired: responseType != MessageAcknowledgement,
+				Sequence:    c.sequence,
+				Type:        messageType,
+			},
+			Payload: payloadData,
+		}
+		data, err := request.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		conn.SetDeadline(time.Now().Add(c.deadline))
+		if _, err := conn.Write(data); err != nil {
+			return nil, err
+		}
+
+		var responses []Packet
+		buf := make([]byte, 1500)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+
+			var response Packet
+			if err := response.UnmarshalBinary(buf[:n]); err != nil {
+				continue // Ignore garbage.
+			}
+			if response.Source != c.source || response.Sequence != c.sequence || response.Type != responseType {
+				continue // Ignore responses to older requests.
+			}
+			c.target = response.Target
+
+			responses = append(responses, response)
+			if accept == nil || accept(responses) {
+				return responses, nil
+			}
+		}
+	}
+
+	// Try to communicate, at most c.retries + 1 times.
+	for i := uint(0); i <= c.retries; i++ {
+		var responses []Packet
+		if responses, err = sendFunc(); err == nil {
+			return responses, nil
+		}
+	}
+
+	return nil, fmt.Errorf("message type %d to %s: %w", messageType, c.address, err)
+}
+
+// get sends a get message and decodes the response into result.
+func (c *connection) get(messageType, responseType MessageType, result interface{}) error {
+	responses, err := c.query(messageType, nil, responseType, nil)
+	if err != nil {
+		return err
+	}
+
+	return responses[0].decodePayload(result)
+}
+
+// set sends a set message and waits for the acknowledgement.
+func (c *connection) set(messageType MessageType, payload interface{}) error {
+	_, err := c.query(messageType, payload, MessageAcknowledgement, nil)
+	return err
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeDevice is an in-process LIFX device that listens on a local UDP port.
+type fakeDevice struct {
+	conn net.PacketConn
+	mac  [8]byte
+
+	mutex    sync.Mutex
+	product  uint32
+	power    uint16
+	label    string
+	colors   []HSBK // Colors of all zones.
+	pending  []HSBK // Buffered zone changes that are not applied yet.
+	drop     int    // Number of requests that are ignored, to simulate packet loss.
+	received []MessageType
+}
+
+// newFakeDevice returns a running device of the given product with the given number of zones.
+// It's closed when the test finishes.
+func newFakeDevice(t *testing.T, product uint32, zones int) *fakeDevice {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	d := &fakeDevice{
+		conn:    conn,
+		mac:     [8]byte{0xd0, 0x73, 0xd5, 0x01, 0x02, 0x03},
+		product: product,
+		label:   "Desk",
+		colors:  make([]HSBK, zones),
+		pending: make([]HSBK, zones),
+	}
+	go d.serve()
+
+	return d
+}
+
+// address returns the address that the device listens on.
+func (d *fakeDevice) address() string {
+	return d.conn.LocalAddr().String()
+}
+
+// state returns the power level and a copy of the colors of all zones.
+func (d *fakeDevice) state() (uint16, []HSBK) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.power, append([]HSBK(nil), d.colors...)
+}
+
+// messages returns the types of all received messages, and clears the list.
+func (d *fakeDevice) messages() []MessageType {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	result := d.received
+	d.received = nil
+	return result
+}
+
+func (d *fakeDevice) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var request Packet
+		if err := request.UnmarshalBinary(buf[:n]); err != nil {
+			continue
+		}
+		if !request.Tagged && request.Target != d.mac {
+			continue
+		}
+
+		d.mutex.Lock()
+		if d.drop > 0 {
+			d.drop--
+			d.mutex.Unlock()
+			continue
+		}
+		d.received = append(d.received, request.Type)
+		responses := d.handle(request)
+		d.mutex.Unlock()
+
+		for _, response := range responses {
+			data, err := response.MarshalBinary()
+			if err != nil {
+				panic(err)
+			}
+			d.conn.WriteTo(data, from)
+		}
+	}
+}
+
+// handle applies the request and returns the responses.
+// The mutex has to be held.
+func (d *fakeDevice) handle(request Packet) []Packet {
+	var responses []Packet
+	respond := func(messageType MessageType, payload interface{}) {
+		data, err := encodePayload(payload)
+		if err != nil {
+			panic(err)
+		}
+		responses = append(responses, Packet{
+			Header:  Header{Source: request.Source, Target: d.mac, Sequence: request.Sequence, Type: messageType},
+			Payload: data,
+		})
+	}
+
+	switch request.Type {
+	case MessageGetService:
+		respond(MessageStateService, stateService{Service: 1, Port: uint32(d.conn.LocalAddr().(*net.UDPAddr).Port)})
+	case MessageGetVersion:
+		respond(MessageStateVersion, stateVersion{Vendor: vendorLIFX, Product: d.product})
+	case MessageGetHostFirmware:
+		respond(MessageStateHostFirmware, stateHostFirmware{VersionMajor: 3, VersionMinor: 70})
+	case MessageGetLabel:
+		var state stateLabel
+		copy(state.Label[:], d.label)
+		respond(MessageStateLabel, state)
+	case MessageGetPower, MessageGetLightPower:
+		responseType := MessageStatePower
+		if request.Type == MessageGetLightPower {
+			responseType = MessageStateLightPower
+		}
+		respond(responseType, power{Level: d.power})
+	case MessageSetPower, MessageSetLightPower:
+		var state power
+		if request.decodePayload(&state) == nil {
+			d.power = state.Level
+		}
+	case MessageGetColor:
+		state := lightState{Color: d.colors[0], Power: d.power}
+		copy(state.Label[:], d.label)
+		respond(MessageLightState, state)
+	case MessageSetColor:
+		var message setColor
+		if request.decodePayload(&message) == nil {
+			for i := range d.colors {
+				d.colors[i], d.pending[i] = message.Color, message.Color
+			}
+		}
+	case MessageSetExtendedColorZones:
+		var message setExtendedColorZones
+		if request.decodePayload(&message) == nil {
+			if int(message.Index) < len(d.pending) {
+				copy(d.pending[message.Index:], message.Colors[:message.ColorsCount])
+			}
+			if message.Apply != MultizoneNoApply {
+				copy(d.colors, d.pending)
+			}
+		}
+	case MessageGetExtendedColorZones:
+		for index := 0; index < len(d.colors); index += maxZonesPerMessage {
+			state := stateExtendedColorZones{Count: uint16(len(d.colors)), Index: uint16(index)}
+			state.ColorsCount = uint8(copy(state.Colors[:], d.colors[index:]))
+			respond(MessageStateExtendedColorZones, state)
+		}
+	}
+
+	if request.AckRequired {
+		respond(MessageAcknowledgement, nil)
+	}
+
+	return responses
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+)
+
+// discoveryInterval is the time between two discovery broadcasts.
+var discoveryInterval = 500 * time.Millisecond
+
+// Device is a LIFX device that responded to a discovery.
+type Device struct {
+	Address string           // The address of the device, can be used with NewLight().
+	MAC     net.HardwareAddr // The MAC address of the device.
+}
+
+// Discover searches for LIFX devices by broadcasting to the given address, until the context is done.
+// If the address is empty, the broadcast address of the local network with the default port is used.
+//
+// The discovery message is repeated regularly, so devices that missed one message are still found.
+// Every device is only returned once.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+//	defer cancel()
+//	devices, err := lifx.Discover(ctx, "")
+func Discover(ctx context.Context, address string) ([]Device, error) {
+	if address == "" {
+		address = net.JoinHostPort("255.255.255.255", strconv.Itoa(DefaultPort))
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	source := rand.New(rand.NewSource(time.Now().UnixNano())).Uint32()%0xFFFFFFFE + 2 // 0 and 1 are reserved.
+	request, err := Packet{Header: Header{Tagged: true, Source: source, ResRequired: true, Type: MessageGetService}}.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// Broadcast until the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(discoveryInterval)
+		defer ticker.Stop()
+		for {
+			conn.WriteToUDP(request, udpAddr)
+			select {
+			case <-ctx.Done():
+				conn.SetReadDeadline(time.Now()) // Stop the receive loop.
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var devices []Device
+	found := map[[8]byte]bool{}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return devices, nil
+			}
+			return devices, err
+		}
+
+		var response Packet
+		if err := response.UnmarshalBinary(buf[:n]); err != nil || response.Source != source || response.Type != MessageStateService {
+			continue
+		}
+		var service stateService
+		if err := response.decodePayload(&service); err != nil || service.Service != 1 || found[response.Target] {
+			continue
+		}
+		found[response.Target] = true
+
+		devices = append(devices, Device{
+			Address: net.JoinHostPort(from.IP.String(), strconv.Itoa(int(service.Port))),
+			MAC:     append(net.HardwareAddr(nil), response.Target[:6]...),
+		})
+	}
+}
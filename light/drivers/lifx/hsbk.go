@@ -0,0 +1,156 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// toUint16 returns the value in the range of [0, 1] scaled to the range of an uint16.
+func toUint16(v float64) uint16 {
+	return uint16(math.Round(math.Max(0, math.Min(1, v)) * 65535))
+}
+
+// HSBK returns the HSBK color that reproduces the DCS vector of a module of the product.
+// See ColorProfile() for the DCS of the different product families.
+func (p *Product) HSBK(v emission.DCSVector) (HSBK, error) {
+	linV := v.ClampedAndLinearized(nil)
+
+	switch p.Family {
+	case FamilyColor:
+		if linV.Channels() != 3 {
+			return HSBK{}, fmt.Errorf("unexpected number of channels. Got %d, want %d", linV.Channels(), 3)
+		}
+
+		// Standard RGB to HSV conversion, but in the linear DCS.
+		r, g, b := linV[0], linV[1], linV[2]
+		max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+		delta := max - min
+
+		var hue float64
+		switch {
+		case delta <= 0:
+		case max == r:
+			hue = math.Mod((g-b)/delta+6, 6)
+		case max == g:
+			hue = (b-r)/delta + 2
+		default:
+			hue = (r-g)/delta + 4
+		}
+
+		hue = math.Round(hue / 6 * 65536)
+		if hue >= 65536 {
+			hue = 0
+		}
+
+		var saturation float64
+		if max > 0 {
+			saturation = delta / max
+		}
+
+		return HSBK{
+			Hue:        uint16(hue),
+			Saturation: toUint16(saturation),
+			Brightness: toUint16(max),
+			Kelvin:     whiteKelvin,
+		}, nil
+
+	case FamilyWhite:
+		if linV.Channels() != 2 {
+			return HSBK{}, fmt.Errorf("unexpected number of channels. Got %d, want %d", linV.Channels(), 2)
+		}
+
+		level := math.Min(linV[0]+linV[1], 1)
+		coldRatio := 0.5
+		if level > 0 {
+			coldRatio = math.Min(linV[0]/level, 1)
+		}
+
+		// Interpolate linearly in mired between warm and cold white.
+		warmMired, coldMired := 1e6/float64(p.MinKelvin), 1e6/float64(p.MaxKelvin)
+		kelvin := 1e6 / (warmMired + coldRatio*(coldMired-warmMired))
+
+		return HSBK{Brightness: toUint16(level), Kelvin: uint16(math.Round(kelvin))}, nil
+
+	case FamilyDimmable:
+		if linV.Channels() != 1 {
+			return HSBK{}, fmt.Errorf("unexpected number of channels. Got %d, want %d", linV.Channels(), 1)
+		}
+
+		return HSBK{Brightness: toUint16(linV[0]), Kelvin: p.MinKelvin}, nil
+	}
+
+	return HSBK{}, fmt.Errorf("unsupported product family %d", p.Family)
+}
+
+// DCS returns the DCS vector of a module of the product that represents the HSBK color.
+// See ColorProfile() for the DCS of the different product families.
+func (p *Product) DCS(c HSBK) (emission.DCSVector, error) {
+	level := float64(c.Brightness) / 65535
+
+	switch p.Family {
+	case FamilyColor:
+		// The white that is mixed in for unsaturated colors.
+		white := emission.LinDCSVector{1, 1, 1}
+		if c.Kelvin != whiteKelvin && c.Kelvin > 0 && c.Saturation < 0xFFFF {
+			profile, err := p.ColorProfile(1)
+			if err != nil {
+				return nil, err
+			}
+			inv, err := profile.PrimaryColors.Inverted()
+			if err != nil {
+				return nil, err
+			}
+			white = inv.Multiplied(blackBody(float64(c.Kelvin), 1)).ClampedToPositive()
+			if max := math.Max(white[0], math.Max(white[1], white[2])); max > 0 {
+				white = white.Scaled(1 / max)
+			}
+		}
+
+		// The fully saturated color of the hue angle.
+		h := float64(c.Hue) / 65536 * 6
+		f := h - math.Floor(h)
+		var pure emission.LinDCSVector
+		switch int(h) % 6 {
+		case 0:
+			pure = emission.LinDCSVector{1, f, 0}
+		case 1:
+			pure = emission.LinDCSVector{1 - f, 1, 0}
+		case 2:
+			pure = emission.LinDCSVector{0, 1, f}
+		case 3:
+			pure = emission.LinDCSVector{0, 1 - f, 1}
+		case 4:
+			pure = emission.LinDCSVector{f, 0, 1}
+		default:
+			pure = emission.LinDCSVector{1, 0, 1 - f}
+		}
+
+		saturation := float64(c.Saturation) / 65535
+		result := make(emission.DCSVector, 3)
+		for i := range result {
+			result[i] = level * ((1-saturation)*white[i] + saturation*pure[i])
+		}
+		return result, nil
+
+	case FamilyWhite:
+		kelvin := math.Max(float64(p.MinKelvin), math.Min(float64(p.MaxKelvin), float64(c.Kelvin)))
+		warmMired, coldMired := 1e6/float64(p.MinKelvin), 1e6/float64(p.MaxKelvin)
+		coldRatio := 0.5
+		if warmMired != coldMired {
+			coldRatio = (warmMired - 1e6/kelvin) / (warmMired - coldMired)
+		}
+		return emission.DCSVector{level * coldRatio, level * (1 - coldRatio)}, nil
+
+	case FamilyDimmable:
+		return emission.DCSVector{level}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported product family %d", p.Family)
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Light represents a single LIFX device.
+//
+// Multizone devices like light strips have one module per zone.
+// All other devices, including tiles and candles, have a single module.
+type Light struct {
+	connection
+
+	// The product describing the device.
+	// This must not be nil.
+	product *Product
+
+	zones         int // Number of zones, 1 for devices without multizone support.
+	colorProfiles []emission.ColorProfile
+
+	Duration time.Duration // Transition duration that is sent with SetColors(). Rounded to ms.
+}
+
+// Check implementation of light.Light.
+var _ light.Light = &Light{}
+
+// NewLight returns an object that represents a single LIFX device accessible by the given address.
+//
+// This will query the product and the number of zones, so it needs to be able to connect via the given address.
+//
+//	light, err := NewLight("192.168.1.123:56700")
+func NewLight(address string) (*Light, error) {
+	l := &Light{
+		connection: newConnection(address),
+	}
+
+	if err := l.determineProduct(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// determineProduct queries the product and the number of zones, and sets up the modules of the light.
+func (l *Light) determineProduct() error {
+	var version stateVersion
+	if err := l.get(MessageGetVersion, MessageStateVersion, &version); err != nil {
+		return fmt.Errorf("couldn't query LIFX product: %w", err)
+	}
+	if version.Vendor != vendorLIFX {
+		return fmt.Errorf("unknown vendor ID %d", version.Vendor)
+	}
+
+	product, err := LookupProduct(version.Product)
+	if err != nil {
+		return err
+	}
+	l.product = product
+
+	zones := 1
+	if product.Multizone {
+		colors, err := l.GetExtendedColorZones()
+		if err != nil {
+			return fmt.Errorf("couldn't query zones: %w", err)
+		}
+		zones = len(colors)
+	}
+
+	return l.init(zones)
+}
+
+// NewLightWithProduct returns an object that represents a single LIFX device accessible by the given address.
+// The number of zones is only used for multizone products.
+//
+// This will not query the device, but use the given product and number of zones.
+// Therefore it will not make an attempt to communicate with the light.
+func NewLightWithProduct(address string, product *Product, zones int) (*Light, error) {
+	if product == nil {
+		return nil, fmt.Errorf("no product defined")
+	}
+
+	l := &Light{
+		connection: newConnection(address),
+		product:    product,
+	}
+
+	if !product.Multizone {
+		zones = 1
+	}
+	if err := l.init(zones); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// init sets up the modules of the light.
+// The light output of the product is distributed equally over all zones.
+func (l *Light) init(zones int) error {
+	if zones < 1 {
+		return fmt.Errorf("a light needs at least one zone, got %d", zones)
+	}
+
+	colorProfile, err := l.product.ColorProfile(l.product.Lumens / float64(zones))
+	if err != nil {
+		return fmt.Errorf("couldn't create color profile of %s: %w", l.product.Name, err)
+	}
+
+	l.zones = zones
+	l.colorProfiles = make([]emission.ColorProfile, 0, zones)
+	for i := 0; i < zones; i++ {
+		l.colorProfiles = append(l.colorProfiles, colorProfile)
+	}
+
+	return nil
+}
+
+// Product returns the product descriptor of the device's abilities and limits.
+func (l *Light) Product() *Product {
+	return l.product
+}
+
+// durationMS returns the duration in ms.
+func durationMS(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	return uint32((d + time.Millisecond/2) / time.Millisecond)
+}
+
+// SetColor sets the color of the whole device, including all zones.
+// The device is not turned on by this.
+func (l *Light) SetColor(color HSBK, duration time.Duration) error {
+	return l.set(MessageSetColor, setColor{Color: color, Duration: durationMS(duration)})
+}
+
+// GetColor queries the color of the device.
+// For multizone devices, this is the color of the first zone.
+func (l *Light) GetColor() (HSBK, error) {
+	var state lightState
+	if err := l.get(MessageGetColor, MessageLightState, &state); err != nil {
+		return HSBK{}, err
+	}
+
+	return state.Color, nil
+}
+
+// SetExtendedColorZones sets the colors of the zones starting at the given index.
+// This is only supported by multizone devices.
+//
+// The change is applied to all zones at once, even if it needs several messages.
+func (l *Light) SetExtendedColorZones(index int, colors []HSBK, duration time.Duration) error {
+	if index < 0 || index+len(colors) > 0xFFFF {
+		return fmt.Errorf("zones [%d, %d) are out of range", index, index+len(colors))
+	}
+
+	for start := 0; start < len(colors); start += maxZonesPerMessage {
+		end := start + maxZonesPerMessage
+		apply := MultizoneNoApply
+		if end >= len(colors) {
+			end, apply = len(colors), MultizoneApplyNow
+		}
+
+		message := setExtendedColorZones{
+			Duration:    durationMS(duration),
+			Apply:       apply,
+			Index:       uint16(index + start),
+			ColorsCount: uint8(end - start),
+		}
+		copy(message.Colors[:], colors[start:end])
+
+		if err := l.set(MessageSetExtendedColorZones, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetExtendedColorZones queries the colors of all zones.
+// This is only supported by multizone devices.
+func (l *Light) GetExtendedColorZones() ([]HSBK, error) {
+	// The device responds with several messages, if it has more zones than fit into one.
+	var colors []HSBK
+	var received int
+	accept := func(responses []Packet) bool {
+		var state stateExtendedColorZones
+		if err := responses[len(responses)-1].decodePayload(&state); err != nil {
+			return false
+		}
+		if len(responses) == 1 {
+			colors, received = make([]HSBK, state.Count), 0
+		}
+		if int(state.Index)+int(state.ColorsCount) <= len(colors) && state.ColorsCount <= maxZonesPerMessage {
+			received += copy(colors[state.Index:], state.Colors[:state.ColorsCount])
+		}
+		return received >= len(colors)
+	}
+
+	if _, err := l.query(MessageGetExtendedColorZones, nil, MessageStateExtendedColorZones, accept); err != nil {
+		return nil, err
+	}
+
+	return colors, nil
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+//
+// The device is turned on if any module is lit, and turned off otherwise.
+func (l *Light) SetColors(emissionValues ...emission.Value) error {
+	if len(emissionValues) > l.zones {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), l.zones)
+	}
+
+	colors := make([]HSBK, 0, l.zones)
+	lit := false
+	for i, colorProfile := range l.colorProfiles {
+		vector := make(emission.DCSVector, colorProfile.Channels())
+		if i < len(emissionValues) {
+			vector = emissionValues[i].IntoDCS(colorProfile)
+		}
+
+		color, err := l.product.HSBK(vector)
+		if err != nil {
+			return fmt.Errorf("module %d: %w", i, err)
+		}
+		lit = lit || color.Brightness > 0
+		colors = append(colors, color)
+	}
+
+	if l.product.Multizone {
+		if err := l.SetExtendedColorZones(0, colors, l.Duration); err != nil {
+			return err
+		}
+	} else {
+		if err := l.SetColor(colors[0], l.Duration); err != nil {
+			return err
+		}
+	}
+
+	return l.SetPower(lit)
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (l *Light) GetColors(emissionValues ...emission.ValueReceiver) error {
+	if len(emissionValues) > l.zones {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), l.zones)
+	}
+	if len(emissionValues) == 0 {
+		return nil
+	}
+
+	var state lightState
+	if err := l.get(MessageGetColor, MessageLightState, &state); err != nil {
+		return err
+	}
+
+	colors := []HSBK{state.Color}
+	switch {
+	case state.Power == 0:
+		// Turned off devices report their last color, but emit nothing.
+		colors = make([]HSBK, len(emissionValues))
+	case l.product.Multizone:
+		var err error
+		if colors, err = l.GetExtendedColorZones(); err != nil {
+			return err
+		}
+		if len(colors) < len(emissionValues) {
+			return fmt.Errorf("device reported %d zones, want %d", len(colors), l.zones)
+		}
+	}
+
+	for i, emissionValue := range emissionValues {
+		vector, err := l.product.DCS(colors[i])
+		if err != nil {
+			return fmt.Errorf("module %d: %w", i, err)
+		}
+		if err := emissionValue.FromDCS(l.colorProfiles[i], vector); err != nil {
+			return fmt.Errorf("failed to transform value of module %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Modules returns the number of modules, which is the number of zones.
+func (l *Light) Modules() int {
+	return l.zones
+}
+
+// ColorProfiles returns the color profiles of every module in this device.
+func (l *Light) ColorProfiles() []emission.ColorProfile {
+	return append([]emission.ColorProfile(nil), l.colorProfiles...)
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		name    string
+		product uint32
+		zones   int
+	}{
+		{"Color", 27, 1},
+		{"White", 50, 1},
+		{"Dimmable", 51, 1},
+		{"Multizone", 32, 100}, // Needs more than one message per update.
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			lighttest.Run(t, func(t *testing.T) light.Light {
+				l, err := NewLight(newFakeDevice(t, test.product, test.zones).address())
+				if err != nil {
+					t.Fatalf("NewLight() failed: %v", err)
+				}
+				return l
+			})
+		})
+	}
+}
+
+func TestNewLight(t *testing.T) {
+	d := newFakeDevice(t, 38, 40)
+
+	l, err := NewLight(d.address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+	if name := l.Product().Name; name != "LIFX Beam" {
+		t.Errorf("Product() returned %q, want %q", name, "LIFX Beam")
+	}
+	if modules := l.Modules(); modules != 40 {
+		t.Errorf("Modules() returned %d, want 40", modules)
+	}
+	if lumens := l.ColorProfiles()[0].WhitePoint().Y; lumens != 1200.0/40 {
+		t.Errorf("Zones have a luminance of %v, want %v", lumens, 1200.0/40)
+	}
+
+	if _, err := NewLight(newFakeDevice(t, 9999, 1).address()); err == nil {
+		t.Errorf("NewLight() of an unknown product succeeded, want error")
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	d := newFakeDevice(t, 55, 1)
+
+	l, err := NewLight(d.address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+	if modules := l.Modules(); modules != 1 {
+		t.Errorf("Modules() returned %d, want a tile to be a single module", modules)
+	}
+	d.messages()
+
+	if err := l.SetColors(emission.DCSVector{1, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	want := []MessageType{MessageSetColor, MessageSetLightPower}
+	if got := d.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Device received %v, want %v", got, want)
+	}
+	if power, colors := d.state(); power != 0xFFFF || colors[0] != (HSBK{Saturation: 65535, Brightness: 65535, Kelvin: whiteKelvin}) {
+		t.Errorf("Device has power level %d and color %+v, want it to be on and red", power, colors[0])
+	}
+}
+
+func TestSetColors(t *testing.T) {
+	d := newFakeDevice(t, 32, 100)
+
+	l, err := NewLight(d.address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+	d.messages()
+
+	// Set the first half of the strip to red, the rest is turned off.
+	values := make([]emission.Value, 50)
+	for i := range values {
+		values[i] = emission.DCSVector{1, 0, 0}
+	}
+	if err := l.SetColors(values...); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	want := []MessageType{MessageSetExtendedColorZones, MessageSetExtendedColorZones, MessageSetLightPower}
+	if got := d.messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Device received %v, want %v", got, want)
+	}
+
+	power, colors := d.state()
+	if power != 0xFFFF {
+		t.Errorf("Device has power level %d, want on", power)
+	}
+	for i, color := range colors {
+		wantColor := HSBK{Saturation: 65535, Brightness: 65535, Kelvin: whiteKelvin}
+		if i >= 50 {
+			wantColor = HSBK{Kelvin: whiteKelvin}
+		}
+		if color != wantColor {
+			t.Errorf("Zone %d has color %+v, want %+v", i, color, wantColor)
+		}
+	}
+
+	// A turned off device emits nothing, even if its zones have a color.
+	if err := l.SetPower(false); err != nil {
+		t.Fatalf("SetPower() failed: %v", err)
+	}
+	var v emission.DCSVector
+	if err := l.GetColors(&v); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+	if !reflect.DeepEqual(v, emission.DCSVector{0, 0, 0}) {
+		t.Errorf("GetColors() returned %v for a turned off device", v)
+	}
+
+	// Turning all modules off turns the device off.
+	if err := l.SetPower(true); err != nil {
+		t.Fatalf("SetPower() failed: %v", err)
+	}
+	if err := l.SetColors(); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if power, _ := d.state(); power != 0 {
+		t.Errorf("Device has power level %d, want off", power)
+	}
+}
+
+func TestSetColor(t *testing.T) {
+	d := newFakeDevice(t, 27, 1)
+
+	product, err := LookupProduct(27)
+	if err != nil {
+		t.Fatalf("LookupProduct() failed: %v", err)
+	}
+	l, err := NewLightWithProduct(d.address(), product, 0)
+	if err != nil {
+		t.Fatalf("NewLightWithProduct() failed: %v", err)
+	}
+
+	color := HSBK{Hue: 1000, Saturation: 2000, Brightness: 3000, Kelvin: 3500}
+	if err := l.SetColor(color, time.Second); err != nil {
+		t.Fatalf("SetColor() failed: %v", err)
+	}
+	if got, err := l.GetColor(); err != nil || got != color {
+		t.Errorf("GetColor() returned %+v, %v, want %+v", got, err, color)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	d := newFakeDevice(t, 27, 1)
+
+	l, err := NewLight(d.address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	d.mutex.Lock()
+	d.drop = 2
+	d.mutex.Unlock()
+
+	if err := l.SetPower(true); err != nil {
+		t.Fatalf("SetPower() failed: %v", err)
+	}
+	if on, err := l.Power(); err != nil || !on {
+		t.Errorf("Power() returned %v, %v, want true", on, err)
+	}
+
+	d.mutex.Lock()
+	d.drop = 100
+	d.mutex.Unlock()
+
+	l.deadline = 10 * time.Millisecond
+	if err := l.SetPower(false); err == nil {
+		t.Errorf("SetPower() to an unresponsive device succeeded, want error")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	d := newFakeDevice(t, 50, 1)
+
+	l, err := NewLight(d.address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	info, err := l.DeviceInfo()
+	if err != nil {
+		t.Fatalf("DeviceInfo() failed: %v", err)
+	}
+	if want := (light.DeviceInfo{Vendor: "LIFX", Model: "LIFX Mini White to Warm", Firmware: "3.70", MAC: "d0:73:d5:01:02:03"}); info != want {
+		t.Errorf("DeviceInfo() returned %+v, want %+v", info, want)
+	}
+
+	if label, err := l.Label(); err != nil || label != "Desk" {
+		t.Errorf("Label() returned %q, %v, want %q", label, err, "Desk")
+	}
+
+	if min, max, ok := l.TemperatureRange(); !ok || min != 1500 || max != 4000 {
+		t.Errorf("TemperatureRange() returned [%v, %v] %v", min, max, ok)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	d := newFakeDevice(t, 27, 1)
+
+	defer func(interval time.Duration) { discoveryInterval = interval }(discoveryInterval)
+	discoveryInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	devices, err := Discover(ctx, d.address())
+	if err != nil {
+		t.Fatalf("Discover() failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Address != d.address() || devices[0].MAC.String() != "d0:73:d5:01:02:03" {
+		t.Errorf("Discover() returned %v", devices)
+	}
+}
+
+func TestOpenURI(t *testing.T) {
+	d := newFakeDevice(t, 32, 16)
+
+	l, err := light.Open(context.Background(), "lifx://"+d.address()+"?duration=1s&retries=2")
+	if err != nil {
+		t.Fatalf("light.Open() failed: %v", err)
+	}
+	if ll, ok := l.(*Light); !ok || ll.Modules() != 16 || ll.Duration != time.Second || ll.retries != 2 {
+		t.Errorf("light.Open() returned %#v", l)
+	}
+
+	d.messages()
+	if l, err = light.Open(context.Background(), "lifx://"+d.address()+"?product=32&zones=8"); err != nil {
+		t.Fatalf("light.Open() failed: %v", err)
+	}
+	if l.Modules() != 8 {
+		t.Errorf("Light has %d modules, want 8", l.Modules())
+	}
+	if messages := d.messages(); len(messages) != 0 {
+		t.Errorf("Device received %v, want no messages", messages)
+	}
+
+	for _, uri := range []string{"lifx://", "lifx://" + d.address() + "?product=9999", "lifx://" + d.address() + "?deadline=soon"} {
+		if _, err := light.Open(context.Background(), uri); err == nil {
+			t.Errorf("light.Open(%q) succeeded, want error", uri)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// DefaultPort is the UDP port that LIFX devices listen on.
+const DefaultPort = 56700
+
+// headerBytes is the size of the header that precedes every payload.
+const headerBytes = 36
+
+// protocolNumber is the protocol number of the LAN protocol, it's part of every header.
+const protocolNumber = 1024
+
+// MessageType identifies the payload of a packet.
+type MessageType uint16
+
+// Message types of the LAN protocol that are used by this package.
+const (
+	MessageGetService              MessageType = 2
+	MessageStateService            MessageType = 3
+	MessageGetHostFirmware         MessageType = 14
+	MessageStateHostFirmware       MessageType = 15
+	MessageGetPower                MessageType = 20
+	MessageSetPower                MessageType = 21
+	MessageStatePower              MessageType = 22
+	MessageGetLabel                MessageType = 23
+	MessageStateLabel              MessageType = 25
+	MessageGetVersion              MessageType = 32
+	MessageStateVersion            MessageType = 33
+	MessageAcknowledgement         MessageType = 45
+	MessageGetColor                MessageType = 101
+	MessageSetColor                MessageType = 102
+	MessageLightState              MessageType = 107
+	MessageGetLightPower           MessageType = 116
+	MessageSetLightPower           MessageType = 117
+	MessageStateLightPower         MessageType = 118
+	MessageSetExtendedColorZones   MessageType = 510
+	MessageGetExtendedColorZones   MessageType = 511
+	MessageStateExtendedColorZones MessageType = 512
+)
+
+// Header is the header of every LIFX packet.
+// It contains the frame, frame address and protocol header of the LAN protocol.
+type Header struct {
+	Tagged      bool    // Set if the packet is addressed to all devices, then the target has to be zero.
+	Source      uint32  // Chosen by the client, devices send it back in responses.
+	Target      [8]byte // The MAC address of the device, padded with two zero bytes.
+	AckRequired bool    // The device should send an acknowledgement.
+	ResRequired bool    // The device should send a response. Get messages always cause a response.
+	Sequence    uint8   // Chosen by the client, devices send it back in responses.
+	Type        MessageType
+}
+
+// Packet is a single LIFX message with its header.
+type Packet struct {
+	Header
+	Payload []byte
+}
+
+// MarshalBinary returns the packet in its wire format.
+func (p Packet) MarshalBinary() ([]byte, error) {
+	size := headerBytes + len(p.Payload)
+	if size > 0xFFFF {
+		return nil, fmt.Errorf("payload of %d bytes is too large", len(p.Payload))
+	}
+
+	data := make([]byte, headerBytes, size)
+
+	// Frame.
+	binary.LittleEndian.PutUint16(data[0:], uint16(size))
+	flags := uint16(protocolNumber) | 1<<12 // The addressable bit is always set.
+	if p.Tagged {
+		flags |= 1 << 13
+	}
+	binary.LittleEndian.PutUint16(data[2:], flags)
+	binary.LittleEndian.PutUint32(data[4:], p.Source)
+
+	// Frame address.
+	copy(data[8:16], p.Target[:])
+	if p.ResRequired {
+		data[22] |= 1 << 0
+	}
+	if p.AckRequired {
+		data[22] |= 1 << 1
+	}
+	data[23] = p.Sequence
+
+	// Protocol header.
+	binary.LittleEndian.PutUint16(data[32:], uint16(p.Type))
+
+	return append(data, p.Payload...), nil
+}
+
+// UnmarshalBinary decodes a packet from its wire format.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	if len(data) < headerBytes {
+		return fmt.Errorf("packet of %d bytes is smaller than the header", len(data))
+	}
+	if size := int(binary.LittleEndian.Uint16(data[0:])); size != len(data) {
+		return fmt.Errorf("packet has a size of %d bytes, but the header contains %d", len(data), size)
+	}
+	flags := binary.LittleEndian.Uint16(data[2:])
+	if protocol := flags & 0x0FFF; protocol != protocolNumber {
+		return fmt.Errorf("unsupported protocol number %d", protocol)
+	}
+
+	p.Tagged = flags&(1<<13) != 0
+	p.Source = binary.LittleEndian.Uint32(data[4:])
+	copy(p.Target[:], data[8:16])
+	p.ResRequired = data[22]&(1<<0) != 0
+	p.AckRequired = data[22]&(1<<1) != 0
+	p.Sequence = data[23]
+	p.Type = MessageType(binary.LittleEndian.Uint16(data[32:]))
+	p.Payload = append([]byte(nil), data[headerBytes:]...)
+
+	return nil
+}
+
+// encodePayload returns the wire format of a payload struct.
+// A nil payload results in an empty payload.
+func encodePayload(payload interface{}) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, payload); err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodePayload decodes the payload of the packet into the payload struct.
+func (p Packet) decodePayload(payload interface{}) error {
+	if size := binary.Size(payload); len(p.Payload) < size {
+		return fmt.Errorf("payload of message type %d has %d bytes, want %d", p.Type, len(p.Payload), size)
+	}
+
+	if err := binary.Read(bytes.NewReader(p.Payload), binary.LittleEndian, payload); err != nil {
+		return fmt.Errorf("failed to decode payload of message type %d: %w", p.Type, err)
+	}
+
+	return nil
+}
+
+// HSBK is the color format of LIFX devices.
+//
+// Hue, saturation and brightness use the whole range of an uint16.
+// The kelvin value sets the color temperature of the white that is mixed in for colors that are not fully saturated.
+type HSBK struct {
+	Hue        uint16 // Hue angle, 0 and 65535 are red.
+	Saturation uint16
+	Brightness uint16
+	Kelvin     uint16 // In the range of [1500, 9000], depending on the product.
+}
+
+// maxZonesPerMessage is the number of zones that fit into a single extended color zones message.
+const maxZonesPerMessage = 82
+
+// MultizoneApply controls when zone changes of a multizone message are applied.
+type MultizoneApply uint8
+
+const (
+	MultizoneNoApply   MultizoneApply = 0 // Buffer the change until a message with MultizoneApply is received.
+	MultizoneApplyNow  MultizoneApply = 1 // Apply this and all buffered changes.
+	MultizoneApplyOnly MultizoneApply = 2 // Ignore the colors of this message, but apply all buffered changes.
+)
+
+// Payloads of the messages.
+type (
+	stateService struct {
+		Service uint8 // 1 for UDP.
+		Port    uint32
+	}
+
+	stateHostFirmware struct {
+		Build        uint64
+		Reserved     uint64
+		VersionMinor uint16
+		VersionMajor uint16
+	}
+
+	power struct {
+		Level uint16 // Either 0 or 65535.
+	}
+
+	stateLabel struct {
+		Label [32]byte
+	}
+
+	stateVersion struct {
+		Vendor   uint32
+		Product  uint32
+		Reserved uint32
+	}
+
+	setColor struct {
+		Reserved uint8
+		Color    HSBK
+		Duration uint32 // In ms.
+	}
+
+	lightState struct {
+		Color     HSBK
+		Reserved  int16
+		Power     uint16
+		Label     [32]byte
+		Reserved2 uint64
+	}
+
+	setLightPower struct {
+		Level    uint16
+		Duration uint32 // In ms.
+	}
+
+	setExtendedColorZones struct {
+		Duration    uint32 // In ms.
+		Apply       MultizoneApply
+		Index       uint16
+		ColorsCount uint8
+		Colors      [maxZonesPerMessage]HSBK
+	}
+
+	stateExtendedColorZones struct {
+		Count       uint16 // Total number of zones of the device.
+		Index       uint16
+		ColorsCount uint8
+		Colors      [maxZonesPerMessage]HSBK
+	}
+)
+
+// label returns the label as string, without the zero padding.
+func (s stateLabel) label() string {
+	if i := bytes.IndexByte(s.Label[:], 0); i >= 0 {
+		return string(s.Label[:i])
+	}
+	return string(s.Label[:])
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func TestPacketMarshal(t *testing.T) {
+	// Example GetService packet of the LAN protocol documentation, with a source of 2.
+	want := []byte{
+		0x24, 0x00, 0x00, 0x34, 0x02, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00,
+	}
+
+	data, err := Packet{Header: Header{Tagged: true, Source: 2, Type: MessageGetService}}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() failed: %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("MarshalBinary() returned % x, want % x", data, want)
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	payload, err := encodePayload(setColor{Color: HSBK{Hue: 21845, Saturation: 65535, Brightness: 32768, Kelvin: 3500}, Duration: 1024})
+	if err != nil {
+		t.Fatalf("encodePayload() failed: %v", err)
+	}
+	if len(payload) != 13 {
+		t.Errorf("SetColor payload has %d bytes, want 13", len(payload))
+	}
+
+	packet := Packet{
+		Header: Header{
+			Source:      0x12345678,
+			Target:      [8]byte{0xd0, 0x73, 0xd5, 0x01, 0x02, 0x03},
+			AckRequired: true,
+			Sequence:    42,
+			Type:        MessageSetColor,
+		},
+		Payload: payload,
+	}
+
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() failed: %v", err)
+	}
+
+	var decoded Packet
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, packet) {
+		t.Errorf("Got %+v, want %+v", decoded, packet)
+	}
+
+	var message setColor
+	if err := decoded.decodePayload(&message); err != nil {
+		t.Fatalf("decodePayload() failed: %v", err)
+	}
+	if message.Color.Hue != 21845 || message.Color.Kelvin != 3500 || message.Duration != 1024 {
+		t.Errorf("Got payload %+v", message)
+	}
+
+	// Payloads that are too short can't be decoded.
+	if err := decoded.decodePayload(&lightState{}); err == nil {
+		t.Errorf("decodePayload() of a too short payload succeeded")
+	}
+}
+
+func TestPacketUnmarshalInvalid(t *testing.T) {
+	valid, _ := Packet{Header: Header{Type: MessageGetColor}}.MarshalBinary()
+
+	wrongSize := append([]byte(nil), valid...)
+	wrongSize[0] = 40
+
+	wrongProtocol := append([]byte(nil), valid...)
+	wrongProtocol[2] = 0x01
+
+	for _, data := range [][]byte{valid[:20], wrongSize, wrongProtocol} {
+		var p Packet
+		if err := p.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(% x) succeeded, want error", data)
+		}
+	}
+}
+
+func TestHSBK(t *testing.T) {
+	color, _ := LookupProduct(27)
+	white, _ := LookupProduct(50)
+	dimmable, _ := LookupProduct(51)
+
+	tests := []struct {
+		product *Product
+		vector  emission.DCSVector
+		want    HSBK
+	}{
+		{color, emission.DCSVector{1, 0, 0}, HSBK{Hue: 0, Saturation: 65535, Brightness: 65535, Kelvin: 6500}},
+		{color, emission.DCSVector{0, 0.5, 0}, HSBK{Hue: 21845, Saturation: 65535, Brightness: 32768, Kelvin: 6500}},
+		{color, emission.DCSVector{0, 0, 1}, HSBK{Hue: 43691, Saturation: 65535, Brightness: 65535, Kelvin: 6500}},
+		{color, emission.DCSVector{1, 1, 1}, HSBK{Hue: 0, Saturation: 0, Brightness: 65535, Kelvin: 6500}},
+		{color, emission.DCSVector{0.5, 0.25, 0.25}, HSBK{Hue: 0, Saturation: 32768, Brightness: 32768, Kelvin: 6500}},
+		{color, emission.DCSVector{0, 0, 0}, HSBK{Kelvin: 6500}},
+		{white, emission.DCSVector{1, 0}, HSBK{Brightness: 65535, Kelvin: 4000}},
+		{white, emission.DCSVector{0, 0.5}, HSBK{Brightness: 32768, Kelvin: 1500}},
+		{dimmable, emission.DCSVector{0.5}, HSBK{Brightness: 32768, Kelvin: 2700}},
+	}
+
+	for _, test := range tests {
+		got, err := test.product.HSBK(test.vector)
+		if err != nil {
+			t.Fatalf("HSBK(%v) failed: %v", test.vector, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: HSBK(%v) returned %+v, want %+v", test.product.Name, test.vector, got, test.want)
+		}
+
+		vector, err := test.product.DCS(got)
+		if err != nil {
+			t.Fatalf("DCS(%+v) failed: %v", got, err)
+		}
+		for i := range vector {
+			if math.Abs(vector[i]-test.vector[i]) > 1e-4 {
+				t.Errorf("%s: DCS(%+v) returned %v, want %v", test.product.Name, got, vector, test.vector)
+				break
+			}
+		}
+	}
+
+	// Whites of other color temperatures, e.g. set by the LIFX app, are converted via the color profile.
+	vector, err := color.DCS(HSBK{Brightness: 65535, Kelvin: 2700})
+	if err != nil {
+		t.Fatalf("DCS() failed: %v", err)
+	}
+	profile, err := color.ColorProfile(1000)
+	if err != nil {
+		t.Fatalf("ColorProfile() failed: %v", err)
+	}
+	xyz, err := profile.DCSToXYZ(vector)
+	if err != nil {
+		t.Fatalf("DCSToXYZ() failed: %v", err)
+	}
+	got, want := xyz.CIE1931xyYAbs(), emission.BlackBodyFixed{Temperature: 2700, Luminance: 1}.CIE1931xyYAbs()
+	if math.Abs(got.X-want.X) > 1e-3 || math.Abs(got.Y-want.Y) > 1e-3 {
+		t.Errorf("Got chromaticity %v for 2700 K, want %v", got, want)
+	}
+}
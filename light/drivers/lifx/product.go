@@ -0,0 +1,152 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// vendorLIFX is the vendor ID of LIFX products.
+const vendorLIFX = 1
+
+// Family describes how the color of a product is controlled.
+type Family int
+
+const (
+	FamilyColor    Family = iota // Full color, with tunable white.
+	FamilyWhite                  // Tunable white only.
+	FamilyDimmable               // A single white, only the brightness can be changed.
+)
+
+// Product describes the abilities and limits of a LIFX product.
+type Product struct {
+	ID        uint32
+	Name      string
+	Family    Family
+	MinKelvin uint16
+	MaxKelvin uint16
+	Multizone bool    // Light strips that support extended multizone messages. Every zone is a module.
+	Matrix    bool    // Tiles and candles. These are controlled as a single module.
+	Lumens    float64 // Approximate light output of the whole device at full brightness.
+}
+
+// products contains all known LIFX products.
+var products = []Product{
+	{ID: 1, Name: "LIFX Original 1000", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1000},
+	{ID: 3, Name: "LIFX Color 650", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 650},
+	{ID: 10, Name: "LIFX White 800 (Low Voltage)", Family: FamilyWhite, MinKelvin: 2700, MaxKelvin: 6500, Lumens: 800},
+	{ID: 11, Name: "LIFX White 800 (High Voltage)", Family: FamilyWhite, MinKelvin: 2700, MaxKelvin: 6500, Lumens: 800},
+	{ID: 22, Name: "LIFX Color 1000", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1000},
+	{ID: 27, Name: "LIFX A19", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1100},
+	{ID: 28, Name: "LIFX BR30", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1000},
+	{ID: 32, Name: "LIFX Z", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Multizone: true, Lumens: 700},
+	{ID: 38, Name: "LIFX Beam", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Multizone: true, Lumens: 1200},
+	{ID: 43, Name: "LIFX A19", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1100},
+	{ID: 44, Name: "LIFX BR30", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Lumens: 1000},
+	{ID: 49, Name: "LIFX Mini Color", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 800},
+	{ID: 50, Name: "LIFX Mini White to Warm", Family: FamilyWhite, MinKelvin: 1500, MaxKelvin: 4000, Lumens: 800},
+	{ID: 51, Name: "LIFX Mini White", Family: FamilyDimmable, MinKelvin: 2700, MaxKelvin: 2700, Lumens: 800},
+	{ID: 55, Name: "LIFX Tile", Family: FamilyColor, MinKelvin: 2500, MaxKelvin: 9000, Matrix: true, Lumens: 400},
+	{ID: 57, Name: "LIFX Candle", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Matrix: true, Lumens: 400},
+	{ID: 59, Name: "LIFX Mini Color", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 800},
+	{ID: 60, Name: "LIFX Mini White to Warm", Family: FamilyWhite, MinKelvin: 1500, MaxKelvin: 4000, Lumens: 800},
+	{ID: 61, Name: "LIFX Mini White", Family: FamilyDimmable, MinKelvin: 2700, MaxKelvin: 2700, Lumens: 800},
+	{ID: 91, Name: "LIFX Color", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 1100},
+	{ID: 92, Name: "LIFX Color", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 1100},
+	{ID: 97, Name: "LIFX A19", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 1100},
+	{ID: 98, Name: "LIFX BR30", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Lumens: 1000},
+	{ID: 117, Name: "LIFX Z", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Multizone: true, Lumens: 700},
+	{ID: 118, Name: "LIFX Z", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Multizone: true, Lumens: 700},
+	{ID: 119, Name: "LIFX Beam", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Multizone: true, Lumens: 1200},
+	{ID: 120, Name: "LIFX Beam", Family: FamilyColor, MinKelvin: 1500, MaxKelvin: 9000, Multizone: true, Lumens: 1200},
+}
+
+// LookupProduct returns the product with the given product ID.
+func LookupProduct(id uint32) (*Product, error) {
+	for i := range products {
+		if products[i].ID == id {
+			return &products[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown product ID %d", id)
+}
+
+// blackBody returns the color of a black body radiator with the given luminance.
+// The temperature is limited to the range of the approximation, which doesn't cover the warmest whites of some products.
+func blackBody(kelvin, luminance float64) emission.CIE1931XYZAbs {
+	kelvin = math.Max(1667, math.Min(25000, kelvin))
+	return emission.BlackBodyFixed{Temperature: kelvin, Luminance: luminance}.CIE1931XYZAbs()
+}
+
+// whiteKelvin is the color temperature that is sent for colors of color products.
+// The primaries of the color profile are balanced to a black body of this temperature, so fully desaturated colors are white.
+const whiteKelvin = 6500
+
+// ColorProfile returns the color profile of a single module of the product, with the given luminance in lumen.
+//
+// The DCS of the different families is:
+//
+//   - Color products: Linear red, green and blue, balanced to a white of 6500 K.
+//   - White products: Cold white and warm white at the ends of the color temperature range.
+//   - Dimmable products: A single white channel.
+//
+// The primaries of LIFX color products are not published, they are approximated by the sRGB primaries.
+func (p *Product) ColorProfile(lumens float64) (*emission.ColorProfileGeneral, error) {
+	var profile *emission.ColorProfileGeneral
+
+	switch p.Family {
+	case FamilyColor:
+		white := blackBody(whiteKelvin, lumens)
+		primaries := emission.TransformationLinDCSToXYZ{
+			emission.CIE1931xyYAbs{X: 0.64, Y: 0.33, LuminanceY: 1}.CIE1931XYZAbs(),
+			emission.CIE1931xyYAbs{X: 0.30, Y: 0.60, LuminanceY: 1}.CIE1931XYZAbs(),
+			emission.CIE1931xyYAbs{X: 0.15, Y: 0.06, LuminanceY: 1}.CIE1931XYZAbs(),
+		}
+		inv, err := primaries.Inverted()
+		if err != nil {
+			return nil, err
+		}
+		scales := inv.Multiplied(white)
+
+		balanced := make(emission.TransformationLinDCSToXYZ, 0, len(primaries))
+		for i, primary := range primaries {
+			balanced = append(balanced, primary.Scaled(scales[i]))
+		}
+		profile = &emission.ColorProfileGeneral{
+			WhitePointColor: white,
+			PrimaryColors:   balanced,
+		}
+
+	case FamilyWhite:
+		cold := blackBody(float64(p.MaxKelvin), lumens)
+		warm := blackBody(float64(p.MinKelvin), lumens)
+		profile = &emission.ColorProfileGeneral{
+			WhitePointColor: cold.Sum(warm).Scaled(0.5),
+			WhiteColors:     emission.TransformationLinDCSToXYZ{cold, warm},
+			OutputLimiter:   emission.OutputLimiterSum{Limit: 1}, // The brightness doesn't depend on the color temperature.
+		}
+
+	case FamilyDimmable:
+		white := blackBody(float64(p.MinKelvin), lumens)
+		profile = &emission.ColorProfileGeneral{
+			WhitePointColor: white,
+			WhiteColors:     emission.TransformationLinDCSToXYZ{white},
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported product family %d", p.Family)
+	}
+
+	if err := profile.Init(); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package lifx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+func init() {
+	light.Register("lifx", openURI)
+}
+
+// openURI creates a light object from an URI in the form of
+//
+//	lifx://host[:port][?retries=5&deadline=200ms&duration=0s&product=27&zones=16]
+//
+// If no port is given, the default port of LIFX devices is used.
+// If the product ID is given, the device will not be queried for its product and number of zones.
+func openURI(ctx context.Context, uri *url.URL) (light.Light, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if uri.Hostname() == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a host", uri)
+	}
+	address := uri.Host
+	if uri.Port() == "" {
+		address = net.JoinHostPort(uri.Hostname(), strconv.Itoa(DefaultPort))
+	}
+
+	query := uri.Query()
+
+	l := &Light{
+		connection: newConnection(address),
+	}
+
+	// Apply communication parameters first, as they are already needed to determine the product.
+	if value := query.Get("retries"); value != "" {
+		retries, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse retries %q: %w", value, err)
+		}
+		l.retries = uint(retries)
+	}
+
+	if value := query.Get("deadline"); value != "" {
+		deadline, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deadline %q: %w", value, err)
+		}
+		l.deadline = deadline
+	}
+
+	if value := query.Get("duration"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duration %q: %w", value, err)
+		}
+		l.Duration = duration
+	}
+
+	if value := query.Get("product"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse product %q: %w", value, err)
+		}
+		if l.product, err = LookupProduct(uint32(id)); err != nil {
+			return nil, err
+		}
+
+		zones := 1
+		if value := query.Get("zones"); value != "" && l.product.Multizone {
+			if zones, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("failed to parse zones %q: %w", value, err)
+			}
+		}
+		if err := l.init(zones); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := l.determineProduct(); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package virtual
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Options contains parameters that change how a virtual light behaves.
+type Options struct {
+	// Latency that is added to every SetColors and GetColors call.
+	Latency time.Duration
+
+	// Number of bits that every DCS channel is quantized to, like a real device would do.
+	// E.g. 8 for devices that use a single byte per channel.
+	// Set to 0 to disable quantization.
+	QuantizationBits uint
+
+	// If true, every SetColors call is recorded in a history with a timestamp.
+	History bool
+}
+
+// HistoryEntry represents a single SetColors call on a virtual light.
+type HistoryEntry struct {
+	Time    time.Time            // The time the values were applied.
+	Vectors []emission.DCSVector // The applied values of all modules in DCS.
+}
+
+// Light is an in-memory light device that implements the light.Light interface.
+//
+// It stores the DCS vectors that a real device would have received.
+// This can be used to test effects and automations without hardware.
+type Light struct {
+	options       Options
+	colorProfiles []emission.ColorProfile
+
+	mutex   sync.Mutex
+	vectors []emission.DCSVector // Currently applied DCS vectors.
+	history []HistoryEntry
+}
+
+// Check implementation of light.Light.
+var _ light.Light = &Light{}
+
+// NewLight returns a virtual light with one module for every given color profile.
+// All modules are turned off initially.
+//
+//	product, _ := wiz.LookupProduct("ESP03_SHRGB1W_01")
+//	l, err := virtual.NewLight(virtual.Options{QuantizationBits: 8}, product.ColorProfile(), product.ColorProfile())
+func NewLight(options Options, colorProfiles ...emission.ColorProfile) (*Light, error) {
+	if len(colorProfiles) < 1 {
+		return nil, fmt.Errorf("a light needs at least one module")
+	}
+	for i, colorProfile := range colorProfiles {
+		if colorProfile == nil {
+			return nil, fmt.Errorf("color profile of module %d is nil", i)
+		}
+	}
+
+	l := &Light{
+		options:       options,
+		colorProfiles: append([]emission.ColorProfile(nil), colorProfiles...),
+		vectors:       make([]emission.DCSVector, 0, len(colorProfiles)),
+	}
+
+	for _, colorProfile := range colorProfiles {
+		l.vectors = append(l.vectors, make(emission.DCSVector, colorProfile.Channels()))
+	}
+
+	return l, nil
+}
+
+// quantized returns v clamped and quantized according to the options.
+func (l *Light) quantized(v emission.DCSVector) emission.DCSVector {
+	result := v.ClampedIndividually()
+
+	if l.options.QuantizationBits > 0 {
+		steps := math.Exp2(float64(l.options.QuantizationBits)) - 1
+		for i, channel := range result {
+			result[i] = math.Round(channel*steps) / steps
+		}
+	}
+
+	return result
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+func (l *Light) SetColors(emissionValues ...emission.Value) error {
+	if len(emissionValues) > len(l.colorProfiles) {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), len(l.colorProfiles))
+	}
+
+	vectors := make([]emission.DCSVector, 0, len(l.colorProfiles))
+	for i, colorProfile := range l.colorProfiles {
+		if i < len(emissionValues) {
+			vector := emissionValues[i].IntoDCS(colorProfile)
+			if vector.Channels() != colorProfile.Channels() {
+				return fmt.Errorf("unexpected number of channels for module %d. Got %d, want %d", i, vector.Channels(), colorProfile.Channels())
+			}
+			vectors = append(vectors, l.quantized(vector))
+		} else {
+			vectors = append(vectors, make(emission.DCSVector, colorProfile.Channels()))
+		}
+	}
+
+	time.Sleep(l.options.Latency)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.vectors = vectors
+	if l.options.History {
+		l.history = append(l.history, HistoryEntry{Time: time.Now(), Vectors: copyVectors(vectors)})
+	}
+
+	return nil
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (l *Light) GetColors(emissionValues ...emission.ValueReceiver) error {
+	if len(emissionValues) > len(l.colorProfiles) {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), len(l.colorProfiles))
+	}
+
+	time.Sleep(l.options.Latency)
+
+	vectors := l.Vectors()
+	for i, emissionValue := range emissionValues {
+		if err := emissionValue.FromDCS(l.colorProfiles[i], vectors[i]); err != nil {
+			return fmt.Errorf("failed to transform value of module %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Modules returns the number of modules.
+func (l *Light) Modules() int {
+	return len(l.colorProfiles)
+}
+
+// ColorProfiles returns the color profiles of every module in this device.
+func (l *Light) ColorProfiles() []emission.ColorProfile {
+	return append([]emission.ColorProfile(nil), l.colorProfiles...)
+}
+
+// Vectors returns a copy of the currently applied DCS vectors of all modules.
+func (l *Light) Vectors() []emission.DCSVector {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return copyVectors(l.vectors)
+}
+
+// History returns a copy of all recorded SetColors calls, oldest first.
+// This is empty if the history is not enabled in the options.
+func (l *Light) History() []HistoryEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := make([]HistoryEntry, 0, len(l.history))
+	for _, entry := range l.history {
+		result = append(result, HistoryEntry{Time: entry.Time, Vectors: copyVectors(entry.Vectors)})
+	}
+
+	return result
+}
+
+// ClearHistory removes all recorded entries from the history.
+func (l *Light) ClearHistory() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.history = nil
+}
+
+// copyVectors returns a deep copy of the given list of vectors.
+func copyVectors(vectors []emission.DCSVector) []emission.DCSVector {
+	result := make([]emission.DCSVector, 0, len(vectors))
+	for _, vector := range vectors {
+		result = append(result, vector.Copy())
+	}
+	return result
+}
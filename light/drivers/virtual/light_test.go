@@ -0,0 +1,109 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package virtual
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/wiz"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+func TestLightConformance(t *testing.T) {
+	product, err := wiz.LookupProduct("ESP03_SHRGB1W_01")
+	if err != nil {
+		t.Fatalf("wiz.LookupProduct() failed: %v", err)
+	}
+
+	lighttest.Run(t, func(t *testing.T) light.Light {
+		l, err := NewLight(Options{QuantizationBits: 8}, product.ColorProfile(), DefaultColorProfile, product.ColorProfile())
+		if err != nil {
+			t.Fatalf("NewLight() failed: %v", err)
+		}
+		return l
+	})
+}
+
+func TestQuantization(t *testing.T) {
+	l, err := NewLight(Options{QuantizationBits: 8}, DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	if err := l.SetColors(emission.DCSVector{0.5, 1.2, -0.1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	got := l.Vectors()[0]
+	want := emission.DCSVector{128.0 / 255, 1, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Channel %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	l, err := NewLight(Options{History: true}, DefaultColorProfile, DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	if err := l.SetColors(emission.DCSVector{1, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := l.SetColors(emission.DCSVector{0, 1, 0}, emission.DCSVector{0, 0, 1}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	history := l.History()
+	if len(history) != 2 {
+		t.Fatalf("History() returned %d entries, want %d", len(history), 2)
+	}
+	if history[1].Time.Before(history[0].Time) {
+		t.Errorf("History entries are not in chronological order")
+	}
+	if history[0].Vectors[1].ComponentSum() != 0 {
+		t.Errorf("Unset module of the first entry is %v, want it to be turned off", history[0].Vectors[1])
+	}
+	if history[1].Vectors[1][2] != 1 {
+		t.Errorf("Second module of the second entry is %v, want %v", history[1].Vectors[1], emission.DCSVector{0, 0, 1})
+	}
+
+	l.ClearHistory()
+	if len(l.History()) != 0 {
+		t.Errorf("History() is not empty after ClearHistory()")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	l, err := light.Open(context.Background(), "virtual://test?modules=3&bits=8")
+	if err != nil {
+		t.Fatalf("light.Open() failed: %v", err)
+	}
+
+	if modules := l.Modules(); modules != 3 {
+		t.Errorf("Modules() returned %d, want %d", modules, 3)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"virtual://test?modules=0",
+		"virtual://test?modules=-1",
+		"virtual://test?modules=abc",
+		"virtual://test?bits=-1",
+		"virtual://test?bits=33",
+		"virtual://test?bits=abc",
+	} {
+		if _, err := light.Open(context.Background(), uri); err == nil {
+			t.Errorf("light.Open(%q) succeeded, want error", uri)
+		}
+	}
+}
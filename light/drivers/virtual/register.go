@@ -0,0 +1,89 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package virtual
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// DefaultColorProfile describes a module with sRGB primaries, a D65 white point with a luminance of 1000 lumen and the sRGB transfer function.
+var DefaultColorProfile emission.ColorProfile = (&emission.ColorProfileGeneral{
+	WhitePointColor: emission.StandardIlluminantD65.Absolute(1000),
+	PrimaryColors: emission.TransformationLinDCSToXYZ{
+		emission.CIE1931XYZRel{X: 0.4124, Y: 0.2126, Z: 0.0193}.Absolute(1000),
+		emission.CIE1931XYZRel{X: 0.3576, Y: 0.7152, Z: 0.1192}.Absolute(1000),
+		emission.CIE1931XYZRel{X: 0.1805, Y: 0.0722, Z: 0.9505}.Absolute(1000),
+	},
+	TransferFunc: emission.TransferFunctionStandardRGB,
+}).MustInit()
+
+// maxQuantizationBits is the largest number of quantization bits that can be set via URI.
+// Values above this are not any different from no quantization, as they are below the precision of float64 DCS channels.
+const maxQuantizationBits = 32
+
+func init() {
+	light.Register("virtual", openURI)
+}
+
+// openURI creates a virtual light from an URI in the form of
+//
+//	virtual://[name][?modules=1&latency=0s&bits=0&history=false]
+//
+// All modules use DefaultColorProfile.
+// There must be at least one module, and bits must not exceed 32.
+// Every call creates a new virtual light, even if the URI is the same.
+func openURI(ctx context.Context, uri *url.URL) (light.Light, error) {
+	query := uri.Query()
+
+	modules := 1
+	if value := query.Get("modules"); value != "" {
+		var err error
+		if modules, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("failed to parse modules %q: %w", value, err)
+		}
+		if modules < 1 {
+			return nil, fmt.Errorf("a light needs at least one module, got %d modules", modules)
+		}
+	}
+
+	var options Options
+	if value := query.Get("latency"); value != "" {
+		var err error
+		if options.Latency, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("failed to parse latency %q: %w", value, err)
+		}
+	}
+	if value := query.Get("bits"); value != "" {
+		bits, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bits %q: %w", value, err)
+		}
+		if bits > maxQuantizationBits {
+			return nil, fmt.Errorf("bits %d exceeds the maximum of %d", bits, maxQuantizationBits)
+		}
+		options.QuantizationBits = uint(bits)
+	}
+	if value := query.Get("history"); value != "" {
+		var err error
+		if options.History, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("failed to parse history %q: %w", value, err)
+		}
+	}
+
+	colorProfiles := make([]emission.ColorProfile, 0, modules)
+	for i := 0; i < modules; i++ {
+		colorProfiles = append(colorProfiles, DefaultColorProfile)
+	}
+
+	return NewLight(options, colorProfiles...)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+// Check implementation of optional light interfaces.
+var (
+	_ light.Switcher          = &Light{}
+	_ light.Identifier        = &Light{}
+	_ light.InfoProvider      = &Light{}
+	_ light.SceneProvider     = &Light{}
+	_ light.NativeStater      = &Light{}
+	_ light.TemperatureRanger = &Light{}
+	_ light.Uptimer           = &Light{}
+)
+
+// SetPower turns the light on or off.
+// Turning the light on restores its last state.
+// This implements the light.Switcher interface.
+func (l *Light) SetPower(on bool) error {
+	return l.SetPilot(NewPilot(on))
+}
+
+// Power queries the light for its on/off state.
+// This implements the light.Switcher interface.
+func (l *Light) Power() (bool, error) {
+	pilot, err := l.GetPilot()
+	if err != nil {
+		return false, err
+	}
+
+	return pilot.State, nil
+}
+
+// Identify lets the light do a short pulse.
+// This implements the light.Identifier interface.
+func (l *Light) Identify() error {
+	return l.Pulse(50, 500*time.Millisecond)
+}
+
+// DeviceInfo queries the light for general information.
+// This implements the light.InfoProvider interface.
+func (l *Light) DeviceInfo() (light.DeviceInfo, error) {
+	systemConfig, err := l.GetSystemConfig()
+	if err != nil {
+		return light.DeviceInfo{}, err
+	}
+
+	return light.DeviceInfo{
+		Vendor:   "WiZ",
+		Model:    systemConfig.ModuleName,
+		Firmware: systemConfig.FWVersion,
+		MAC:      systemConfig.Mac,
+	}, nil
+}
+
+// Scenes returns the names of all scenes that the product supports.
+// This implements the light.SceneProvider interface.
+func (l *Light) Scenes() []string {
+	scenes := l.product.ScenesCapability()
+
+	names := make([]string, 0, len(scenes))
+	for _, scene := range scenes {
+		names = append(names, scene.Name())
+	}
+
+	return names
+}
+
+// SetScene starts the scene with the given name at full brightness and speed.
+// Use SetPilot() with NewPilotWithScene() for more control.
+// This implements the light.SceneProvider interface.
+func (l *Light) SetScene(name string) error {
+	for _, scene := range l.product.ScenesCapability() {
+		if scene.Name() == name {
+			return l.SetPilot(NewPilotWithScene(scene, 100, 100))
+		}
+	}
+
+	return fmt.Errorf("scene %q is not supported by %s", name, l.product.ModuleName())
+}
+
+// TemperatureRange returns the interval of color temperatures that the product supports.
+// This implements the light.TemperatureRanger interface.
+func (l *Light) TemperatureRange() (min, max float64, ok bool) {
+	minTemp, maxTemp, ok := l.product.TempCapability()
+	return float64(minTemp), float64(maxTemp), ok
+}
+
+// Uptime queries the light for the time since it was started.
+// This uses the ping value of the system configuration, which counts the seconds since the device started.
+// This implements the light.Uptimer interface.
+func (l *Light) Uptime() (time.Duration, error) {
+	systemConfig, err := l.GetSystemConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(systemConfig.Ping) * time.Second, nil
+}
+
+// NativeState returns the current pilot of the light as JSON.
+// Other than GetColors, this also captures scenes and color temperatures.
+// This implements the light.NativeStater interface.
+func (l *Light) NativeState() (json.RawMessage, error) {
+	pilot, err := l.GetPilot()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(pilot.restorable())
+}
+
+// RestoreNativeState sends a pilot that was previously returned by NativeState to the light.
+// This implements the light.NativeStater interface.
+func (l *Light) RestoreNativeState(state json.RawMessage) error {
+	var pilot Pilot
+	if err := json.Unmarshal(state, &pilot); err != nil {
+		return fmt.Errorf("failed to unmarshal pilot: %w", err)
+	}
+
+	return l.SetPilot(pilot.restorable())
+}
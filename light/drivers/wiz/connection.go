@@ -0,0 +1,148 @@
+// Copyright (c) 2021-2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+)
+
+// connection contains everything that is needed to communicate with a WiZ device.
+type connection struct {
+	address string
+
+	deadline  time.Duration // Default timeout duration for any communication operation (sending and receiving).
+	retries   uint          // Number of retries when the deadline got exceeded.
+	connMutex sync.Mutex    // Mutex preventing simultaneous connections to this device.
+
+	Logger Logger // Logger that receives structured entries of the network communication. Can be nil.
+}
+
+// newConnection returns a connection to the device with the given address and default parameters.
+func newConnection(address string) connection {
+	return connection{
+		address:  address,
+		deadline: 100 * time.Millisecond,
+		retries:  10,
+	}
+}
+
+// call sends a query with the given method and parameters, and writes the result into result.
+// result can be nil, if the result is not needed.
+func (c *connection) call(m method, params, result interface{}) error {
+	return c.callContext(context.Background(), m, params, result)
+}
+
+// callContext is like call, but stops retrying once ctx is done.
+func (c *connection) callContext(ctx context.Context, m method, params, result interface{}) error {
+	q := query{
+		Method: m,
+		Env:    "pro",
+		Params: params,
+	}
+
+	var r response
+	r.Result = result
+	if err := c.jsonQuery(ctx, q, &r); err != nil {
+		return err
+	}
+
+	// Check if the response contains any error code.
+	return r.Check(q.Method)
+}
+
+// jsonQuery sends the given query structure as JSON, and unmarshals the JSON response into the given structure r.
+func (c *connection) jsonQuery(ctx context.Context, q query, r interface{}) error {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+
+	responseData, err := c.rawQuery(ctx, q.Method, data)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(responseData, &r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// rawQuery sends the given data to the light bulb via UDP.
+// The response given by the bulb will be returned as byte slice.
+//
+// This will not send any further attempts once ctx is done.
+// The method m is only used for logging.
+func (c *connection) rawQuery(ctx context.Context, m method, data []byte) ([]byte, error) {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	conn, err := net.Dial("udp", c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Function that sends the given data, and tries to receive the response packet.
+	sendFunc := func() ([]byte, error) {
+		deadline := time.Now().Add(c.deadline)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetDeadline(deadline)
+		if _, err := conn.Write(data); err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, 1024)
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	}
+
+	// Try to communicate, at most c.retries + 1 times.
+	for i := uint(0); i <= c.retries; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		// ctx may not be marked as done yet, even if its deadline has passed.
+		if ctxDeadline, ok := ctx.Deadline(); ok && !time.Now().Before(ctxDeadline) {
+			return nil, context.DeadlineExceeded
+		}
+
+		start := time.Now()
+
+		var res []byte
+		res, err = sendFunc()
+
+		if c.Logger != nil {
+			c.Logger.LogQuery(LogEntry{
+				Method:   string(m),
+				Address:  c.address,
+				Attempt:  i + 1,
+				Latency:  time.Since(start),
+				Request:  data,
+				Response: res,
+				Err:      err,
+			})
+		}
+
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	return nil, err
+}
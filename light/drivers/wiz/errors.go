@@ -1,4 +1,4 @@
-// Copyright (c) 2021 David Vogel
+// Copyright (c) 2021-2022 David Vogel
 //
 // This software is released under the MIT License.
 // https://opensource.org/licenses/MIT
@@ -33,3 +33,33 @@ func (e *ErrQueryFailed) QueryErrorCode() QueryErrorCode {
 func (e *ErrQueryFailed) Message() string {
 	return e.message
 }
+
+// ErrNotALight is returned if a light object is created for a device that is not a light, like a WiZ smart plug.
+// Use NewPlug() for these devices.
+type ErrNotALight struct {
+	moduleName string
+}
+
+func (e *ErrNotALight) Error() string {
+	return fmt.Sprintf("device with module name %q is not a light", e.moduleName)
+}
+
+// ModuleName returns the module name of the device.
+func (e *ErrNotALight) ModuleName() string {
+	return e.moduleName
+}
+
+// ErrNotAPlug is returned if a plug object is created for a device that is not a plug or socket.
+// Use NewLight() for light devices.
+type ErrNotAPlug struct {
+	moduleName string
+}
+
+func (e *ErrNotAPlug) Error() string {
+	return fmt.Sprintf("device with module name %q is not a plug", e.moduleName)
+}
+
+// ModuleName returns the module name of the device.
+func (e *ErrNotAPlug) ModuleName() string {
+	return e.moduleName
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import "fmt"
+
+// FanMode describes the operation mode of a fan.
+type FanMode uint
+
+const (
+	FanModeNormal FanMode = 1 // The fan rotates at a constant speed.
+	FanModeBreeze FanMode = 2 // The fan speed varies to simulate a natural breeze.
+)
+
+func (m FanMode) String() string {
+	switch m {
+	case FanModeNormal:
+		return "Normal"
+	case FanModeBreeze:
+		return "Breeze"
+	}
+
+	return fmt.Sprintf("Unknown (%d)", uint(m))
+}
+
+// FanDirection describes the rotation direction of a fan.
+type FanDirection uint
+
+const (
+	FanDirectionForward FanDirection = 0 // Downdraft, used in summer.
+	FanDirectionReverse FanDirection = 1 // Updraft, used in winter.
+)
+
+func (d FanDirection) String() string {
+	switch d {
+	case FanDirectionForward:
+		return "Forward"
+	case FanDirectionReverse:
+		return "Reverse"
+	}
+
+	return fmt.Sprintf("Unknown (%d)", uint(d))
+}
+
+// Fan contains the state of a fan.
+type Fan struct {
+	State     bool         // On off state.
+	Speed     uint         // Speed in the range of [1, max], see Light.FanCapability().
+	Direction FanDirection // Rotation direction.
+	Mode      FanMode      // Operation mode.
+}
+
+// FanCapability returns the maximum fan speed of the device.
+// If the returned bool is false, the device doesn't contain a fan.
+//
+// The result is queried from the device's model configuration once, and cached afterwards.
+func (l *Light) FanCapability() (maxSpeed uint, has bool, err error) {
+	l.paramMutex.Lock()
+	defer l.paramMutex.Unlock()
+
+	if l.fanSpeedMax == nil {
+		modelConfig, err := l.GetModelConfig()
+		if err != nil {
+			return 0, false, fmt.Errorf("couldn't query model configuration: %w", err)
+		}
+
+		speedMax := uint(0)
+		if modelConfig.FanSpeed > 0 {
+			speedMax = uint(modelConfig.FanSpeed)
+		}
+		l.fanSpeedMax = &speedMax
+	}
+
+	return *l.fanSpeedMax, *l.fanSpeedMax > 0, nil
+}
+
+// SetFan sets the state of the fan.
+// This will not change the state of the light.
+//
+// The speed is only used when the fan is turned on, otherwise the device keeps its previous speed.
+//
+// This returns an error if the device doesn't contain a fan, or if the fan is turned on with a speed outside of the supported range.
+func (l *Light) SetFan(state bool, speed uint, direction FanDirection, mode FanMode) error {
+	maxSpeed, has, err := l.FanCapability()
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("device doesn't contain a fan")
+	}
+
+	var fanState uint
+	var fanSpeed *uint
+	if state {
+		if speed < 1 || speed > maxSpeed {
+			return fmt.Errorf("fan speed %d is outside of the supported range [%d, %d]", speed, 1, maxSpeed)
+		}
+		fanState, fanSpeed = 1, &speed
+	}
+
+	// Only send the fan parameters, so the light output stays untouched.
+	params := struct {
+		FanState uint         `json:"fanState"`
+		FanMode  FanMode      `json:"fanMode"`
+		FanSpeed *uint        `json:"fanSpeed,omitempty"`
+		FanRevrs FanDirection `json:"fanRevrs"`
+	}{
+		FanState: fanState,
+		FanMode:  mode,
+		FanSpeed: fanSpeed,
+		FanRevrs: direction,
+	}
+
+	return l.call(methodSetPilot, params, nil)
+}
+
+// GetFan queries the device for the current state of the fan.
+//
+// This returns an error if the device doesn't contain a fan.
+func (l *Light) GetFan() (Fan, error) {
+	pilot, err := l.GetPilot()
+	if err != nil {
+		return Fan{}, err
+	}
+
+	if pilot.FanState == nil {
+		return Fan{}, fmt.Errorf("device doesn't contain a fan")
+	}
+
+	fan := Fan{
+		State: *pilot.FanState != 0,
+	}
+	if pilot.FanSpeed != nil {
+		fan.Speed = *pilot.FanSpeed
+	}
+	if pilot.FanRevrs != nil {
+		fan.Direction = *pilot.FanRevrs
+	}
+	if pilot.FanMode != nil {
+		fan.Mode = *pilot.FanMode
+	}
+
+	return fan, nil
+}
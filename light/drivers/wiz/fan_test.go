@@ -0,0 +1,90 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newFanDevice returns a fake ceiling fan with a dimmable light and the given model configuration.
+func newFanDevice(t *testing.T, modelConfig string) (*fakeDevice, *Light) {
+	device := newFakeDevice(t, "ESP03_FANDIMS_31")
+	device.mutex.Lock()
+	device.fixtures = map[method]json.RawMessage{
+		methodGetModelConfig: json.RawMessage(modelConfig),
+	}
+	device.mutex.Unlock()
+
+	l, err := NewLight(device.Address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	return device, l
+}
+
+func TestFan(t *testing.T) {
+	device, l := newFanDevice(t, `{"fanSpeed":6}`)
+
+	if maxSpeed, has, err := l.FanCapability(); err != nil || !has || maxSpeed != 6 {
+		t.Fatalf("FanCapability() returned (%v, %v, %v), want (%v, %v, %v)", maxSpeed, has, err, 6, true, nil)
+	}
+
+	dimming := uint(50)
+	device.mutex.Lock()
+	device.pilot = Pilot{State: true, Dimming: &dimming}
+	device.mutex.Unlock()
+
+	if err := l.SetFan(true, 4, FanDirectionReverse, FanModeBreeze); err != nil {
+		t.Fatalf("SetFan() failed: %v", err)
+	}
+	want := Fan{State: true, Speed: 4, Direction: FanDirectionReverse, Mode: FanModeBreeze}
+	if fan, err := l.GetFan(); err != nil || fan != want {
+		t.Errorf("GetFan() returned (%v, %v), want (%v, %v)", fan, err, want, nil)
+	}
+
+	// Turning the fan off doesn't need a valid speed, and the device keeps the previous one.
+	if err := l.SetFan(false, 0, FanDirectionReverse, FanModeBreeze); err != nil {
+		t.Fatalf("SetFan() failed to turn the fan off: %v", err)
+	}
+	want.State = false
+	if fan, err := l.GetFan(); err != nil || fan != want {
+		t.Errorf("GetFan() returned (%v, %v), want (%v, %v)", fan, err, want, nil)
+	}
+
+	// The light output must not be touched by any fan operation.
+	device.mutex.Lock()
+	pilot := device.pilot
+	device.mutex.Unlock()
+	if !pilot.State || pilot.Dimming == nil || *pilot.Dimming != dimming {
+		t.Errorf("Fan operations changed the light to %v", pilot)
+	}
+}
+
+func TestFanSpeedRange(t *testing.T) {
+	_, l := newFanDevice(t, `{"fanSpeed":6}`)
+
+	for _, speed := range []uint{0, 7} {
+		if err := l.SetFan(true, speed, FanDirectionForward, FanModeNormal); err == nil {
+			t.Errorf("SetFan() with speed %d succeeded, want error", speed)
+		}
+	}
+}
+
+func TestFanMissing(t *testing.T) {
+	_, l := newFanDevice(t, `{"fanSpeed":0}`)
+
+	if _, has, err := l.FanCapability(); err != nil || has {
+		t.Errorf("FanCapability() returned (%v, %v), want (%v, %v)", has, err, false, nil)
+	}
+	if err := l.SetFan(false, 0, FanDirectionForward, FanModeNormal); err == nil {
+		t.Errorf("SetFan() succeeded on a device without fan, want error")
+	}
+	if _, err := l.GetFan(); err == nil {
+		t.Errorf("GetFan() succeeded on a device without fan, want error")
+	}
+}
@@ -6,8 +6,8 @@
 package wiz
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"sync"
 	"time"
 
@@ -16,19 +16,18 @@ import (
 )
 
 type Light struct {
-	address string
+	connection
 
 	// The product describing the device.
 	// Either an exact match or a general product that may fit good enough.
 	// This must not be nil.
 	product *Product
 
-	deadline  time.Duration // Default timeout duration for any communication operation (sending and receiving).
-	retries   uint          // Number of retries when the deadline got exceeded.
-	connMutex sync.Mutex    // Mutex preventing simultaneous connections to this device.
-	//paramMutex sync.Mutex    // Mutex protecting parameters of this object.
+	paramMutex  sync.Mutex // Mutex protecting parameters of this object.
+	fanSpeedMax *uint      // Cached result of the fan capability query. Nil if not queried yet.
+	fadeTimes   *fadeTimes // Cached fade times of the device's user configuration. Nil if not known yet.
 
-	DebugWriter io.Writer // Writer that can be used to debug network communication.
+	TransitionInterval time.Duration // Time between two updates of software transitions, see SetColorsWithTransition(). Defaults to 50 ms if zero.
 }
 
 // Check implementation of light.Light.
@@ -41,13 +40,11 @@ var _ light.Light = &Light{}
 //	light, err := NewLight("192.168.1.123:38899")
 func NewLight(address string) (*Light, error) {
 	light := &Light{
-		address:  address,
-		deadline: 100 * time.Millisecond,
-		retries:  10,
+		connection: newConnection(address),
 	}
 
 	var err error
-	if light.product, err = light.determineProduct(); err != nil {
+	if light.product, err = light.determineProduct(context.Background()); err != nil {
 		return nil, fmt.Errorf("couldn't determine WiZ product: %w", err)
 	}
 
@@ -64,20 +61,19 @@ func NewLightWithProduct(address string, product *Product) (*Light, error) { //
 	}
 
 	light := &Light{
-		address:  address,
-		deadline: 100 * time.Millisecond,
-		retries:  10,
-		product:  product,
+		connection: newConnection(address),
+		product:    product,
 	}
 
 	return light, nil
 }
 
 // determineProduct queries and determines the product of the device.
-func (l *Light) determineProduct() (*Product, error) {
+// Once ctx is done, no further attempts to query the device are made.
+func (l *Light) determineProduct(ctx context.Context) (*Product, error) {
 	// Query device info from lamp.
-	devInfo, err := l.GetDeviceInfo()
-	if err != nil {
+	var devInfo DevInfo
+	if err := l.callContext(ctx, methodGetDevInfo, nil, &devInfo); err != nil {
 		return nil, err
 	}
 
@@ -165,7 +161,7 @@ func (l *Light) GetColors(emissionValues ...emission.ValueReceiver) error {
 	}
 
 	if pilot.Scene != nil {
-		return fmt.Errorf("current state can't be represented by a color")
+		return fmt.Errorf("current pilot %v: %w", pilot, light.ErrNotRepresentable)
 	}
 
 	// Generate DCS color/vector.
@@ -0,0 +1,252 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+	"github.com/Dadido3/D3iot/light/lighttest"
+)
+
+// fakeDevice simulates a WiZ device that listens on a local UDP port.
+// It only supports the most basic methods.
+type fakeDevice struct {
+	conn       net.PacketConn
+	moduleName string
+
+	mutex            sync.Mutex
+	pilot            Pilot
+	setPilotCount    int                        // Number of received setPilot queries.
+	userConfig       *UserConfig                // User configuration, or nil if the device doesn't support it.
+	userConfigWrites int                        // Number of received setUserConfig queries.
+	fixtures         map[method]json.RawMessage // Recorded results that are returned for methods that are not simulated.
+}
+
+// newFakeDevice starts a simulated WiZ device with the given module name.
+// The device is stopped when the test finishes.
+func newFakeDevice(t *testing.T, moduleName string) *fakeDevice {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+
+	d := &fakeDevice{
+		conn:       conn,
+		moduleName: moduleName,
+	}
+
+	go d.serve()
+	t.Cleanup(func() { conn.Close() })
+
+	return d
+}
+
+// Address returns the address the device is listening on.
+func (d *fakeDevice) Address() string {
+	return d.conn.LocalAddr().String()
+}
+
+func (d *fakeDevice) serve() {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var q struct {
+			Method method          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(buf[:n], &q); err != nil {
+			continue
+		}
+
+		responseData, err := json.Marshal(d.handle(q.Method, q.Params))
+		if err != nil {
+			continue
+		}
+		d.conn.WriteTo(responseData, addr)
+	}
+}
+
+// handle returns the response to a query with the given method and parameters.
+func (d *fakeDevice) handle(m method, params json.RawMessage) interface{} {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	r := response{Method: m, Env: "pro"}
+
+	switch m {
+	case methodGetDevInfo:
+		r.Result = DevInfo{Mac: "a8bb50d47cf3", DevMac: "a8bb50d47cf3", ModuleName: d.moduleName}
+
+	case methodGetPilot:
+		r.Result = d.pilot
+
+	case methodSetPilot:
+		d.setPilotCount++
+
+		var fields map[string]json.RawMessage
+		var pilot Pilot
+		if err := json.Unmarshal(params, &fields); err != nil {
+			return errorResponse(m)
+		}
+		if err := json.Unmarshal(params, &pilot); err != nil {
+			return errorResponse(m)
+		}
+
+		onlyFan := true
+		for key := range fields {
+			if !strings.HasPrefix(key, "fan") {
+				onlyFan = false
+			}
+		}
+
+		if _, ok := fields["state"]; ok && len(fields) == 1 {
+			// Only switch the light on or off.
+			d.pilot.State = pilot.State
+		} else if onlyFan {
+			// Only change the fan, keep any fan parameter that is not sent.
+			if pilot.FanState != nil {
+				d.pilot.FanState = pilot.FanState
+			}
+			if pilot.FanMode != nil {
+				d.pilot.FanMode = pilot.FanMode
+			}
+			if pilot.FanSpeed != nil {
+				d.pilot.FanSpeed = pilot.FanSpeed
+			}
+			if pilot.FanRevrs != nil {
+				d.pilot.FanRevrs = pilot.FanRevrs
+			}
+		} else {
+			d.pilot = pilot
+		}
+		r.Result = struct {
+			Success bool `json:"success"`
+		}{true}
+
+	case methodGetUserConfig:
+		if d.userConfig == nil {
+			return errorResponse(m)
+		}
+		r.Result = d.userConfig
+
+	case methodSetUserConfig:
+		if d.userConfig == nil {
+			return errorResponse(m)
+		}
+		if err := json.Unmarshal(params, d.userConfig); err != nil {
+			return errorResponse(m)
+		}
+		d.userConfigWrites++
+		r.Result = struct {
+			Success bool `json:"success"`
+		}{true}
+
+	default:
+		fixture, ok := d.fixtures[m]
+		if !ok {
+			return errorResponse(m)
+		}
+		r.Result = fixture
+	}
+
+	return r
+}
+
+// errorResponse returns a response that signals invalid parameters.
+func errorResponse(m method) response {
+	r := response{Method: m, Env: "pro"}
+	r.Error = &struct {
+		Code    QueryErrorCode `json:"code"`
+		Message string         `json:"message"`
+	}{QueryErrorCodeInvalidParams, "Invalid params"}
+	return r
+}
+
+func TestLightConformance(t *testing.T) {
+	lighttest.Run(t, func(t *testing.T) light.Light {
+		device := newFakeDevice(t, "ESP03_SHRGB1W_01")
+
+		l, err := NewLight(device.Address())
+		if err != nil {
+			t.Fatalf("NewLight() failed: %v", err)
+		}
+
+		return l
+	})
+}
+
+func TestNewLightWithPlug(t *testing.T) {
+	device := newFakeDevice(t, "ESP10_SOCKET_06")
+
+	_, err := NewLight(device.Address())
+
+	var e *ErrNotALight
+	if !errors.As(err, &e) {
+		t.Fatalf("NewLight() returned %v, want error of type %T", err, e)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	device := newFakeDevice(t, "ESP03_SHRGB1W_01")
+
+	l, err := NewLight(device.Address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+
+	// A scene can't be represented by emission values, so it has to be captured natively.
+	scenePilot := NewPilotWithScene(SceneOcean, 80, 50)
+	scenePilot.Mac = "a8bb50d47cf3"
+	device.mutex.Lock()
+	device.pilot = scenePilot
+	device.mutex.Unlock()
+
+	snapshot, err := light.Snapshot(context.Background(), l)
+	if err != nil {
+		t.Fatalf("light.Snapshot() failed: %v", err)
+	}
+
+	// Store and load the snapshot, to check that it can be serialized.
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	var loaded light.State
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+
+	for _, restore := range []func() error{
+		func() error { return snapshot.Restore(context.Background()) },
+		func() error { return loaded.RestoreTo(context.Background(), l) },
+	} {
+		if err := l.SetColors(emission.DCSVector{1, 0, 0, 0, 0}); err != nil {
+			t.Fatalf("SetColors() failed: %v", err)
+		}
+		if err := restore(); err != nil {
+			t.Fatalf("Restoring snapshot failed: %v", err)
+		}
+
+		device.mutex.Lock()
+		pilot := device.pilot
+		device.mutex.Unlock()
+		if pilot.Scene == nil || *pilot.Scene != SceneOcean || pilot.Dimming == nil || *pilot.Dimming != 80 || pilot.R != nil || pilot.Mac != "" {
+			t.Errorf("Device has pilot %v after restoring, want %v", pilot, scenePilot)
+		}
+	}
+}
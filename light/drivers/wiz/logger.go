@@ -0,0 +1,60 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// LogEntry contains all the information about a single attempt to communicate with a device.
+//
+// Every retry creates its own entry, so a query with 2 retries will result in 3 entries.
+type LogEntry struct {
+	Method   string        // The method of the query. E.g. "getPilot".
+	Address  string        // The address of the device, as given to NewLight().
+	Attempt  uint          // Attempt number, starting at 1.
+	Latency  time.Duration // Time between sending the request and receiving the response (or the error).
+	Request  []byte        // The JSON payload that was sent to the device.
+	Response []byte        // The JSON payload that was received from the device. This is nil in case of an error.
+	Err      error         // Any error that happened during the attempt.
+}
+
+// Logger receives structured log entries about the network communication with WiZ devices.
+//
+// The logger may be called concurrently from different lights, so it has to be safe for concurrent use.
+// It shouldn't block, as it is called while the connection to the device is held.
+type Logger interface {
+	LogQuery(entry LogEntry)
+}
+
+// LoggerFunc is an adapter to allow the use of ordinary functions as loggers.
+//
+// This can be used to forward log entries to any structured logging library:
+//
+//	light.Logger = wiz.LoggerFunc(func(e wiz.LogEntry) {
+//		slogger.Debug("wiz query", "method", e.Method, "address", e.Address, "attempt", e.Attempt, "latency", e.Latency, "err", e.Err)
+//	})
+type LoggerFunc func(entry LogEntry)
+
+// LogQuery implements the Logger interface.
+func (f LoggerFunc) LogQuery(entry LogEntry) {
+	f(entry)
+}
+
+// WriterLogger returns a logger that writes every entry as a single line of text into w.
+//
+// This is mostly meant for debugging.
+func WriterLogger(w io.Writer) Logger {
+	return LoggerFunc(func(e LogEntry) {
+		if e.Err != nil {
+			fmt.Fprintf(w, "%s %q attempt %d after %v: %s failed: %v\n", e.Address, e.Method, e.Attempt, e.Latency, e.Request, e.Err)
+		} else {
+			fmt.Fprintf(w, "%s %q attempt %d after %v: %s --> %s\n", e.Address, e.Method, e.Attempt, e.Latency, e.Request, e.Response)
+		}
+	})
+}
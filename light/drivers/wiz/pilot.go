@@ -1,4 +1,4 @@
-// Copyright (c) 2021 David Vogel
+// Copyright (c) 2021-2022 David Vogel
 //
 // This software is released under the MIT License.
 // https://opensource.org/licenses/MIT
@@ -34,6 +34,11 @@ type Pilot struct {
 	B  *uint8 `json:"b,omitempty"` // Blue luminance range 0-255.
 	CW *uint8 `json:"c,omitempty"` // Cold white luminance range 0-255.
 	WW *uint8 `json:"w,omitempty"` // Warm white luminance range 0-255.
+
+	FanState *uint         `json:"fanState,omitempty"` // Fan on off state. 0 = off, 1 = on. Only available on devices with a fan.
+	FanMode  *FanMode      `json:"fanMode,omitempty"`  // Fan mode. Only available on devices with a fan.
+	FanSpeed *uint         `json:"fanSpeed,omitempty"` // Fan speed in the range of [1, max], see Light.FanCapability(). Only available on devices with a fan.
+	FanRevrs *FanDirection `json:"fanRevrs,omitempty"` // Fan rotation direction. Only available on devices with a fan.
 }
 
 // NewPilot returns a pilot with the given light state.
@@ -141,6 +146,38 @@ func NewPilotWithScene(s Scene, dimming, speed uint) Pilot {
 	return p
 }
 
+// restorable returns a copy of a received pilot that can be sent back to the device to restore its state.
+// This removes all read-only fields and unused parameters.
+func (p Pilot) restorable() Pilot {
+	if !p.State {
+		off := NewPilot(false)
+		off.FanState, off.FanMode, off.FanSpeed, off.FanRevrs = p.FanState, p.FanMode, p.FanSpeed, p.FanRevrs
+		return off
+	}
+
+	p.Mac, p.RSSI, p.Src, p.SchdPsetID = "", 0, "", nil
+
+	if p.Scene != nil && p.Scene.id != 0 {
+		// Only keep the parameters that the scene needs.
+		dimming, speed := uint(100), uint(100)
+		if p.Dimming != nil {
+			dimming = *p.Dimming
+		}
+		if p.Speed != nil {
+			speed = *p.Speed
+		}
+		scene := NewPilotWithScene(*p.Scene, dimming, speed)
+		p.Scene, p.Dimming, p.Speed, p.Temp = scene.Scene, scene.Dimming, scene.Speed, nil
+		p.R, p.G, p.B, p.CW, p.WW = nil, nil, nil, nil, nil
+		return p
+	}
+
+	// The device reports a scene ID of 0 when no scene is active.
+	p.Scene, p.Speed = nil, nil
+
+	return p
+}
+
 // WithDimming returns a copy of the pilot set to the given dimming value.
 func (p Pilot) WithDimming(dimming uint) Pilot {
 	p.Dimming = &dimming
@@ -341,6 +378,19 @@ func (p Pilot) String() string {
 		result += fmt.Sprintf(", WW: %d", *p.WW)
 	}
 
+	if p.FanState != nil {
+		result += fmt.Sprintf(", FanState: %d", *p.FanState)
+	}
+	if p.FanMode != nil {
+		result += fmt.Sprintf(", FanMode: %v", *p.FanMode)
+	}
+	if p.FanSpeed != nil {
+		result += fmt.Sprintf(", FanSpeed: %d", *p.FanSpeed)
+	}
+	if p.FanRevrs != nil {
+		result += fmt.Sprintf(", FanRevrs: %v", *p.FanRevrs)
+	}
+
 	result += "}"
 
 	return result
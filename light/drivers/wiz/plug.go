@@ -0,0 +1,113 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"fmt"
+)
+
+// Plug represents a WiZ smart plug or socket.
+//
+// Plugs can only be switched on and off, therefore they don't implement the light.Light interface.
+// Their power-on behaviour can be changed with SetPowerOnRestore().
+type Plug struct {
+	connection
+
+	moduleName string // The ModuleName that the device returns via GetDeviceInfo().
+}
+
+// NewPlug returns an object that represents a single WiZ plug accessible by the given address.
+//
+// This will query the device info, so it needs to be able to connect via the given address.
+// If the device is not a plug or socket, this will return an error of the type *ErrNotAPlug.
+//
+//	plug, err := NewPlug("192.168.1.123:38899")
+func NewPlug(address string) (*Plug, error) {
+	plug := &Plug{
+		connection: newConnection(address),
+	}
+
+	devInfo, err := plug.GetDeviceInfo()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't query device info: %w", err)
+	}
+
+	if dc, err := parseDeviceClass(devInfo.ModuleName); err != nil || dc != deviceClassSocket {
+		return nil, &ErrNotAPlug{moduleName: devInfo.ModuleName}
+	}
+
+	plug.moduleName = devInfo.ModuleName
+
+	return plug, nil
+}
+
+// ModuleName returns the module name of the device.
+func (p *Plug) ModuleName() string {
+	return p.moduleName
+}
+
+// SetState turns the plug on or off.
+func (p *Plug) SetState(on bool) error {
+	return p.call(methodSetPilot, NewPilot(on), nil)
+}
+
+// State queries the plug for its on/off state.
+func (p *Plug) State() (bool, error) {
+	var pilot Pilot
+	if err := p.call(methodGetPilot, nil, &pilot); err != nil {
+		return false, err
+	}
+
+	return pilot.State, nil
+}
+
+// GetPower queries the plug for the power that is currently drawn by the connected load.
+// The result is in watts.
+//
+// Not all plugs support energy readings, in that case the device will return an error of the type *ErrQueryFailed.
+func (p *Plug) GetPower() (float64, error) {
+	var result struct {
+		Power float64 `json:"power"` // Power in milliwatts.
+	}
+	if err := p.call(methodGetPower, nil, &result); err != nil {
+		return 0, err
+	}
+
+	return result.Power / 1000, nil
+}
+
+// SetPowerOnRestore sets the power-on behaviour of the plug.
+// If restore is true, the plug restores its last state after a power loss, otherwise it is turned on.
+//
+// This writes the "po" field of the user configuration, which is stored in the flash memory of the device.
+// The meaning of this field was determined by experimentation, it may differ with some firmware versions.
+// Plugs that don't support it will return an error of the type *ErrQueryFailed.
+func (p *Plug) SetPowerOnRestore(restore bool) error {
+	params := struct {
+		PO bool `json:"po"`
+	}{
+		PO: restore,
+	}
+
+	return p.call(methodSetUserConfig, params, nil)
+}
+
+// PowerOnRestore queries the power-on behaviour of the plug.
+// See SetPowerOnRestore() for details.
+func (p *Plug) PowerOnRestore() (bool, error) {
+	userConfig, err := p.GetUserConfig()
+	if err != nil {
+		return false, err
+	}
+
+	return userConfig.PO, nil
+}
+
+// Reboot reboots the plug.
+// This will not reset any parameters.
+func (p *Plug) Reboot() error {
+	return p.call(methodReboot, nil, nil)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPlugGetPower(t *testing.T) {
+	device := newFakeDevice(t, "ESP25_SOCKET_01")
+	device.mutex.Lock()
+	device.fixtures = map[method]json.RawMessage{
+		methodGetPower: json.RawMessage(`{"power":4321}`), // Recorded from a plug with energy monitoring.
+	}
+	device.mutex.Unlock()
+
+	plug, err := NewPlug(device.Address())
+	if err != nil {
+		t.Fatalf("NewPlug() failed: %v", err)
+	}
+
+	watts, err := plug.GetPower()
+	if err != nil {
+		t.Fatalf("GetPower() failed: %v", err)
+	}
+	if watts != 4.321 {
+		t.Errorf("GetPower() returned %v W, want %v W", watts, 4.321)
+	}
+}
+
+func TestPlugGetPowerUnsupported(t *testing.T) {
+	device := newFakeDevice(t, "ESP10_SOCKET_06")
+
+	plug, err := NewPlug(device.Address())
+	if err != nil {
+		t.Fatalf("NewPlug() failed: %v", err)
+	}
+
+	_, err = plug.GetPower()
+
+	var e *ErrQueryFailed
+	if !errors.As(err, &e) {
+		t.Errorf("GetPower() returned %v, want error of type %T", err, e)
+	}
+}
+
+func TestPlugPowerOnRestore(t *testing.T) {
+	device := newFakeDevice(t, "ESP10_SOCKET_06")
+	device.mutex.Lock()
+	device.userConfig = &UserConfig{}
+	device.mutex.Unlock()
+
+	plug, err := NewPlug(device.Address())
+	if err != nil {
+		t.Fatalf("NewPlug() failed: %v", err)
+	}
+
+	for _, restore := range []bool{true, false} {
+		if err := plug.SetPowerOnRestore(restore); err != nil {
+			t.Fatalf("SetPowerOnRestore(%v) failed: %v", restore, err)
+		}
+		if got, err := plug.PowerOnRestore(); err != nil || got != restore {
+			t.Errorf("PowerOnRestore() returned %v, %v, want %v", got, err, restore)
+		}
+	}
+
+	device.mutex.Lock()
+	defer device.mutex.Unlock()
+	if device.userConfigWrites != 2 {
+		t.Errorf("Device received %d user config writes, want 2", device.userConfigWrites)
+	}
+}
+
+func TestPlugPowerOnRestoreUnsupported(t *testing.T) {
+	device := newFakeDevice(t, "ESP10_SOCKET_06")
+
+	plug, err := NewPlug(device.Address())
+	if err != nil {
+		t.Fatalf("NewPlug() failed: %v", err)
+	}
+
+	var e *ErrQueryFailed
+	if err := plug.SetPowerOnRestore(true); !errors.As(err, &e) {
+		t.Errorf("SetPowerOnRestore() returned %v, want error of type %T", err, e)
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021 David Vogel
+// Copyright (c) 2021-2022 David Vogel
 //
 // This software is released under the MIT License.
 // https://opensource.org/licenses/MIT
@@ -39,6 +39,11 @@ func (p Product) ModuleName() string {
 	return p.moduleName
 }
 
+// ColorProfile returns the color profile of the product's light module.
+func (p Product) ColorProfile() emission.ColorProfile {
+	return p.colorProfile
+}
+
 // DimmingCapability returns the min and max dimming value that the product supports.
 // If the returned bool is false, the device doesn't have any dimming control.
 func (p Product) DimmingCapability() (min, max uint, has bool) {
@@ -101,9 +106,10 @@ func (p Product) String() string {
 type deviceClass string
 
 const (
-	deviceClassDW    deviceClass = "DW"    // Dimmable white.
-	deviceClassTW    deviceClass = "TW"    // Tweakable/Tunable white.
-	deviceClassRGBTW deviceClass = "RGBTW" // RGB + cold white + warm white. DeviceClassRGBTW is called "RGB" in the moduleName.
+	deviceClassDW     deviceClass = "DW"     // Dimmable white.
+	deviceClassTW     deviceClass = "TW"     // Tweakable/Tunable white.
+	deviceClassRGBTW  deviceClass = "RGBTW"  // RGB + cold white + warm white. DeviceClassRGBTW is called "RGB" in the moduleName.
+	deviceClassSocket deviceClass = "SOCKET" // Smart plugs and sockets. These are not lights, see Plug.
 )
 
 // deviceClassDWScenes is a predefined list of scenes that are available to DW class devices.
@@ -171,11 +177,23 @@ func parseDeviceClass(moduleName string) (deviceClass, error) {
 		return deviceClassTW, nil
 	case strings.HasPrefix(details, "SHRGB"):
 		return deviceClassRGBTW, nil
+	case strings.HasPrefix(details, "FANDIM"):
+		return deviceClassDW, nil // Fan with a dimmable white light. The fan is controlled separately.
+	case strings.HasPrefix(details, "SOCKET"):
+		return deviceClassSocket, nil
 	}
 
 	return "", fmt.Errorf("%q doesn't match with any known device class", details)
 }
 
+// LookupProduct returns a matching product for the given moduleName.
+// This can be a similar product if there is no exact match.
+//
+// This can be used to get the color profile of a product without connecting to a device.
+func LookupProduct(moduleName string) (*Product, error) {
+	return determineProduct(moduleName)
+}
+
 // determineProduct returns a matching product for the given moduleName.
 // This can be a similar product if there is no exact match.
 func determineProduct(moduleName string) (*Product, error) {
@@ -193,6 +211,11 @@ func determineProduct(moduleName string) (*Product, error) {
 		return nil, err
 	}
 
+	// Plugs and sockets have no light output.
+	if deviceClass == deviceClassSocket {
+		return nil, &ErrNotALight{moduleName: moduleName}
+	}
+
 	// Find the first product with the same device class.
 	// TODO: Improve how unknown devices are matched
 	for _, product := range products {
@@ -0,0 +1,80 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+// defaultPort is the UDP port that WiZ devices listen on.
+const defaultPort = "38899"
+
+func init() {
+	light.Register("wiz", openURI)
+}
+
+// openURI creates a light object from an URI in the form of
+//
+//	wiz://host[:port][?retries=10&deadline=100ms&product=ESP03_SHRGB1W_01]
+//
+// If no port is given, the default port of WiZ devices is used.
+// If the product is given, the device will not be queried for its product.
+func openURI(ctx context.Context, uri *url.URL) (light.Light, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if uri.Hostname() == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a host", uri)
+	}
+	address := uri.Host
+	if uri.Port() == "" {
+		address = net.JoinHostPort(uri.Hostname(), defaultPort)
+	}
+
+	query := uri.Query()
+
+	l := &Light{
+		connection: newConnection(address),
+	}
+
+	// Apply communication parameters first, as they are already needed to determine the product.
+	if value := query.Get("retries"); value != "" {
+		retries, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse retries %q: %w", value, err)
+		}
+		l.retries = uint(retries)
+	}
+
+	if value := query.Get("deadline"); value != "" {
+		deadline, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deadline %q: %w", value, err)
+		}
+		l.deadline = deadline
+	}
+
+	var err error
+	if moduleName := query.Get("product"); moduleName != "" {
+		if l.product, err = determineProduct(moduleName); err != nil {
+			return nil, fmt.Errorf("couldn't determine WiZ product %q: %w", moduleName, err)
+		}
+	} else {
+		if l.product, err = l.determineProduct(ctx); err != nil {
+			return nil, fmt.Errorf("couldn't determine WiZ product: %w", err)
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+func TestOpenURI(t *testing.T) {
+	device := newFakeDevice(t, "ESP03_SHRGB1W_01")
+
+	l, err := light.Open(context.Background(), "wiz://"+device.Address()+"?retries=2&deadline=50ms")
+	if err != nil {
+		t.Fatalf("light.Open() failed: %v", err)
+	}
+
+	wizLight, ok := l.(*Light)
+	if !ok {
+		t.Fatalf("light.Open() returned %T, want %T", l, wizLight)
+	}
+	if wizLight.retries != 2 || wizLight.deadline != 50*time.Millisecond {
+		t.Errorf("Light has retries %d and deadline %v, want %d and %v", wizLight.retries, wizLight.deadline, 2, 50*time.Millisecond)
+	}
+	if moduleName := wizLight.Product().ModuleName(); moduleName != "ESP03_SHRGB1W_01" {
+		t.Errorf("Light has product %q, want %q", moduleName, "ESP03_SHRGB1W_01")
+	}
+}
+
+func TestOpenURIContext(t *testing.T) {
+	// A device that never answers.
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.ListenPacket() failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Without the context, this would retry for 100 seconds.
+	start := time.Now()
+	_, err = light.Open(ctx, "wiz://"+conn.LocalAddr().String()+"?retries=1000&deadline=100ms")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("light.Open() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("light.Open() returned after %v, want it to return when ctx is done", elapsed)
+	}
+}
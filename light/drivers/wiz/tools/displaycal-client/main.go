@@ -6,16 +6,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 
+	"github.com/Dadido3/D3iot/light"
+	_ "github.com/Dadido3/D3iot/light/drivers/all"
 	"github.com/Dadido3/D3iot/light/drivers/wiz"
+	"github.com/Dadido3/D3iot/light/emission"
 )
 
-var flagDeviceAddress = flag.String("address", "", "The address of the device to be controlled. Example: \"--address wiz-123abc:38899\" or \"--address 192.168.1.123:38899\"")
+var flagDevice = flag.String("device", "", "The URI of the light device to be controlled. Example: \"--device wiz://wiz-123abc:38899\" or \"--device wiz://192.168.1.123:38899\"")
 var flagDisplayCALAddress = flag.String("http-server", "http://localhost:8080/", "The address of the DisplayCAL web-server. Example: \"--http-server http://localhost:8080/\"")
 var flagMaxR = flag.Uint("max-r", 255, "The maximum value of the red channel. The input will be scaled to fit into [0, max].")
 var flagMaxG = flag.Uint("max-g", 255, "The maximum value of the green channel. The input will be scaled to fit into [0, max].")
@@ -24,15 +28,19 @@ var flagMaxB = flag.Uint("max-b", 255, "The maximum value of the blue channel. T
 func main() {
 	flag.Parse()
 
-	if *flagDeviceAddress == "" {
-		log.Printf("No device address given. Start program with the \"--address\" parameter set.")
-		log.Printf("Example: displaycal-client --address wiz-123abc:38899")
+	if *flagDevice == "" {
+		log.Printf("No device URI given. Start program with the \"--device\" parameter set.")
+		log.Printf("Example: displaycal-client --device wiz://wiz-123abc:38899")
 		return
 	}
 
-	light, err := wiz.NewLight(*flagDeviceAddress)
+	device, err := light.Open(context.Background(), *flagDevice)
 	if err != nil {
-		log.Panicf("wiz.NewLight() failed: %v", err)
+		log.Panicf("light.Open() failed: %v", err)
+	}
+
+	if channels := device.ColorProfiles()[0].Channels(); channels < 3 {
+		log.Panicf("Device %q has only %d channels, it needs at least red, green and blue emitters", *flagDevice, channels)
 	}
 
 	// Setup HTTP request.
@@ -57,12 +65,26 @@ func main() {
 
 		rScaled, gScaled, bScaled := uint8(r*(*flagMaxR)/255), uint8(g*(*flagMaxG)/255), uint8(b*(*flagMaxB)/255)
 
-		if err := light.SetPilot(wiz.NewPilotWithRGB(100, rScaled, gScaled, bScaled)); err != nil {
-			log.Panicf("light.SetPilot() with RGB %d, %d, %d failed: %v", rScaled, gScaled, bScaled, err)
+		if err := setRGB(device, rScaled, gScaled, bScaled); err != nil {
+			log.Panicf("setRGB() with RGB %d, %d, %d failed: %v", rScaled, gScaled, bScaled, err)
 		}
 	}
 }
 
+// setRGB sets the red, green and blue emitters of the device to the given raw values, all other emitters are turned off.
+// WiZ lights get the values directly via their pilot.
+// All other devices get them as the first three channels of their device color space, which are red, green and blue by convention.
+func setRGB(device light.Light, r, g, b uint8) error {
+	if wizLight, ok := device.(*wiz.Light); ok {
+		return wizLight.SetPilot(wiz.NewPilotWithRGB(100, r, g, b))
+	}
+
+	vector := make(emission.DCSVector, device.ColorProfiles()[0].Channels())
+	vector[0], vector[1], vector[2] = float64(r)/255, float64(g)/255, float64(b)/255
+
+	return device.SetColors(vector)
+}
+
 func queryMessage(request *http.Request) (response string, err error) {
 	client := new(http.Client)
 
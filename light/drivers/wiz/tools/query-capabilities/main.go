@@ -6,7 +6,7 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -15,14 +15,27 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Dadido3/D3iot/light"
+	_ "github.com/Dadido3/D3iot/light/drivers/all"
 	"github.com/Dadido3/D3iot/light/drivers/wiz"
+	"github.com/Dadido3/D3iot/light/emission"
 )
 
 // Result contains all important data of the query.
 type Result struct {
-	ModuleName     string
-	MatchedProduct *wiz.Product // The closest matching product.
+	// Information that is available for all light devices.
+	Modules          int
+	Channels         []int                    // The number of DCS channels of every module.
+	WhitePoints      []emission.CIE1931XYZAbs // The white point of every module.
+	DeviceInfo       *light.DeviceInfo        `json:",omitempty"` // Only for devices that implement light.InfoProvider.
+	TemperatureRange *[2]float64              `json:",omitempty"` // Only for devices that implement light.TemperatureRanger.
+	Scenes           []string                 `json:",omitempty"` // Only for devices that implement light.SceneProvider.
+
+	// WiZ specific information.
+	ModuleName     string       `json:",omitempty"`
+	MatchedProduct *wiz.Product `json:",omitempty"` // The closest matching product.
 
 	CurrentPilot        wiz.Pilot        // Current pilot.
 	CurrentFavs         wiz.Favs         // Current Favorite settings.
@@ -33,30 +46,102 @@ type Result struct {
 	SupportedScenes  []wiz.Scene // List of supported scenes.
 	MinTemp, MaxTemp *uint       // Temperature range [minTemp, maxTemp] in K.
 
-	DebugEntries []string // Debug output of the library, split by newline characters.
+	DebugEntries []DebugEntry // Debug output of the library, one entry per communication attempt.
 }
 
-var flagDeviceAddress = flag.String("address", "wiz-d47cf3:38899", "The address of the device to be queried. Example: \"--address wiz-123abc:38899\" or \"--address 192.168.1.123:38899\"")
+// DebugEntry contains a single communication attempt with the device.
+type DebugEntry struct {
+	Method   string
+	Attempt  uint
+	Latency  time.Duration
+	Request  string
+	Response string `json:",omitempty"`
+	Error    string `json:",omitempty"`
+}
+
+var flagDevice = flag.String("device", "wiz://wiz-d47cf3:38899", "The URI of the light device to be queried. Example: \"--device wiz://wiz-123abc:38899\" or \"--device wiz://192.168.1.123:38899\"")
 
 func main() {
 	flag.Parse()
 
-	if *flagDeviceAddress == "" {
-		log.Printf("No device address given. Start program with the \"--address\" parameter set.")
-		log.Printf("Example: query-capabilities --address wiz-123abc:38899")
+	if *flagDevice == "" {
+		log.Printf("No device URI given. Start program with the \"--device\" parameter set.")
+		log.Printf("Example: query-capabilities --device wiz://wiz-123abc:38899")
 		return
 	}
 
 	var res Result
 
-	light, err := wiz.NewLight(*flagDeviceAddress)
+	device, err := light.Open(context.Background(), *flagDevice)
 	if err != nil {
-		log.Panicf("wiz.NewLight() failed: %v", err)
+		log.Panicf("light.Open() failed: %v", err)
+	}
+
+	queryGeneric(device, &res)
+
+	// Devices of other drivers only support the generic queries.
+	if wizLight, ok := device.(*wiz.Light); ok {
+		queryWiZ(wizLight, &res)
+	}
+
+	// Write result.
+	name := res.ModuleName
+	if name == "" && res.DeviceInfo != nil {
+		name = res.DeviceInfo.Model
+	}
+	if name == "" {
+		name = "device"
+	}
+	os.Mkdir("queried", 0755)
+	filename := filepath.Join("queried", strings.ReplaceAll(name, string(filepath.Separator), "_")+".json")
+	if err := writeResult(filename, res); err != nil {
+		log.Panicf("Failed to write file %q: %v", filename, err)
+	}
+}
+
+// queryGeneric queries the information that is available via the light.Light interface and its optional interfaces.
+func queryGeneric(device light.Light, res *Result) {
+	res.Modules = device.Modules()
+	for _, colorProfile := range device.ColorProfiles() {
+		res.Channels = append(res.Channels, colorProfile.Channels())
+		res.WhitePoints = append(res.WhitePoints, colorProfile.WhitePoint())
+	}
+
+	if infoProvider, ok := device.(light.InfoProvider); ok {
+		if info, err := infoProvider.DeviceInfo(); err != nil {
+			log.Panicf("DeviceInfo() failed: %v", err)
+		} else {
+			res.DeviceInfo = &info
+		}
 	}
 
-	// Write debug output into buffer.
-	debugBuffer := new(bytes.Buffer)
-	light.DebugWriter = debugBuffer
+	if ranger, ok := device.(light.TemperatureRanger); ok {
+		if min, max, ok := ranger.TemperatureRange(); ok {
+			res.TemperatureRange = &[2]float64{min, max}
+		}
+	}
+
+	if sceneProvider, ok := device.(light.SceneProvider); ok {
+		res.Scenes = sceneProvider.Scenes()
+	}
+}
+
+// queryWiZ queries the WiZ specific information, and probes the supported scenes and color temperatures.
+func queryWiZ(light *wiz.Light, res *Result) {
+	// Write debug output into result.
+	light.Logger = wiz.LoggerFunc(func(e wiz.LogEntry) {
+		entry := DebugEntry{
+			Method:   e.Method,
+			Attempt:  e.Attempt,
+			Latency:  e.Latency,
+			Request:  string(e.Request),
+			Response: string(e.Response),
+		}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+		res.DebugEntries = append(res.DebugEntries, entry)
+	})
 
 	// Get device information.
 	if devInfo, err := light.GetDeviceInfo(); err != nil {
@@ -158,15 +243,6 @@ func main() {
 		log.Printf("light.SetPilot() failed: %v", err)
 	}
 
-	// Write debug buffer into result.
-	res.DebugEntries = strings.Split(debugBuffer.String(), "\n")
-
-	// Write result.
-	os.Mkdir("queried", 0755)
-	filename := filepath.Join("queried", res.ModuleName+".json")
-	if err := writeResult(filename, res); err != nil {
-		log.Panicf("Failed to write file %q: %v", filename, err)
-	}
 }
 
 func writeResult(filename string, res Result) error {
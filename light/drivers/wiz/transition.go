@@ -0,0 +1,170 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// defaultTransitionInterval is the time between two updates of a software transition, if nothing else is set.
+const defaultTransitionInterval = 50 * time.Millisecond
+
+// fadeTimes contains the hardware fade times of a device.
+type fadeTimes struct {
+	fadeIn, fadeOut time.Duration
+}
+
+// SetFade sets the hardware fade times of the device.
+// The fade-in time is used when the light is turned on, the fade-out time when it is turned off.
+//
+// The values are stored in the user configuration of the device, so they persist until they are changed again.
+func (l *Light) SetFade(fadeIn, fadeOut time.Duration) error {
+	params := struct {
+		FadeIn  uint `json:"fadeIn"`
+		FadeOut uint `json:"fadeOut"`
+	}{
+		FadeIn:  uint(fadeIn.Milliseconds()),
+		FadeOut: uint(fadeOut.Milliseconds()),
+	}
+
+	if err := l.call(methodSetUserConfig, params, nil); err != nil {
+		return err
+	}
+
+	l.paramMutex.Lock()
+	defer l.paramMutex.Unlock()
+	l.fadeTimes = &fadeTimes{fadeIn: fadeIn, fadeOut: fadeOut}
+
+	return nil
+}
+
+// getFadeTimes returns the hardware fade times of the device.
+//
+// The result is queried from the device's user configuration once, and cached afterwards.
+func (l *Light) getFadeTimes() (fadeTimes, error) {
+	l.paramMutex.Lock()
+	defer l.paramMutex.Unlock()
+
+	if l.fadeTimes == nil {
+		userConfig, err := l.GetUserConfig()
+		if err != nil {
+			return fadeTimes{}, err
+		}
+
+		l.fadeTimes = &fadeTimes{
+			fadeIn:  time.Duration(userConfig.FadeIn) * time.Millisecond,
+			fadeOut: time.Duration(userConfig.FadeOut) * time.Millisecond,
+		}
+	}
+
+	return *l.fadeTimes, nil
+}
+
+// SetColorsWithTransition sets the emission values of all the modules in the light device.
+// Other than SetColors, this will transition smoothly from the current to the new state over the duration d.
+// This blocks until the transition is done, or until ctx is cancelled.
+//
+// If the light is turned on or off and the fade time of the firmware equals d, the firmware does the transition.
+// The fade times of the device are never changed by this, see SetFade().
+// In all other cases, the transition is done by software.
+// The software transition interpolates in the CIE 1976 L*a*b* color space of the light's color profile, so the path between two colors is perceptually uniform and stays inside the gamut of the device.
+// See TransitionInterval for the update rate.
+func (l *Light) SetColorsWithTransition(ctx context.Context, d time.Duration, emissionValues ...emission.Value) error {
+	if len(emissionValues) > l.Modules() {
+		return fmt.Errorf("got %d emission values, this device has only %d module", len(emissionValues), l.Modules())
+	}
+
+	colorProfile := l.ColorProfiles()[0]
+
+	// Determine the start and target state.
+	var startVector emission.DCSVector
+	if err := l.GetColors(&startVector); err != nil {
+		if errors.Is(err, light.ErrNotRepresentable) {
+			// There is no meaningful start color, so just jump to the target.
+			return l.SetColors(emissionValues...)
+		}
+		return err
+	}
+	targetVector := make(emission.DCSVector, colorProfile.Channels())
+	if len(emissionValues) > 0 {
+		targetVector = emissionValues[0].IntoDCS(colorProfile)
+	}
+
+	// Try to use the firmware to fade on or off.
+	startOff, targetOff := isDCSOff(startVector), isDCSOff(targetVector)
+	if startOff != targetOff {
+		if ok, err := l.setColorsWithFirmwareFade(ctx, d, targetOff, emissionValues...); ok || err != nil {
+			return err
+		}
+	}
+
+	return l.setColorsWithSoftwareTransition(ctx, d, startVector, emissionValues...)
+}
+
+// setColorsWithFirmwareFade sets the emission values by using the fade times of the firmware.
+// It returns false if the firmware doesn't support fade times, or if the fade time of the device doesn't equal d.
+func (l *Light) setColorsWithFirmwareFade(ctx context.Context, d time.Duration, turnOff bool, emissionValues ...emission.Value) (bool, error) {
+	fades, err := l.getFadeTimes()
+	if err != nil {
+		var e *ErrQueryFailed
+		if errors.As(err, &e) {
+			// The device doesn't support this.
+			return false, nil
+		}
+		return false, err
+	}
+
+	// The fade times are stored in the flash memory of the device, and they are a setting of the user.
+	// So they are never changed by a transition, and only used if they already match.
+	if turnOff && fades.fadeOut != d || !turnOff && fades.fadeIn != d {
+		return false, nil
+	}
+
+	if err := l.SetColors(emissionValues...); err != nil {
+		return true, err
+	}
+
+	// Wait until the device is done with the fade, to behave the same as the software transition.
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// setColorsWithSoftwareTransition interpolates between the start vector and the target values by sending intermediate values to the device.
+func (l *Light) setColorsWithSoftwareTransition(ctx context.Context, d time.Duration, startVector emission.DCSVector, emissionValues ...emission.Value) error {
+	interval := l.TransitionInterval
+	if interval <= 0 {
+		interval = defaultTransitionInterval
+	}
+
+	options := light.FadeOptions{
+		Space:    light.InterpolationCIE1976LAB,
+		Interval: interval,
+	}
+
+	return light.Fade(ctx, l, []emission.Value{startVector}, emissionValues, d, options)
+}
+
+// isDCSOff returns true if all channels of v are zero.
+func isDCSOff(v emission.DCSVector) bool {
+	for _, channel := range v {
+		if channel > 0 {
+			return false
+		}
+	}
+	return true
+}
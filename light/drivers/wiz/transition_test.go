@@ -0,0 +1,146 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package wiz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// newTransitionDevice returns a fake RGBTW light with the given user configuration.
+func newTransitionDevice(t *testing.T, userConfig *UserConfig) (*fakeDevice, *Light) {
+	device := newFakeDevice(t, "ESP03_SHRGB1W_01")
+	device.mutex.Lock()
+	device.userConfig = userConfig
+	device.mutex.Unlock()
+
+	l, err := NewLight(device.Address())
+	if err != nil {
+		t.Fatalf("NewLight() failed: %v", err)
+	}
+	l.TransitionInterval = 5 * time.Millisecond
+
+	return device, l
+}
+
+func TestTransitionFirmware(t *testing.T) {
+	device, l := newTransitionDevice(t, &UserConfig{FadeIn: 20, FadeOut: 20})
+	target := emission.DCSVector{1, 0, 0, 0, 0}
+
+	steps := []struct {
+		values    []emission.Value
+		duration  time.Duration
+		firmware  bool
+		wantState bool
+	}{
+		{[]emission.Value{target}, 20 * time.Millisecond, true, true},
+		{nil, 20 * time.Millisecond, true, false},
+		{[]emission.Value{target}, 50 * time.Millisecond, false, true}, // The fade time doesn't match, so the transition is done by software.
+		{nil, 50 * time.Millisecond, false, false},
+	}
+
+	for i, step := range steps {
+		device.mutex.Lock()
+		setPilotCount := device.setPilotCount
+		device.mutex.Unlock()
+
+		if err := l.SetColorsWithTransition(context.Background(), step.duration, step.values...); err != nil {
+			t.Fatalf("Step %d: SetColorsWithTransition() failed: %v", i, err)
+		}
+
+		device.mutex.Lock()
+		pilot, userConfig, writes := device.pilot, *device.userConfig, device.userConfigWrites
+		setPilots := device.setPilotCount - setPilotCount
+		device.mutex.Unlock()
+
+		if pilot.State != step.wantState {
+			t.Errorf("Step %d: Device has state %v, want %v", i, pilot.State, step.wantState)
+		}
+		if step.firmware && setPilots != 1 {
+			t.Errorf("Step %d: Device received %d pilots, want %d", i, setPilots, 1)
+		}
+		if !step.firmware && setPilots < 3 {
+			t.Errorf("Step %d: Device received %d pilots, want a software transition with at least %d steps", i, setPilots, 3)
+		}
+		if userConfig.FadeIn != 20 || userConfig.FadeOut != 20 || writes != 0 {
+			t.Errorf("Step %d: Device has fade times (%d, %d) after %d writes, want the unchanged (%d, %d)", i, userConfig.FadeIn, userConfig.FadeOut, writes, 20, 20)
+		}
+	}
+}
+
+func TestTransitionSoftware(t *testing.T) {
+	device, l := newTransitionDevice(t, &UserConfig{})
+
+	if err := l.SetColors(emission.DCSVector{1, 0, 0, 0, 0}); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	device.mutex.Lock()
+	device.setPilotCount = 0
+	device.mutex.Unlock()
+
+	if err := l.SetColorsWithTransition(context.Background(), 50*time.Millisecond, emission.DCSVector{0, 0, 1, 0, 0}); err != nil {
+		t.Fatalf("SetColorsWithTransition() failed: %v", err)
+	}
+
+	device.mutex.Lock()
+	pilot, setPilots, writes := device.pilot, device.setPilotCount, device.userConfigWrites
+	device.mutex.Unlock()
+
+	if setPilots < 3 {
+		t.Errorf("Device received %d pilots, want at least %d intermediate steps", setPilots, 3)
+	}
+	if writes != 0 {
+		t.Errorf("Device received %d user configuration writes, want %d", writes, 0)
+	}
+	if pilot.R == nil || *pilot.R != 0 || pilot.B == nil || *pilot.B != 255 {
+		t.Errorf("Device has pilot %v, want the target color", pilot)
+	}
+}
+
+func TestTransitionFirmwareUnsupported(t *testing.T) {
+	device, l := newTransitionDevice(t, nil)
+
+	if err := l.SetColorsWithTransition(context.Background(), 50*time.Millisecond, emission.DCSVector{1, 0, 0, 0, 0}); err != nil {
+		t.Fatalf("SetColorsWithTransition() failed: %v", err)
+	}
+
+	device.mutex.Lock()
+	pilot, setPilots := device.pilot, device.setPilotCount
+	device.mutex.Unlock()
+
+	if setPilots < 3 {
+		t.Errorf("Device received %d pilots, want a software transition with at least %d steps", setPilots, 3)
+	}
+	if !pilot.State || pilot.R == nil || *pilot.R != 255 {
+		t.Errorf("Device has pilot %v, want the target color", pilot)
+	}
+}
+
+func TestTransitionCancel(t *testing.T) {
+	_, l := newTransitionDevice(t, &UserConfig{FadeIn: 10000, FadeOut: 10000})
+
+	for _, values := range [][]emission.Value{
+		{emission.DCSVector{1, 0, 0, 0, 0}}, // Firmware fade from off to on.
+		{emission.DCSVector{0, 1, 0, 0, 0}}, // Software transition.
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		err := l.SetColorsWithTransition(ctx, 10*time.Second, values...)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("SetColorsWithTransition() returned %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("SetColorsWithTransition() returned after %v, want it to return when ctx is done", elapsed)
+		}
+	}
+}
@@ -6,9 +6,8 @@
 package wiz
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net"
 	"time"
 )
 
@@ -59,15 +58,15 @@ type SystemConfig struct {
 	TypeID      uint   `json:"typeId"`
 	HomeLock    bool   `json:"homeLock"`
 	PairingLock bool   `json:"pairingLock"`
-	Ping        uint   `json:"ping"`
+	Ping        uint   `json:"ping"` // Seconds since the device started.
 }
 
 type UserConfig struct {
-	FadeIn     uint `json:"fadeIn"`     // Fade-in time in milliseconds.
-	FadeOut    uint `json:"fadeOut"`    // Fade-out time in milliseconds.
+	FadeIn     uint `json:"fadeIn"`     // Fade-in time in milliseconds. Can be set with Light.SetFade().
+	FadeOut    uint `json:"fadeOut"`    // Fade-out time in milliseconds. Can be set with Light.SetFade().
 	DFTDim     uint `json:"dftDim"`     // Not sure. Default dimming value in percent?
 	OpMode     int  `json:"opMode"`     // No idea.
-	PO         bool `json:"po"`         // No idea.
+	PO         bool `json:"po"`         // Power-on behaviour of plugs, see Plug.SetPowerOnRestore().
 	MinDimming uint `json:"minDimming"` // Minimal dimming value in percent.
 	TapSensor  int  `json:"tapSensor"`  // Not sure. Number of tap sensors?
 }
@@ -103,6 +102,7 @@ const (
 	methodGetPilot        method = "getPilot"
 	methodGetSystemConfig method = "getSystemConfig"
 	methodGetUserConfig   method = "getUserConfig"
+	methodGetPower        method = "getPower"
 	//methodGetWifiConfig   method = "getWifiConfig"
 
 	// Sync stuff.
@@ -168,7 +168,7 @@ func (l *Light) Pulse(delta int, duration time.Duration) error {
 	}
 
 	var r response
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return err
 	}
 
@@ -185,7 +185,7 @@ func (l *Light) Reboot() error {
 	}
 
 	var r response
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return err
 	}
 
@@ -202,29 +202,18 @@ func (l *Light) SetPilot(p Pilot) error {
 	}
 
 	var r response
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return err
 	}
 
 	return r.Check(q.Method)
 }
 
-// GetDeviceInfo queries the bulb for its device info.
-func (l *Light) GetDeviceInfo() (DevInfo, error) {
-	q := query{
-		Method: methodGetDevInfo,
-		Env:    "pro",
-	}
-
-	result := DevInfo{}
-
-	var r response
-	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
-		return DevInfo{}, err
-	}
-
-	return result, r.Check(q.Method) // This may return data in case of an error.
+// GetDeviceInfo queries the device for its device info.
+func (c *connection) GetDeviceInfo() (DevInfo, error) {
+	var result DevInfo
+	err := c.call(methodGetDevInfo, nil, &result)
+	return result, err // This may return data in case of an error.
 }
 
 // GetFavs queries the bulb for its favorites/presets.
@@ -238,7 +227,7 @@ func (l *Light) GetFavs() (Favs, error) {
 
 	var r response
 	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return Favs{}, err
 	}
 
@@ -256,7 +245,7 @@ func (l *Light) GetModelConfig() (ModelConfig, error) {
 
 	var r response
 	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return ModelConfig{}, err
 	}
 
@@ -274,47 +263,25 @@ func (l *Light) GetPilot() (Pilot, error) {
 
 	var r response
 	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return Pilot{}, err
 	}
 
 	return result, r.Check(q.Method) // This may return data in case of an error.
 }
 
-// GetSystemConfig queries the bulb for its system configuration.
-func (l *Light) GetSystemConfig() (SystemConfig, error) {
-	q := query{
-		Method: methodGetSystemConfig,
-		Env:    "pro",
-	}
-
-	result := SystemConfig{}
-
-	var r response
-	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
-		return SystemConfig{}, err
-	}
-
-	return result, r.Check(q.Method) // This may return data in case of an error.
+// GetSystemConfig queries the device for its system configuration.
+func (c *connection) GetSystemConfig() (SystemConfig, error) {
+	var result SystemConfig
+	err := c.call(methodGetSystemConfig, nil, &result)
+	return result, err // This may return data in case of an error.
 }
 
-// GetUserConfig queries the bulb for its user configuration.
-func (l *Light) GetUserConfig() (UserConfig, error) {
-	q := query{
-		Method: methodGetUserConfig,
-		Env:    "pro",
-	}
-
-	result := UserConfig{}
-
-	var r response
-	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
-		return UserConfig{}, err
-	}
-
-	return result, r.Check(q.Method) // This may return data in case of an error.
+// GetUserConfig queries the device for its user configuration.
+func (c *connection) GetUserConfig() (UserConfig, error) {
+	var result UserConfig
+	err := c.call(methodGetUserConfig, nil, &result)
+	return result, err // This may return data in case of an error.
 }
 
 // GetWifiConfig queries the bulb for its user configuration.
@@ -328,76 +295,9 @@ func (l *Light) GetUserConfig() (UserConfig, error) {
 
 	var r response
 	r.Result = &result
-	if err := l.jsonQuery(q, &r); err != nil {
+	if err := l.jsonQuery(context.Background(), q, &r); err != nil {
 		return WifiConfig{}, err
 	}
 
 	return result, r.Check(q.Method) // This may return data in case of an error.
 }*/
-
-// jsonQuery sends the given query structure as JSON, and unmarshals the JSON response into the given structure r.
-func (l *Light) jsonQuery(q query, r interface{}) error {
-	data, err := json.Marshal(q)
-	if err != nil {
-		return err
-	}
-
-	if l.DebugWriter != nil {
-		fmt.Fprintf(l.DebugWriter, "Query %q: %s\n", q.Method, string(data))
-	}
-
-	responseData, err := l.rawQuery(data)
-	if err != nil {
-		return err
-	}
-
-	if l.DebugWriter != nil {
-		fmt.Fprintf(l.DebugWriter, "Response to %q: %s\n", q.Method, string(responseData))
-	}
-
-	if err := json.Unmarshal(responseData, &r); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// rawQuery sends the given data to the light bulb via UDP.
-// The response given by the bulb will be returned as byte slice.
-func (l *Light) rawQuery(data []byte) ([]byte, error) {
-	l.connMutex.Lock()
-	defer l.connMutex.Unlock()
-
-	conn, err := net.Dial("udp", l.address)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
-	// Function that sends the given data, and tries to receive the response packet.
-	sendFunc := func() ([]byte, error) {
-		conn.SetDeadline(time.Now().Add(l.deadline))
-		if _, err := conn.Write(data); err != nil {
-			return nil, err
-		}
-
-		buf := make([]byte, 1024)
-
-		n, err := conn.Read(buf)
-		if err != nil {
-			return nil, err
-		}
-
-		return buf[:n], nil
-	}
-
-	// Try to communicate, at most l.retries + 1 times.
-	for i := uint(0); i <= l.retries; i++ {
-		var res []byte
-		if res, err = sendFunc(); err == nil {
-			return res, err
-		}
-	}
-
-	return nil, err
-}
@@ -0,0 +1,47 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package effects
+
+import (
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Candle returns an effect that simulates the flickering light of a candle on the first module.
+// The luminance is given in lumen.
+func Candle(luminance float64) *Effect {
+	return &Effect{
+		Name: "candle",
+		Keyframes: []Keyframe{
+			{Time: 0, Values: []emission.Value{emission.BlackBodyFixed{Temperature: 1800, Luminance: luminance * 0.8}}},
+			{Time: 1500 * time.Millisecond, Values: []emission.Value{emission.BlackBodyFixed{Temperature: 1950, Luminance: luminance}}},
+			{Time: 2500 * time.Millisecond, Values: []emission.Value{emission.BlackBodyFixed{Temperature: 1850, Luminance: luminance * 0.9}}},
+		},
+		Duration: 4 * time.Second,
+		Loop:     true,
+		Space:    light.InterpolationMired,
+		Easing:   "in-out-sine",
+		Flicker:  Flicker{Amount: 0.4, Interval: 80 * time.Millisecond},
+	}
+}
+
+// Breathe returns an effect that slowly fades the modules between off and the given emission values.
+// A single breath lasts for the given period.
+func Breathe(period time.Duration, values ...emission.Value) *Effect {
+	return &Effect{
+		Name: "breathe",
+		Keyframes: []Keyframe{
+			{Time: 0, Values: values},
+			{Time: period / 2, Values: nil},
+		},
+		Duration: period,
+		Loop:     true,
+		Space:    light.InterpolationCIE1976LAB,
+		Easing:   "in-out-sine",
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package effects implements keyframe based light effects that can be played on any light device.
+package effects
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Easings contains all easing functions that can be referenced by name in an effect.
+// Custom easing functions can be added before any effect is validated or played.
+var Easings = map[string]light.Easing{
+	"linear":      light.EaseLinear,
+	"in-quad":     light.EaseInQuad,
+	"out-quad":    light.EaseOutQuad,
+	"in-out-quad": light.EaseInOutQuad,
+	"in-out-sine": light.EaseInOutSine,
+}
+
+// Keyframe defines the emission values of all modules at a specific point in time of an effect.
+type Keyframe struct {
+	Time   time.Duration    // Offset from the start of the effect.
+	Values []emission.Value // Emission values of every module. Modules without value are turned off.
+}
+
+// Flicker adds random brightness variations to an effect, e.g. to simulate a candle.
+type Flicker struct {
+	Amount   float64       // The maximum reduction of brightness in the range [0, 1]. Zero disables flicker.
+	Interval time.Duration // The time between two random brightness levels. Defaults to 100 ms if zero.
+}
+
+// Effect is a sequence of keyframes.
+// The emission values between two keyframes are interpolated.
+type Effect struct {
+	Name      string
+	Keyframes []Keyframe // Keyframes sorted by time.
+
+	// The length of a single iteration of the effect.
+	// Defaults to the time of the last keyframe if zero.
+	// If the effect loops and the duration is longer than the last keyframe, the last keyframe is interpolated into the first keyframe.
+	Duration time.Duration
+
+	Loop bool // If true, the effect is repeated endlessly.
+
+	Space  light.InterpolationSpace // The color space that is used to interpolate between keyframes.
+	Easing string                   // The name of the easing function in Easings that is applied between two keyframes. Defaults to "linear".
+
+	Flicker Flicker
+}
+
+// Options contains the parameters that are used to play an effect.
+type Options struct {
+	// Speed factor of the effect. 2 plays the effect twice as fast.
+	// Defaults to 1 if zero.
+	Speed float64
+
+	// Brightness factor of the effect in the range [0, 1].
+	// Defaults to 1 if zero.
+	Intensity float64
+
+	// Seed of the random number generator used for flicker.
+	// Lights that play the same effect with the same seed and start time flicker in unison.
+	Seed int64
+
+	// The reference time of the effect.
+	// Several lights that play the same effect with the same start time stay in phase.
+	// Defaults to the time when the effect is started if zero.
+	Start time.Time
+
+	// The time between two updates that are sent to a light device.
+	// Defaults to 50 ms if zero.
+	Interval time.Duration
+}
+
+// withDefaults returns a copy of the options with all zero fields set to their default.
+func (o Options) withDefaults() Options {
+	if o.Speed <= 0 {
+		o.Speed = 1
+	}
+	if o.Intensity <= 0 {
+		o.Intensity = 1
+	}
+	if o.Start.IsZero() {
+		o.Start = time.Now()
+	}
+	if o.Interval <= 0 {
+		o.Interval = 50 * time.Millisecond
+	}
+
+	return o
+}
+
+// Validate returns an error if the effect is not well-formed.
+func (e *Effect) Validate() error {
+	if len(e.Keyframes) == 0 {
+		return fmt.Errorf("effect %q has no keyframes", e.Name)
+	}
+	if !sort.SliceIsSorted(e.Keyframes, func(i, j int) bool { return e.Keyframes[i].Time < e.Keyframes[j].Time }) {
+		return fmt.Errorf("keyframes of effect %q are not sorted by time", e.Name)
+	}
+	if first := e.Keyframes[0].Time; first < 0 {
+		return fmt.Errorf("first keyframe of effect %q has negative time %v", e.Name, first)
+	}
+	if last := e.Keyframes[len(e.Keyframes)-1].Time; e.Duration != 0 && e.Duration < last {
+		return fmt.Errorf("duration %v of effect %q is shorter than the last keyframe at %v", e.Duration, e.Name, last)
+	}
+	if e.Easing != "" {
+		if _, ok := Easings[e.Easing]; !ok {
+			return fmt.Errorf("unknown easing function %q in effect %q", e.Easing, e.Name)
+		}
+	}
+	if e.Flicker.Amount < 0 || e.Flicker.Amount > 1 {
+		return fmt.Errorf("flicker amount %v of effect %q is outside of the range [0, 1]", e.Flicker.Amount, e.Name)
+	}
+
+	return nil
+}
+
+// duration returns the length of a single iteration.
+func (e *Effect) duration() time.Duration {
+	if e.Duration > 0 {
+		return e.Duration
+	}
+	return e.Keyframes[len(e.Keyframes)-1].Time
+}
+
+// Evaluate returns the emission values of all modules at the given time since the start of the effect.
+// The result can be passed directly to SetColors of a light device with the given color profiles.
+//
+// done is true if the effect doesn't loop and the end is reached.
+func (e *Effect) Evaluate(colorProfiles []emission.ColorProfile, elapsed time.Duration, options Options) (values []emission.Value, done bool, err error) {
+	if err := e.Validate(); err != nil {
+		return nil, false, err
+	}
+	options = options.withDefaults()
+
+	position := time.Duration(float64(elapsed) * options.Speed)
+	duration := e.duration()
+
+	if e.Loop && duration > 0 {
+		position %= duration
+		if position < 0 {
+			position += duration
+		}
+	} else if position >= duration {
+		position, done = duration, true
+	}
+
+	// Find the two keyframes around the current position.
+	// If the position is after the last keyframe, the effect loops back to the first one at the end of the duration.
+	next := sort.Search(len(e.Keyframes), func(i int) bool { return e.Keyframes[i].Time > position })
+	var from, to Keyframe
+	var t float64
+	switch {
+	case next == 0:
+		from, to = e.Keyframes[0], e.Keyframes[0]
+	case next == len(e.Keyframes) && e.Loop && duration > e.Keyframes[next-1].Time:
+		from, to = e.Keyframes[next-1], e.Keyframes[0]
+		to.Time = duration
+	case next == len(e.Keyframes):
+		from, to = e.Keyframes[next-1], e.Keyframes[next-1]
+	default:
+		from, to = e.Keyframes[next-1], e.Keyframes[next]
+	}
+	if to.Time > from.Time {
+		t = float64(position-from.Time) / float64(to.Time-from.Time)
+	}
+
+	easing := light.EaseLinear
+	if e.Easing != "" {
+		easing = Easings[e.Easing]
+	}
+
+	if len(from.Values) > len(colorProfiles) || len(to.Values) > len(colorProfiles) {
+		return nil, false, fmt.Errorf("effect %q has more values than the device has modules (%d)", e.Name, len(colorProfiles))
+	}
+
+	values = make([]emission.Value, 0, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		value, err := light.Interpolate(colorProfile, moduleValue(colorProfile, from.Values, i), moduleValue(colorProfile, to.Values, i), easing(t), e.Space)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to interpolate module %d: %w", i, err)
+		}
+
+		// Apply brightness modifications.
+		brightness := options.Intensity
+		if e.Flicker.Amount > 0 {
+			brightness *= 1 - e.Flicker.Amount*e.noise(options.Seed, i, position)
+		}
+		if brightness != 1 {
+			xyz, err := colorProfile.DCSToXYZ(value.IntoDCS(colorProfile))
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to transform value of module %d: %w", i, err)
+			}
+			value = xyz.Scaled(brightness)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, done, nil
+}
+
+// moduleValue returns the emission value of the module with the given index.
+// Modules without value are off.
+func moduleValue(colorProfile emission.ColorProfile, values []emission.Value, index int) emission.Value {
+	if index >= len(values) {
+		return make(emission.DCSVector, colorProfile.Channels())
+	}
+
+	return values[index]
+}
+
+// noise returns a smooth pseudo random value in the range [0, 1] for the given module and position.
+//
+// The result only depends on the parameters, so several lights that play the same effect stay in sync.
+func (e *Effect) noise(seed int64, module int, position time.Duration) float64 {
+	interval := e.Flicker.Interval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	slot := int64(position / interval)
+	t := float64(position%interval) / float64(interval)
+
+	a, b := hashFloat(seed, module, slot), hashFloat(seed, module, slot+1)
+
+	return a + (b-a)*light.EaseInOutSine(t)
+}
+
+// hashFloat returns a pseudo random number in the range [0, 1) that is derived from the given parameters.
+func hashFloat(seed int64, module int, slot int64) float64 {
+	// SplitMix64 finalizer.
+	x := uint64(seed) ^ uint64(module)*0x9e3779b97f4a7c15 ^ uint64(slot)*0xbf58476d1ce4e5b9
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return float64(x>>11) / (1 << 53)
+}
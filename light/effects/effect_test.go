@@ -0,0 +1,197 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package effects
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+var testColorProfiles = []emission.ColorProfile{virtual.DefaultColorProfile}
+
+// dcs returns the given value as DCS vector of the test color profile.
+func dcs(v emission.Value) emission.DCSVector {
+	return v.IntoDCS(testColorProfiles[0])
+}
+
+func vectorsEqual(a, b emission.DCSVector, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEvaluate(t *testing.T) {
+	red, blue := emission.DCSVector{1, 0, 0}, emission.DCSVector{0, 0, 1}
+	effect := &Effect{
+		Keyframes: []Keyframe{
+			{Time: 0, Values: []emission.Value{red}},
+			{Time: time.Second, Values: []emission.Value{blue}},
+		},
+		Duration: 2 * time.Second,
+		Space:    light.InterpolationCIE1931xyY,
+	}
+
+	tests := []struct {
+		loop    bool
+		elapsed time.Duration
+		speed   float64
+		want    emission.DCSVector
+		done    bool
+	}{
+		{false, 0, 1, red, false},
+		{false, time.Second, 1, blue, false},
+		{false, 3 * time.Second, 1, blue, true},
+		{false, 500 * time.Millisecond, 2, blue, false},
+		{true, 2 * time.Second, 1, red, false},
+		{true, 3 * time.Second, 1, blue, false},
+	}
+
+	for i, test := range tests {
+		effect.Loop = test.loop
+		values, done, err := effect.Evaluate(testColorProfiles, test.elapsed, Options{Speed: test.speed})
+		if err != nil {
+			t.Fatalf("Test %d: Evaluate() failed: %v", i, err)
+		}
+		if done != test.done {
+			t.Errorf("Test %d: Evaluate() returned done = %v, want %v", i, done, test.done)
+		}
+		if got := dcs(values[0]); !vectorsEqual(got, test.want, 0.001) {
+			t.Errorf("Test %d: Evaluate() returned %v, want %v", i, got, test.want)
+		}
+	}
+
+	// The loop interpolates from the last keyframe back into the first one.
+	effect.Loop = true
+	values, _, err := effect.Evaluate(testColorProfiles, 1500*time.Millisecond, Options{})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if got := dcs(values[0]); got[0] < 0.01 || got[2] < 0.01 {
+		t.Errorf("Evaluate() returned %v, want a mix of %v and %v", got, red, blue)
+	}
+}
+
+func TestFlicker(t *testing.T) {
+	effect := Candle(200)
+
+	for elapsed := time.Duration(0); elapsed < 2*time.Second; elapsed += 17 * time.Millisecond {
+		a, _, err := effect.Evaluate(testColorProfiles, elapsed, Options{Seed: 1})
+		if err != nil {
+			t.Fatalf("Evaluate() failed: %v", err)
+		}
+		b, _, err := effect.Evaluate(testColorProfiles, elapsed, Options{Seed: 1})
+		if err != nil {
+			t.Fatalf("Evaluate() failed: %v", err)
+		}
+		if !vectorsEqual(dcs(a[0]), dcs(b[0]), 0) {
+			t.Errorf("Flicker at %v is not deterministic: %v != %v", elapsed, a[0], b[0])
+		}
+
+		noise := effect.noise(1, 0, elapsed)
+		if noise < 0 || noise > 1 {
+			t.Errorf("Noise at %v is %v, want it in the range [0, 1]", elapsed, noise)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []*Effect{
+		{},
+		{Keyframes: []Keyframe{{Time: time.Second}, {Time: 0}}},
+		{Keyframes: []Keyframe{{Time: time.Second}}, Duration: time.Millisecond},
+		{Keyframes: []Keyframe{{Time: 0}}, Easing: "unknown"},
+		{Keyframes: []Keyframe{{Time: 0}}, Flicker: Flicker{Amount: 2}},
+	}
+
+	for i, effect := range tests {
+		if err := effect.Validate(); err == nil {
+			t.Errorf("Test %d: Validate() returned no error", i)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	effect := &Effect{
+		Name: "test",
+		Keyframes: []Keyframe{
+			{Time: 0, Values: []emission.Value{emission.BlackBodyFixed{Temperature: 2700, Luminance: 400}, emission.StandardRGB{R: 1, G: 0.5, B: 0}}},
+			{Time: 1500 * time.Millisecond, Values: []emission.Value{emission.CIE1931xyYAbs{X: 0.3, Y: 0.3, LuminanceY: 100}, emission.DCSVector{0, 0.5, 1}}},
+		},
+		Duration: 3 * time.Second,
+		Loop:     true,
+		Space:    light.InterpolationMired,
+		Easing:   "in-out-sine",
+		Flicker:  Flicker{Amount: 0.2, Interval: 50 * time.Millisecond},
+	}
+
+	data, err := json.Marshal(effect)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+
+	loaded, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	data2, err := json.Marshal(loaded)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Errorf("JSON round trip changed the effect:\n%s\n%s", data, data2)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	lights := make([]light.Light, 0, 2)
+	for i := 0; i < 2; i++ {
+		l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+		if err != nil {
+			t.Fatalf("virtual.NewLight() failed: %v", err)
+		}
+		lights = append(lights, l)
+	}
+
+	target := emission.DCSVector{0, 1, 0}
+	effect := &Effect{
+		Keyframes: []Keyframe{
+			{Time: 0, Values: []emission.Value{emission.DCSVector{1, 0, 0}}},
+			{Time: 30 * time.Millisecond, Values: []emission.Value{target}},
+		},
+	}
+
+	if err := Play(context.Background(), effect, Options{Interval: time.Millisecond}, lights...); err != nil {
+		t.Fatalf("Play() failed: %v", err)
+	}
+
+	for i, l := range lights {
+		if got := l.(*virtual.Light).Vectors()[0]; !vectorsEqual(got, target, 0.001) {
+			t.Errorf("Light %d ended at %v, want %v", i, got, target)
+		}
+	}
+
+	// Looping effects run until they are cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Play(ctx, Breathe(time.Second, target), Options{}, lights...); err != context.DeadlineExceeded {
+		t.Errorf("Play() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package effects
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// jsonEffect is the JSON representation of an effect.
+type jsonEffect struct {
+	Name      string                   `json:"name,omitempty"`
+	Loop      bool                     `json:"loop,omitempty"`
+	Duration  string                   `json:"duration,omitempty"`
+	Space     light.InterpolationSpace `json:"space"`
+	Easing    string                   `json:"easing,omitempty"`
+	Flicker   *jsonFlicker             `json:"flicker,omitempty"`
+	Keyframes []jsonKeyframe           `json:"keyframes"`
+}
+
+type jsonFlicker struct {
+	Amount   float64 `json:"amount"`
+	Interval string  `json:"interval,omitempty"`
+}
+
+type jsonKeyframe struct {
+	Time   string                 `json:"time"`
+	Values []emission.TaggedValue `json:"values"`
+}
+
+// MarshalJSON implements the JSON marshaler interface.
+func (e Effect) MarshalJSON() ([]byte, error) {
+	j := jsonEffect{
+		Name:      e.Name,
+		Loop:      e.Loop,
+		Space:     e.Space,
+		Easing:    e.Easing,
+		Keyframes: make([]jsonKeyframe, 0, len(e.Keyframes)),
+	}
+	if e.Duration != 0 {
+		j.Duration = e.Duration.String()
+	}
+	if e.Flicker.Amount != 0 {
+		j.Flicker = &jsonFlicker{Amount: e.Flicker.Amount}
+		if e.Flicker.Interval != 0 {
+			j.Flicker.Interval = e.Flicker.Interval.String()
+		}
+	}
+
+	for _, keyframe := range e.Keyframes {
+		jKeyframe := jsonKeyframe{Time: keyframe.Time.String(), Values: make([]emission.TaggedValue, 0, len(keyframe.Values))}
+		for _, value := range keyframe.Values {
+			jKeyframe.Values = append(jKeyframe.Values, emission.TaggedValue{Value: value})
+		}
+		j.Keyframes = append(j.Keyframes, jKeyframe)
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the JSON unmarshaler interface.
+func (e *Effect) UnmarshalJSON(data []byte) error {
+	var j jsonEffect
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	parseDuration := func(s string) (time.Duration, error) {
+		if s == "" {
+			return 0, nil
+		}
+		return time.ParseDuration(s)
+	}
+
+	result := Effect{
+		Name:      j.Name,
+		Loop:      j.Loop,
+		Space:     j.Space,
+		Easing:    j.Easing,
+		Keyframes: make([]Keyframe, 0, len(j.Keyframes)),
+	}
+
+	var err error
+	if result.Duration, err = parseDuration(j.Duration); err != nil {
+		return fmt.Errorf("failed to parse duration: %w", err)
+	}
+	if j.Flicker != nil {
+		result.Flicker.Amount = j.Flicker.Amount
+		if result.Flicker.Interval, err = parseDuration(j.Flicker.Interval); err != nil {
+			return fmt.Errorf("failed to parse flicker interval: %w", err)
+		}
+	}
+
+	for i, jKeyframe := range j.Keyframes {
+		keyframe := Keyframe{Values: make([]emission.Value, 0, len(jKeyframe.Values))}
+		if keyframe.Time, err = parseDuration(jKeyframe.Time); err != nil {
+			return fmt.Errorf("failed to parse time of keyframe %d: %w", i, err)
+		}
+		for _, jValue := range jKeyframe.Values {
+			if jValue.Value == nil {
+				return fmt.Errorf("keyframe %d contains a null value", i)
+			}
+			keyframe.Values = append(keyframe.Values, jValue.Value)
+		}
+		result.Keyframes = append(result.Keyframes, keyframe)
+	}
+
+	*e = result
+	return nil
+}
+
+// Load reads a single effect in JSON format from r.
+// The effect is validated before it is returned.
+func Load(r io.Reader) (*Effect, error) {
+	var e Effect
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, fmt.Errorf("failed to decode effect: %w", err)
+	}
+
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// LoadFile reads a single effect in JSON format from the file at the given path.
+func LoadFile(path string) (*Effect, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package effects
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+)
+
+// Play plays the effect on all the given light devices.
+// All lights use the same start time, so they stay in phase.
+//
+// This blocks until the effect is done, in which case nil is returned.
+// Looping effects only stop when ctx is cancelled, in which case ctx.Err() is returned.
+// If setting the colors of any light fails, the effect is stopped on all lights and the error is returned.
+func Play(ctx context.Context, effect *Effect, options Options, lights ...light.Light) error {
+	if err := effect.Validate(); err != nil {
+		return err
+	}
+	options = options.withDefaults()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l light.Light) {
+			defer wg.Done()
+
+			if err := play(ctx, effect, options, l); err != nil {
+				errOnce.Do(func() {
+					if err != ctx.Err() {
+						err = fmt.Errorf("failed to play effect %q on light %d: %w", effect.Name, i, err)
+					}
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, l)
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// play plays the effect on a single light device.
+func play(ctx context.Context, effect *Effect, options Options, l light.Light) error {
+	colorProfiles := l.ColorProfiles()
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		values, done, err := effect.Evaluate(colorProfiles, time.Since(options.Start), options)
+		if err != nil {
+			return err
+		}
+		if err := l.SetColors(values...); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -13,12 +13,17 @@ import "math"
 //
 // The valid temperature range is 1667K to 25000K.
 type BlackBodyFixed struct {
-	Temperature float64 // Temperature in K.
-	Luminance   float64 // Luminance in lumen.
+	Temperature float64 `json:"temperature"` // Temperature in K.
+	Luminance   float64 `json:"luminance"`   // Luminance in lumen.
 }
 
 var _ Value = &BlackBodyFixed{} // TODO: Implement transformation from DCS
 
+// String returns the value in a form that can be parsed by ParseValue.
+func (b BlackBodyFixed) String() string {
+	return formatFloat(b.Temperature) + "K@" + formatFloat(b.Luminance) + "lm"
+}
+
 // IntoDCS implements the Value interface.
 func (b BlackBodyFixed) IntoDCS(cp ColorProfile) DCSVector {
 	return cp.XYZToDCS(b.CIE1931XYZAbs())
@@ -68,8 +73,8 @@ func (b BlackBodyFixed) CIE1931XYZAbs() CIE1931XYZAbs {
 //
 // This doesn't return a color of a daylight temperature.
 type BlackBodyArea struct {
-	Temperature float64 // Temperature in K.
-	Area        float64 // Area in m².
+	Temperature float64 `json:"temperature"` // Temperature in K.
+	Area        float64 `json:"area"`        // Area in m².
 }
 
 var _ Value = &BlackBodyArea{} // TODO: Implement transformation from DCS
@@ -219,8 +219,9 @@ func (c CIE1931XYZRel) CIE1976LAB(whitePoint CIE1931XYZRel) CIE1976LAB {
 //
 // An equal-energy radiator would result in x == y == 1/3.
 type CIE1931xyYAbs struct {
-	X, Y       float64 // x, y in the range of [0, 1]
-	LuminanceY float64 // Luminance Y in lumen.
+	X          float64 `json:"x"`         // x in the range of [0, 1]
+	Y          float64 `json:"y"`         // y in the range of [0, 1]
+	LuminanceY float64 `json:"luminance"` // Luminance Y in lumen.
 }
 
 var (
@@ -228,6 +229,11 @@ var (
 	_ ValueReceiver = &CIE1931xyYAbs{}
 )
 
+// String returns the color in a form that can be parsed by ParseValue.
+func (c CIE1931xyYAbs) String() string {
+	return "xyY(" + formatFloats(c.X, c.Y, c.LuminanceY) + ")"
+}
+
 // IntoDCS implements the Value interface.
 func (c CIE1931xyYAbs) IntoDCS(cp ColorProfile) DCSVector {
 	return cp.XYZToDCS(c.CIE1931XYZAbs())
@@ -274,8 +280,9 @@ func (c CIE1931xyYAbs) Scaled(s float64) CIE1931xyYAbs {
 //
 // An equal-energy radiator would result in x == y == 1/3.
 type CIE1931xyYRel struct {
-	X, Y       float64 // x, y in the range of [0, 1]
-	LuminanceY float64 // Relative luminance Y in the range of [0, 1].
+	X          float64 `json:"x"`         // x in the range of [0, 1]
+	Y          float64 `json:"y"`         // y in the range of [0, 1]
+	LuminanceY float64 `json:"luminance"` // Relative luminance Y in the range of [0, 1].
 }
 
 var (
@@ -283,6 +290,11 @@ var (
 	_ ValueReceiver = &CIE1931xyYRel{}
 )
 
+// String returns the color in a form that can be parsed by ParseValue.
+func (c CIE1931xyYRel) String() string {
+	return "xyY(" + formatFloats(c.X, c.Y) + ", " + formatFloat(c.LuminanceY*100) + "%)"
+}
+
 // IntoDCS implements the Value interface.
 func (c CIE1931xyYRel) IntoDCS(cp ColorProfile) DCSVector {
 	maxLuminance := cp.WhitePoint().Y
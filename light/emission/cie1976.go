@@ -9,11 +9,11 @@ import "math"
 
 // CIE1976LAB represents a color in the L*a*b* color space defined by the CIE in 1976.
 type CIE1976LAB struct {
-	L float64 // Perceptual lightness L* in the range of [0, 100].
-	A float64 // Redness a*.
-	B float64 // Blueness b*.
+	L float64 `json:"l"` // Perceptual lightness L* in the range of [0, 100].
+	A float64 `json:"a"` // Redness a*.
+	B float64 `json:"b"` // Blueness b*.
 
-	WhitePoint CIE1931XYZRel // White point in CIE 1931 XYZ coordinates with relative luminance.
+	WhitePoint CIE1931XYZRel `json:"whitePoint"` // White point in CIE 1931 XYZ coordinates with relative luminance.
 }
 
 var (
@@ -21,6 +21,19 @@ var (
 	_ ValueReceiver = &CIE1976LAB{}
 )
 
+// String returns the color in a form that can be parsed by ParseValue.
+// The white point is omitted if it is D50, and written by name if it is any other standard illuminant.
+func (c CIE1976LAB) String() string {
+	switch name := standardIlluminantName(c.WhitePoint); name {
+	case "D50":
+		return "lab(" + formatFloats(c.L, c.A, c.B) + ")"
+	case "":
+		return "lab(" + formatFloats(c.L, c.A, c.B, c.WhitePoint.X, c.WhitePoint.Y, c.WhitePoint.Z) + ")"
+	default:
+		return "lab(" + formatFloats(c.L, c.A, c.B) + ", " + name + ")"
+	}
+}
+
 // IntoDCS implements the Value interface.
 func (c CIE1976LAB) IntoDCS(cp ColorProfile) DCSVector {
 	return c.CIE1931XYZRel().IntoDCS(cp)
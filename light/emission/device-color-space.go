@@ -24,6 +24,11 @@ var (
 	_ ValueReceiver = &DCSVector{}
 )
 
+// String returns the vector in a form that can be parsed by ParseValue.
+func (v DCSVector) String() string {
+	return "dcs(" + formatFloats(v...) + ")"
+}
+
 // Copy returns a copy of v.
 func (v DCSVector) Copy() DCSVector {
 	vCopy := make(DCSVector, v.Channels())
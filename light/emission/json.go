@@ -0,0 +1,223 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package emission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// valueTypes contains all emission value types that can be marshaled with MarshalValue.
+var valueTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: map[string]reflect.Type{},
+	byType: map[reflect.Type]string{},
+}
+
+func init() {
+	RegisterValueType("xyz", CIE1931XYZAbs{})
+	RegisterValueType("xyzRel", CIE1931XYZRel{})
+	RegisterValueType("xyY", CIE1931xyYAbs{})
+	RegisterValueType("xyYRel", CIE1931xyYRel{})
+	RegisterValueType("lab", CIE1976LAB{})
+	RegisterValueType("srgb", StandardRGB{})
+	RegisterValueType("blackbody", BlackBodyFixed{})
+	RegisterValueType("blackbodyArea", BlackBodyArea{})
+	RegisterValueType("daylight", StandardIlluminantDSeries{})
+	RegisterValueType("dcs", DCSVector{})
+	RegisterValueType("linDCS", LinDCSVector{})
+	RegisterValueType("noWhiteOptimization", NoWhiteOptimization{})
+}
+
+// RegisterValueType makes the type of the given emission value available to MarshalValue and UnmarshalValue under the given name.
+// The type must be able to be marshaled with encoding/json.
+//
+// This panics if the name or the type is already registered.
+func RegisterValueType(name string, prototype Value) {
+	t := reflect.TypeOf(prototype)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	valueTypes.Lock()
+	defer valueTypes.Unlock()
+
+	if _, ok := valueTypes.byName[name]; ok {
+		panic(fmt.Sprintf("emission value type %q is already registered", name))
+	}
+	if existing, ok := valueTypes.byType[t]; ok {
+		panic(fmt.Sprintf("emission value type %v is already registered as %q", t, existing))
+	}
+
+	valueTypes.byName[name], valueTypes.byType[t] = t, name
+}
+
+// MarshalValue returns the JSON encoding of the given emission value, tagged with its type name:
+//
+//	{"type":"blackbody","temperature":2700,"luminance":400}
+//
+// Values that are not encoded as JSON objects, like DCS vectors, are stored in the value field:
+//
+//	{"type":"dcs","value":[0.1,0.2,1,0,0]}
+//
+// The type of the value has to be registered with RegisterValueType.
+func MarshalValue(v Value) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("can't marshal nil emission value")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	valueTypes.RLock()
+	name, ok := valueTypes.byType[t]
+	valueTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("emission value type %T is not registered", v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	nameData, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	buf.Write(nameData)
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		// Merge the fields of the object.
+		rest := bytes.TrimSpace(trimmed[1:])
+		if len(rest) > 0 && rest[0] != '}' {
+			buf.WriteByte(',')
+		}
+		buf.Write(rest)
+	} else {
+		buf.WriteString(`,"value":`)
+		buf.Write(trimmed)
+		buf.WriteByte('}')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalValue returns the emission value of the given JSON encoding, as created by MarshalValue.
+// The result is of the registered type, not a pointer to it.
+//
+// Unknown fields are rejected, so that typos in config files don't result in zero values.
+func UnmarshalValue(data []byte) (Value, error) {
+	var tagged struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &tagged); err != nil {
+		return nil, err
+	}
+
+	valueTypes.RLock()
+	t, ok := valueTypes.byName[tagged.Type]
+	valueTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown emission value type %q", tagged.Type)
+	}
+
+	ptr := reflect.New(t)
+	if t.Kind() == reflect.Struct {
+		// Remove the type tag, as it's not a field of the value.
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		delete(fields, "type")
+		fieldsData, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		if err := unmarshalStrict(fieldsData, ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %q emission value: %w", tagged.Type, err)
+		}
+	} else {
+		// Check that there are no other fields than the type and the value.
+		if err := unmarshalStrict(data, &tagged); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %q emission value: %w", tagged.Type, err)
+		}
+		if tagged.Value != nil {
+			if err := unmarshalStrict(tagged.Value, ptr.Interface()); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %q emission value: %w", tagged.Type, err)
+			}
+		}
+	}
+
+	value, ok := ptr.Elem().Interface().(Value)
+	if !ok {
+		return nil, fmt.Errorf("registered type %v doesn't implement the Value interface", t)
+	}
+
+	return value, nil
+}
+
+// unmarshalStrict works like json.Unmarshal, but returns an error for object keys that don't match any field of v.
+func unmarshalStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+// TaggedValue wraps an emission value so that it is marshaled with MarshalValue and unmarshaled with UnmarshalValue.
+// This can be used as field type in structs that contain emission values of any type.
+//
+// When unmarshaling, a JSON string is parsed with ParseValue, so config files can also contain colors like "#ff8800" or "2700K@400lm".
+type TaggedValue struct {
+	Value
+}
+
+// MarshalJSON implements the JSON marshaler interface.
+func (t TaggedValue) MarshalJSON() ([]byte, error) {
+	if t.Value == nil {
+		return []byte("null"), nil
+	}
+
+	return MarshalValue(t.Value)
+}
+
+// UnmarshalJSON implements the JSON unmarshaler interface.
+func (t *TaggedValue) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		t.Value = nil
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		value, err := ParseValue(str)
+		if err != nil {
+			return err
+		}
+		t.Value = value
+		return nil
+	}
+
+	value, err := UnmarshalValue(data)
+	if err != nil {
+		return err
+	}
+	t.Value = value
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package emission
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalValue(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{BlackBodyFixed{Temperature: 2700, Luminance: 400}, `{"type":"blackbody","temperature":2700,"luminance":400}`},
+		{CIE1931xyYAbs{X: 0.31, Y: 0.33, LuminanceY: 200}, `{"type":"xyY","x":0.31,"y":0.33,"luminance":200}`},
+		{StandardRGB{R: 1, G: 0.5, B: 0}, `{"type":"srgb","r":1,"g":0.5,"b":0}`},
+		{CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: CIE1931XYZRel{X: 1, Y: 1, Z: 1}}, `{"type":"lab","l":50,"a":20,"b":-10,"whitePoint":{"X":1,"Y":1,"Z":1}}`},
+		{DCSVector{0.1, 0.2, 1, 0, 0}, `{"type":"dcs","value":[0.1,0.2,1,0,0]}`},
+		{NoWhiteOptimization{StandardRGB{R: 1}}, `{"type":"noWhiteOptimization","value":{"type":"srgb","r":1,"g":0,"b":0}}`},
+		{&CIE1931XYZAbs{X: 1, Y: 2, Z: 3}, `{"type":"xyz","X":1,"Y":2,"Z":3}`},
+	}
+
+	for _, test := range tests {
+		data, err := MarshalValue(test.value)
+		if err != nil {
+			t.Errorf("MarshalValue(%v) failed: %v", test.value, err)
+			continue
+		}
+		if string(data) != test.want {
+			t.Errorf("MarshalValue(%v) returned %s, want %s", test.value, data, test.want)
+		}
+	}
+}
+
+func TestUnmarshalValue(t *testing.T) {
+	// One value of every registered type.
+	values := map[string]Value{
+		"xyz":                 CIE1931XYZAbs{X: 1, Y: 2, Z: 3},
+		"xyzRel":              CIE1931XYZRel{X: 0.1, Y: 0.2, Z: 0.3},
+		"xyY":                 CIE1931xyYAbs{X: 0.31, Y: 0.33, LuminanceY: 200},
+		"xyYRel":              CIE1931xyYRel{X: 0.31, Y: 0.33, LuminanceY: 0.5},
+		"lab":                 CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: StandardIlluminantD65},
+		"srgb":                StandardRGB{R: 1, G: 0.5, B: 0},
+		"blackbody":           BlackBodyFixed{Temperature: 2700, Luminance: 400},
+		"blackbodyArea":       BlackBodyArea{Temperature: 2700, Area: 0.001},
+		"daylight":            StandardIlluminantDSeries{Temperature: 6500, Luminance: 400},
+		"dcs":                 DCSVector{0.1, 0.2, 1, 0, 0},
+		"linDCS":              LinDCSVector{0.1, 0.2, 1},
+		"noWhiteOptimization": NoWhiteOptimization{BlackBodyFixed{Temperature: 2700, Luminance: 400}},
+		"custom":              customValue{Level: 0.5},
+	}
+
+	valueTypes.RLock()
+	for name := range valueTypes.byName {
+		if _, ok := values[name]; !ok {
+			t.Errorf("Registered type %q has no round trip test", name)
+		}
+	}
+	valueTypes.RUnlock()
+
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalValue(value)
+			if err != nil {
+				t.Fatalf("MarshalValue(%v) failed: %v", value, err)
+			}
+			got, err := UnmarshalValue(data)
+			if err != nil {
+				t.Fatalf("UnmarshalValue(%s) failed: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, value) {
+				t.Errorf("UnmarshalValue(%s) returned %#v, want %#v", data, got, value)
+			}
+		})
+	}
+
+	if _, err := UnmarshalValue([]byte(`{"type":"unknown"}`)); err == nil {
+		t.Errorf("UnmarshalValue() of an unknown type returned no error")
+	}
+
+	// Unknown fields, like typos, are rejected.
+	for _, data := range []string{
+		`{"type":"xyY","x":0.31,"y":0.33,"luminanceY":200}`,
+		`{"type":"blackbody","temperature":2700,"lumen":400}`,
+		`{"type":"dcs","value":[1,0,0],"channels":3}`,
+		`{"type":"noWhiteOptimization","value":{"type":"srgb","r":1,"green":1}}`,
+	} {
+		if value, err := UnmarshalValue([]byte(data)); err == nil {
+			t.Errorf("UnmarshalValue(%s) returned %#v, want error", data, value)
+		}
+	}
+}
+
+// customValue is an emission value that is not part of this package.
+type customValue struct {
+	Level float64 `json:"level"`
+}
+
+func (c customValue) IntoDCS(cp ColorProfile) DCSVector {
+	return StandardIlluminantE.Scaled(c.Level).IntoDCS(cp)
+}
+
+func init() {
+	RegisterValueType("custom", customValue{})
+}
+
+func TestTaggedValue(t *testing.T) {
+	type config struct {
+		Colors []TaggedValue `json:"colors"`
+	}
+	c := config{Colors: []TaggedValue{{customValue{Level: 0.5}}, {BlackBodyFixed{Temperature: 2700, Luminance: 400}}, {}}}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+
+	var got config
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("JSON round trip returned %#v, want %#v", got, c)
+	}
+}
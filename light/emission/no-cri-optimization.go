@@ -5,6 +5,8 @@
 
 package emission
 
+import "encoding/json"
+
 // NoWhiteOptimization can be used to wrap any emission value to disable any white emitter optimization later in the color management pipeline.
 //
 // Wrapping such a value will cause the emission value to be only constructed out of primary color emitters (e.g. red, green, blue), and no white emitters.
@@ -25,3 +27,23 @@ func (n NoWhiteOptimization) IntoDCS(cp ColorProfile) DCSVector {
 
 	return n.EmissionValue.IntoDCS(nCP)
 }
+
+// MarshalJSON implements the JSON marshaler interface.
+func (n NoWhiteOptimization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		EmissionValue TaggedValue `json:"value"`
+	}{TaggedValue{n.EmissionValue}})
+}
+
+// UnmarshalJSON implements the JSON unmarshaler interface.
+func (n *NoWhiteOptimization) UnmarshalJSON(data []byte) error {
+	var j struct {
+		EmissionValue TaggedValue `json:"value"`
+	}
+	if err := unmarshalStrict(data, &j); err != nil {
+		return err
+	}
+	n.EmissionValue = j.EmissionValue.Value
+
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package emission
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// standardIlluminants contains the standard illuminants that can be referenced by name in ParseValue.
+var standardIlluminants = map[string]CIE1931XYZRel{
+	"a":   StandardIlluminantA,
+	"b":   StandardIlluminantB,
+	"c":   StandardIlluminantC,
+	"d50": StandardIlluminantD50,
+	"d55": StandardIlluminantD55,
+	"d65": StandardIlluminantD65,
+	"d75": StandardIlluminantD75,
+	"d93": StandardIlluminantD93,
+	"e":   StandardIlluminantE,
+}
+
+// ParseValue parses a human readable color string.
+// The following formats are supported, case-insensitive:
+//
+//	#ff8800, #f80                 -> StandardRGB
+//	rgb(255, 136, 0)              -> StandardRGB, channels in the range of [0, 255]
+//	orange                        -> StandardRGB, any CSS named color
+//	2700K@400lm                   -> BlackBodyFixed
+//	D65@400lm                     -> CIE1931xyYAbs, any standard illuminant (A, B, C, D50, D55, D65, D75, D93, E)
+//	D65@50%                       -> CIE1931xyYRel
+//	xyY(0.31, 0.33, 200)          -> CIE1931xyYAbs, luminance in lumen
+//	xyY(0.31, 0.33, 50%)          -> CIE1931xyYRel
+//	lab(50, 20, -10)              -> CIE1976LAB, relative to D50
+//	lab(50, 20, -10, D65)         -> CIE1976LAB, relative to any standard illuminant
+//	lab(50, 20, -10, 0.9, 1, 1.1) -> CIE1976LAB, relative to the white point in CIE 1931 XYZ with relative luminance
+//	dcs(0.1, 0.2, 1, 0, 0)        -> DCSVector
+//
+// The String methods of the returned types format them back in the same grammar.
+func ParseValue(s string) (Value, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+
+	if strings.HasPrefix(str, "#") {
+		return parseHexColor(str[1:])
+	}
+
+	if name, args, ok := parseFunction(str); ok {
+		switch name {
+		case "rgb":
+			v, err := parseNumbers(args, 3)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			return StandardRGB{R: v[0] / 255, G: v[1] / 255, B: v[2] / 255}, nil
+
+		case "xyy":
+			if len(args) != 3 {
+				return nil, fmt.Errorf("invalid color %q: got %d arguments, want 3", s, len(args))
+			}
+			v, err := parseNumbers(args[:2], 2)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			luminance, relative, err := parseLuminance(args[2], "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			if relative {
+				return CIE1931xyYRel{X: v[0], Y: v[1], LuminanceY: luminance}, nil
+			}
+			return CIE1931xyYAbs{X: v[0], Y: v[1], LuminanceY: luminance}, nil
+
+		case "lab":
+			if len(args) != 3 && len(args) != 4 && len(args) != 6 {
+				return nil, fmt.Errorf("invalid color %q: got %d arguments, want 3, 4 or 6", s, len(args))
+			}
+			v, err := parseNumbers(args[:3], 3)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			whitePoint := StandardIlluminantD50
+			switch len(args) {
+			case 4:
+				illuminant, ok := standardIlluminants[args[3]]
+				if !ok {
+					return nil, fmt.Errorf("invalid color %q: unknown illuminant %q", s, args[3])
+				}
+				whitePoint = illuminant
+			case 6:
+				w, err := parseNumbers(args[3:], 3)
+				if err != nil {
+					return nil, fmt.Errorf("invalid color %q: %w", s, err)
+				}
+				whitePoint = CIE1931XYZRel{X: w[0], Y: w[1], Z: w[2]}
+			}
+			return CIE1976LAB{L: v[0], A: v[1], B: v[2], WhitePoint: whitePoint}, nil
+
+		case "dcs":
+			v, err := parseNumbers(args, len(args))
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			return DCSVector(v), nil
+		}
+
+		return nil, fmt.Errorf("invalid color %q: unknown function %q", s, name)
+	}
+
+	if i := strings.Index(str, "@"); i >= 0 {
+		base, luminanceStr := strings.TrimSpace(str[:i]), strings.TrimSpace(str[i+1:])
+
+		// Black body radiator.
+		if strings.HasSuffix(base, "k") {
+			temperature, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(base, "k")), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: invalid temperature: %w", s, err)
+			}
+			luminance, _, err := parseLuminance(luminanceStr, "lm")
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			return BlackBodyFixed{Temperature: temperature, Luminance: luminance}, nil
+		}
+
+		// Standard illuminant.
+		if illuminant, ok := standardIlluminants[base]; ok {
+			luminance, relative, err := parseLuminance(luminanceStr, "lm")
+			if err != nil {
+				return nil, fmt.Errorf("invalid color %q: %w", s, err)
+			}
+			xyY := illuminant.CIE1931xyYRel()
+			if relative {
+				return CIE1931xyYRel{X: xyY.X, Y: xyY.Y, LuminanceY: luminance}, nil
+			}
+			return CIE1931xyYAbs{X: xyY.X, Y: xyY.Y, LuminanceY: luminance}, nil
+		}
+
+		return nil, fmt.Errorf("invalid color %q: unknown temperature or illuminant %q", s, base)
+	}
+
+	if rgb, ok := cssColors[str]; ok {
+		return standardRGBFromUint32(rgb), nil
+	}
+
+	return nil, fmt.Errorf("invalid color %q", s)
+}
+
+// standardIlluminantName returns the upper case name of the given standard illuminant.
+// This returns an empty string if whitePoint is not one of the standard illuminants.
+func standardIlluminantName(whitePoint CIE1931XYZRel) string {
+	for name, illuminant := range standardIlluminants {
+		if illuminant == whitePoint {
+			return strings.ToUpper(name)
+		}
+	}
+
+	return ""
+}
+
+// parseHexColor parses the hexadecimal part of a color in the form of ff8800 or f80.
+func parseHexColor(hex string) (StandardRGB, error) {
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return StandardRGB{}, fmt.Errorf("invalid hex color %q: want 3 or 6 digits", "#"+hex)
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return StandardRGB{}, fmt.Errorf("invalid hex color %q: %w", "#"+hex, err)
+	}
+
+	return standardRGBFromUint32(uint32(rgb)), nil
+}
+
+// standardRGBFromUint32 returns the color of a 24 bit value in the form of 0xRRGGBB.
+func standardRGBFromUint32(rgb uint32) StandardRGB {
+	return StandardRGB{
+		R: float64(rgb>>16&0xff) / 255,
+		G: float64(rgb>>8&0xff) / 255,
+		B: float64(rgb&0xff) / 255,
+	}
+}
+
+// parseFunction splits a string in the form of name(a, b, c) into its name and arguments.
+func parseFunction(s string) (name string, args []string, ok bool) {
+	open := strings.Index(s, "(")
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return "", nil, false
+	}
+
+	name = strings.TrimSpace(s[:open])
+	for _, arg := range strings.Split(s[open+1:len(s)-1], ",") {
+		args = append(args, strings.TrimSpace(arg))
+	}
+
+	return name, args, true
+}
+
+// parseNumbers parses the given strings into floats.
+// It returns an error if the number of strings is not n.
+func parseNumbers(args []string, n int) ([]float64, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("got %d arguments, want %d", len(args), n)
+	}
+
+	result := make([]float64, 0, len(args))
+	for _, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+// parseLuminance parses an absolute luminance with the given optional unit, or a relative luminance in percent.
+// Relative luminances are returned in the range of [0, 1].
+func parseLuminance(s, unit string) (luminance float64, relative bool, err error) {
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid relative luminance: %w", err)
+		}
+		return v / 100, true, nil
+	}
+
+	if unit != "" {
+		if !strings.HasSuffix(s, unit) {
+			return 0, false, fmt.Errorf("luminance %q needs the unit %q or %%", s, unit)
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(s, unit))
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid luminance: %w", err)
+	}
+
+	return v, false, nil
+}
+
+// formatFloat returns the shortest representation of v.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatFloats returns the shortest representations of all values, separated by commas.
+func formatFloats(values ...float64) string {
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		strs = append(strs, formatFloat(v))
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package emission
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	d65 := StandardIlluminantD65.CIE1931xyYRel()
+
+	tests := []struct {
+		input string
+		want  Value
+	}{
+		{"#ff8800", StandardRGB{R: 1, G: 136.0 / 255, B: 0}},
+		{"#F80", StandardRGB{R: 1, G: 136.0 / 255, B: 0}},
+		{"rgb(255,136,0)", StandardRGB{R: 1, G: 136.0 / 255, B: 0}},
+		{" RGB( 255 , 136 , 0 ) ", StandardRGB{R: 1, G: 136.0 / 255, B: 0}},
+		{"orange", StandardRGB{R: 1, G: 165.0 / 255, B: 0}},
+		{"RebeccaPurple", StandardRGB{R: 102.0 / 255, G: 51.0 / 255, B: 153.0 / 255}},
+		{"2700K@400lm", BlackBodyFixed{Temperature: 2700, Luminance: 400}},
+		{"2700k @ 400 lm", BlackBodyFixed{Temperature: 2700, Luminance: 400}},
+		{"D65@50%", CIE1931xyYRel{X: d65.X, Y: d65.Y, LuminanceY: 0.5}},
+		{"D65@400lm", CIE1931xyYAbs{X: d65.X, Y: d65.Y, LuminanceY: 400}},
+		{"xyY(0.31,0.33,200)", CIE1931xyYAbs{X: 0.31, Y: 0.33, LuminanceY: 200}},
+		{"xyY(0.31,0.33,50%)", CIE1931xyYRel{X: 0.31, Y: 0.33, LuminanceY: 0.5}},
+		{"lab(50,20,-10)", CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: StandardIlluminantD50}},
+		{"lab(50,20,-10,D65)", CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: StandardIlluminantD65}},
+		{"lab(50,20,-10,0.9,1,1.1)", CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: CIE1931XYZRel{X: 0.9, Y: 1, Z: 1.1}}},
+		{"dcs(0.1,0.2,1,0,0)", DCSVector{0.1, 0.2, 1, 0, 0}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseValue(test.input)
+		if err != nil {
+			t.Errorf("ParseValue(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseValue(%q) returned %#v, want %#v", test.input, got, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "#12345", "#gggggg", "rgb(1,2)", "hsl(1,2,3)", "2700K@400", "F1@50%", "notacolor", "xyY(0.3,0.3,abc)", "lab(50,20,-10,F1)", "lab(50,20,-10,1,1)"} {
+		if _, err := ParseValue(input); err == nil {
+			t.Errorf("ParseValue(%q) returned no error", input)
+		}
+	}
+}
+
+func TestValueString(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{StandardRGB{R: 1, G: 136.0 / 255, B: 0}, "#ff8800"},
+		{StandardRGB{R: 1, G: 0.5, B: 0}, "rgb(255, 127.5, 0)"},
+		{BlackBodyFixed{Temperature: 2700, Luminance: 400}, "2700K@400lm"},
+		{CIE1931xyYAbs{X: 0.31, Y: 0.33, LuminanceY: 200}, "xyY(0.31, 0.33, 200)"},
+		{CIE1931xyYRel{X: 0.31, Y: 0.33, LuminanceY: 0.5}, "xyY(0.31, 0.33, 50%)"},
+		{CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: StandardIlluminantD50}, "lab(50, 20, -10)"},
+		{CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: StandardIlluminantD65}, "lab(50, 20, -10, D65)"},
+		{CIE1976LAB{L: 50, A: 20, B: -10, WhitePoint: CIE1931XYZRel{X: 0.9, Y: 1, Z: 1.1}}, "lab(50, 20, -10, 0.9, 1, 1.1)"},
+		{DCSVector{0.1, 0.2, 1, 0, 0}, "dcs(0.1, 0.2, 1, 0, 0)"},
+	}
+
+	for _, test := range tests {
+		got := fmt.Sprint(test.value)
+		if got != test.want {
+			t.Errorf("String() returned %q, want %q", got, test.want)
+			continue
+		}
+
+		// Parsing the result must return the original value.
+		parsed, err := ParseValue(got)
+		if err != nil {
+			t.Errorf("ParseValue(%q) failed: %v", got, err)
+			continue
+		}
+		if !valuesAlmostEqual(parsed, test.value) {
+			t.Errorf("ParseValue(%q) returned %#v, want %#v", got, parsed, test.value)
+		}
+	}
+}
+
+// valuesAlmostEqual compares two values of the same type field by field, with some tolerance for rounding errors.
+func valuesAlmostEqual(a, b Value) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+
+	switch va.Kind() {
+	case reflect.Slice:
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if math.Abs(va.Index(i).Float()-vb.Index(i).Float()) > 1e-9 {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < va.NumField(); i++ {
+			if va.Field(i).Kind() != reflect.Float64 {
+				continue
+			}
+			if math.Abs(va.Field(i).Float()-vb.Field(i).Float()) > 1e-9 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestTaggedValueString(t *testing.T) {
+	var got TaggedValue
+	if err := got.UnmarshalJSON([]byte(`"2700K@400lm"`)); err != nil {
+		t.Fatalf("UnmarshalJSON() failed: %v", err)
+	}
+	if want := (BlackBodyFixed{Temperature: 2700, Luminance: 400}); got.Value != want {
+		t.Errorf("UnmarshalJSON() returned %#v, want %#v", got.Value, want)
+	}
+}
@@ -56,8 +56,8 @@ var (
 //
 // The valid temperature range is 4000K to 25000K.
 type StandardIlluminantDSeries struct {
-	Temperature float64 // Temperature in K.
-	Luminance   float64 // Luminance in lumen.
+	Temperature float64 `json:"temperature"` // Temperature in K.
+	Luminance   float64 `json:"luminance"`   // Luminance in lumen.
 }
 
 var _ Value = &StandardIlluminantDSeries{} // TODO: Implement transformation from DCS
@@ -5,6 +5,11 @@
 
 package emission
 
+import (
+	"fmt"
+	"math"
+)
+
 // Reuse TransformationLinDCSToXYZ type for transformation from color space with 3 channels.
 var standardRGBTransformation = TransformationLinDCSToXYZ{
 	CIE1931XYZRel{0.4124, 0.2126, 0.0193}.Absolute(1),
@@ -18,12 +23,28 @@ var standardRGBInvTransformation = standardRGBTransformation.MustInverted()
 // StandardRGB represents a color according to IEC 61966-2-1:1999.
 // Commonly known as sRGB.
 type StandardRGB struct {
-	R, G, B float64 // R, G and B are in the range of [0, 1].
+	R float64 `json:"r"` // R in the range of [0, 1].
+	G float64 `json:"g"` // G in the range of [0, 1].
+	B float64 `json:"b"` // B in the range of [0, 1].
 }
 
 // Check if type implements the RGB interface.
 var _ RGB = &StandardRGB{}
 
+// String returns the color in a form that can be parsed by ParseValue.
+// Colors that can be represented with 8 bit per channel are formatted as #rrggbb.
+func (c StandardRGB) String() string {
+	is8Bit := func(v float64) bool {
+		return v >= 0 && v <= 1 && math.Abs(math.Round(v*255)-v*255) < 1e-9
+	}
+
+	if is8Bit(c.R) && is8Bit(c.G) && is8Bit(c.B) {
+		return fmt.Sprintf("#%02x%02x%02x", int(math.Round(c.R*255)), int(math.Round(c.G*255)), int(math.Round(c.B*255)))
+	}
+
+	return "rgb(" + formatFloats(c.R*255, c.G*255, c.B*255) + ")"
+}
+
 // CIE1931XYZRel transforms the RGB color space into a CIE 1931 XYZ color space with relative luminance.
 // This implements the RGB interface.
 func (c StandardRGB) CIE1931XYZRel() CIE1931XYZRel {
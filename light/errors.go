@@ -0,0 +1,14 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import "errors"
+
+// ErrNotRepresentable is returned (wrapped) by GetColors if the current state of a light device can't be represented by emission values.
+// This is the case if a device plays some built-in dynamic scene, for example.
+//
+// Use errors.Is() to check for this error.
+var ErrNotRepresentable = errors.New("state can't be represented by emission values")
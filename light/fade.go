@@ -0,0 +1,154 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Easing maps the linear progress t of a transition in the range [0, 1] to the interpolation position.
+// An easing function should return 0 for t = 0 and 1 for t = 1.
+type Easing func(t float64) float64
+
+// Predefined easing functions.
+var (
+	EaseLinear    Easing = func(t float64) float64 { return t }
+	EaseInQuad    Easing = func(t float64) float64 { return t * t }
+	EaseOutQuad   Easing = func(t float64) float64 { return t * (2 - t) }
+	EaseInOutQuad Easing = func(t float64) float64 {
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return -1 + (4-2*t)*t
+	}
+	EaseInOutSine Easing = func(t float64) float64 { return (1 - math.Cos(math.Pi*t)) / 2 }
+)
+
+// FadeOptions contains the parameters of a fade.
+type FadeOptions struct {
+	// The color space that is used to interpolate between the start and target values.
+	// Defaults to InterpolationCIE1976LAB.
+	Space InterpolationSpace
+
+	// The easing function that is applied to the progress of the fade.
+	// Defaults to EaseLinear if nil.
+	Easing Easing
+
+	// The time between two updates that are sent to the light device.
+	// Defaults to 50 ms if zero.
+	Interval time.Duration
+}
+
+// Fade transitions the modules of the light device from the emission values in from to the emission values in to over the given duration.
+//
+// Every module is interpolated independently.
+// Like with SetColors, modules without a value in to are faded to off.
+// If from is nil, the current state is queried from the device with GetColors.
+// Otherwise modules without a value in from are faded in from off.
+//
+// This blocks until the target is reached, in which case nil is returned.
+// The exact target values are always set at the end of the fade, so there are no rounding errors.
+// If ctx is cancelled, the fade stops at the current intermediate state and ctx.Err() is returned.
+func Fade(ctx context.Context, l Light, from, to []emission.Value, duration time.Duration, options FadeOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if options.Easing == nil {
+		options.Easing = EaseLinear
+	}
+	if options.Interval <= 0 {
+		options.Interval = 50 * time.Millisecond
+	}
+
+	colorProfiles := l.ColorProfiles()
+	if len(to) > len(colorProfiles) {
+		return fmt.Errorf("got %d target emission values, this device has only %d modules", len(to), len(colorProfiles))
+	}
+	if len(from) > len(colorProfiles) {
+		return fmt.Errorf("got %d start emission values, this device has only %d modules", len(from), len(colorProfiles))
+	}
+
+	if from == nil {
+		vectors := make([]emission.DCSVector, len(colorProfiles))
+		receivers := make([]emission.ValueReceiver, 0, len(vectors))
+		for i := range vectors {
+			receivers = append(receivers, &vectors[i])
+		}
+		if err := l.GetColors(receivers...); err != nil {
+			return fmt.Errorf("failed to query current state: %w", err)
+		}
+		from = make([]emission.Value, 0, len(vectors))
+		for _, vector := range vectors {
+			from = append(from, vector)
+		}
+	}
+
+	// Determine start and target color of every module.
+	startXYZ := make([]emission.CIE1931XYZAbs, 0, len(colorProfiles))
+	targetXYZ := make([]emission.CIE1931XYZAbs, 0, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		start, err := moduleXYZ(colorProfile, from, i)
+		if err != nil {
+			return fmt.Errorf("failed to transform start value of module %d: %w", i, err)
+		}
+		target, err := moduleXYZ(colorProfile, to, i)
+		if err != nil {
+			return fmt.Errorf("failed to transform target value of module %d: %w", i, err)
+		}
+		startXYZ, targetXYZ = append(startXYZ, start), append(targetXYZ, target)
+	}
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	intermediate := make([]emission.Value, len(colorProfiles))
+	startTime := time.Now()
+	for {
+		t := 1.0
+		if duration > 0 {
+			t = float64(time.Since(startTime)) / float64(duration)
+		}
+		if t >= 1 {
+			break
+		}
+
+		position := options.Easing(t)
+		for i, colorProfile := range colorProfiles {
+			value, err := interpolateXYZ(colorProfile, startXYZ[i], targetXYZ[i], position, options.Space)
+			if err != nil {
+				return err
+			}
+			intermediate[i] = value
+		}
+		if err := l.SetColors(intermediate...); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	// Set the exact target values at the end.
+	return l.SetColors(to...)
+}
+
+// moduleXYZ returns the color of the module with the given index.
+// Modules without value are off.
+func moduleXYZ(colorProfile emission.ColorProfile, values []emission.Value, index int) (emission.CIE1931XYZAbs, error) {
+	if index >= len(values) {
+		return colorProfile.DCSToXYZ(make(emission.DCSVector, colorProfile.Channels()))
+	}
+
+	return colorProfile.DCSToXYZ(values[index].IntoDCS(colorProfile))
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func TestFade(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{History: true}, virtual.DefaultColorProfile, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	from := []emission.Value{emission.DCSVector{1, 0, 0}}
+	to := []emission.Value{emission.DCSVector{0, 0, 1}, emission.DCSVector{0, 1, 0}}
+	options := light.FadeOptions{Space: light.InterpolationCIE1931xyY, Easing: light.EaseInOutSine, Interval: time.Millisecond}
+	if err := light.Fade(context.Background(), l, from, to, 20*time.Millisecond, options); err != nil {
+		t.Fatalf("light.Fade() failed: %v", err)
+	}
+
+	history := l.History()
+	if len(history) < 3 {
+		t.Fatalf("History() returned %d entries, want at least %d", len(history), 3)
+	}
+	if history[0].Vectors[1].ComponentSum() > 0.01 {
+		t.Errorf("Second module starts at %v, want it to be turned off", history[0].Vectors[1])
+	}
+	last := history[len(history)-1].Vectors
+	if last[0][2] != 1 || last[1][1] != 1 {
+		t.Errorf("Fade ended at %v, want %v", last, to)
+	}
+
+	// A cancelled fade must not change anything.
+	l.ClearHistory()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := light.Fade(ctx, l, nil, from, time.Second, light.FadeOptions{}); err != context.Canceled {
+		t.Errorf("light.Fade() returned %v, want %v", err, context.Canceled)
+	}
+	if len(l.History()) != 0 {
+		t.Errorf("Cancelled fade changed the state of the light")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// InterpolationSpace describes the color space that is used to interpolate between two emission values.
+type InterpolationSpace int
+
+const (
+	// InterpolationCIE1976LAB interpolates in the CIE 1976 L*a*b* color space relative to the white point of the module.
+	// This is perceptually uniform and a good choice for most transitions.
+	InterpolationCIE1976LAB InterpolationSpace = iota
+
+	// InterpolationCIE1931xyY interpolates the chromaticity and the luminance linearly.
+	InterpolationCIE1931xyY
+
+	// InterpolationMired interpolates the correlated color temperature in mired, and the luminance linearly.
+	// This is a good choice for transitions between two whites, as the path follows the Planckian locus.
+	// Colors far away from the Planckian locus will be mapped onto it.
+	InterpolationMired
+)
+
+func (s InterpolationSpace) String() string {
+	switch s {
+	case InterpolationCIE1976LAB:
+		return "CIE1976LAB"
+	case InterpolationCIE1931xyY:
+		return "CIE1931xyY"
+	case InterpolationMired:
+		return "Mired"
+	}
+
+	return fmt.Sprintf("InterpolationSpace(%d)", int(s))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s InterpolationSpace) MarshalText() ([]byte, error) {
+	switch s {
+	case InterpolationCIE1976LAB, InterpolationCIE1931xyY, InterpolationMired:
+		return []byte(s.String()), nil
+	}
+
+	return nil, fmt.Errorf("unsupported interpolation space %d", int(s))
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *InterpolationSpace) UnmarshalText(text []byte) error {
+	for _, space := range []InterpolationSpace{InterpolationCIE1976LAB, InterpolationCIE1931xyY, InterpolationMired} {
+		if string(text) == space.String() {
+			*s = space
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown interpolation space %q", text)
+}
+
+// Interpolate returns the emission value between a and b at the position t in the range [0, 1].
+// t = 0 returns a color equal to a, t = 1 a color equal to b.
+//
+// Both values are transformed into the gamut of the given color profile first, so the result is always something that the module can output.
+func Interpolate(colorProfile emission.ColorProfile, a, b emission.Value, t float64, space InterpolationSpace) (emission.Value, error) {
+	aXYZ, err := colorProfile.DCSToXYZ(a.IntoDCS(colorProfile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %v: %w", a, err)
+	}
+	bXYZ, err := colorProfile.DCSToXYZ(b.IntoDCS(colorProfile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %v: %w", b, err)
+	}
+
+	return interpolateXYZ(colorProfile, aXYZ, bXYZ, t, space)
+}
+
+// interpolateXYZ returns the emission value between a and b at the position t in the range [0, 1].
+func interpolateXYZ(colorProfile emission.ColorProfile, a, b emission.CIE1931XYZAbs, t float64, space InterpolationSpace) (emission.Value, error) {
+	lerp := func(a, b float64) float64 { return a + (b-a)*t }
+
+	switch space {
+	case InterpolationCIE1976LAB:
+		whitePoint := colorProfile.WhitePoint()
+		whitePointRel := whitePoint.Relative(whitePoint.Y)
+		aLAB, bLAB := a.Relative(whitePoint.Y).CIE1976LAB(whitePointRel), b.Relative(whitePoint.Y).CIE1976LAB(whitePointRel)
+
+		return emission.CIE1976LAB{
+			L:          lerp(aLAB.L, bLAB.L),
+			A:          lerp(aLAB.A, bLAB.A),
+			B:          lerp(aLAB.B, bLAB.B),
+			WhitePoint: whitePointRel,
+		}, nil
+
+	case InterpolationCIE1931xyY:
+		axyY, bxyY := chromaticity(a, b), chromaticity(b, a)
+
+		return emission.CIE1931xyYAbs{
+			X:          lerp(axyY.X, bxyY.X),
+			Y:          lerp(axyY.Y, bxyY.Y),
+			LuminanceY: lerp(a.Y, b.Y),
+		}, nil
+
+	case InterpolationMired:
+		axyY, bxyY := chromaticity(a, b), chromaticity(b, a)
+		aMired, bMired := 1e6/correlatedColorTemperature(axyY), 1e6/correlatedColorTemperature(bxyY)
+
+		return emission.BlackBodyFixed{
+			Temperature: clampTemperature(1e6 / lerp(aMired, bMired)),
+			Luminance:   lerp(a.Y, b.Y),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported interpolation space %v", space)
+}
+
+// chromaticity returns the chromaticity of c.
+// If c is black and has no defined chromaticity, the chromaticity of the fallback color is used.
+// If both are black, the chromaticity of the equal-energy radiator is returned.
+func chromaticity(c, fallback emission.CIE1931XYZAbs) emission.CIE1931xyYAbs {
+	if sum := c.X + c.Y + c.Z; sum > 0 {
+		return c.CIE1931xyYAbs()
+	}
+	if sum := fallback.X + fallback.Y + fallback.Z; sum > 0 {
+		xyY := fallback.CIE1931xyYAbs()
+		xyY.LuminanceY = 0
+		return xyY
+	}
+	return emission.CIE1931xyYAbs{X: 1.0 / 3, Y: 1.0 / 3}
+}
+
+// correlatedColorTemperature returns an approximation of the correlated color temperature in K of the given chromaticity.
+//
+// This uses the approximation by McCamy (1992), which is accurate enough for the range of about 2000 K to 12500 K.
+func correlatedColorTemperature(c emission.CIE1931xyYAbs) float64 {
+	n := (c.X - 0.3320) / (0.1858 - c.Y)
+	return clampTemperature(449*n*n*n + 3525*n*n + 6823.3*n + 5520.33)
+}
+
+// clampTemperature clamps the given temperature into the valid range of emission.BlackBodyFixed.
+func clampTemperature(t float64) float64 {
+	return math.Min(math.Max(t, 1667), 25000)
+}
@@ -13,6 +13,7 @@ import "github.com/Dadido3/D3iot/light/emission"
 // A light device contains a number of modules, but at least 1.
 // A module is a set of light emitting things that together create a single color impression.
 //
+// Optional features like power state or built-in scenes are described by additional interfaces, see Switcher, Identifier, InfoProvider and SceneProvider.
 // If you need more control over the device, you can use a type assertion to get to the underlying object that implements this interface.
 type Light interface {
 	// SetColors sets the emission values of all the modules in the light device.
@@ -0,0 +1,278 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package lighttest contains a conformance test suite for implementations of the light.Light interface.
+//
+// Drivers can use it in their tests like this:
+//
+//	func TestConformance(t *testing.T) {
+//		lighttest.Run(t, func(t *testing.T) light.Light {
+//			return newTestLight(t)
+//		})
+//	}
+package lighttest
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Factory returns a new light device.
+// It is called once for every sub-test, use t.Cleanup() to free any resources.
+type Factory func(t *testing.T) light.Light
+
+// dcsTolerance is the maximum allowed difference of a DCS channel after a round trip.
+// This allows for quantization of about 7 bits, and for rounding errors.
+const dcsTolerance = 0.01
+
+// labTolerance is the maximum allowed ΔE* after a round trip.
+const labTolerance = 2.3
+
+// Run checks that the light devices returned by factory follow the contract of the light.Light interface.
+//
+// The following is checked:
+//
+//	- Devices have at least one module.
+//	- The number of color profiles equals the number of modules.
+//	- Setting or getting more values than there are modules returns an error.
+//	- Modules that are not set are turned off.
+//	- GetColors returns what was set via SetColors, within some tolerance.
+//	- Concurrent calls are safe.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Modules", func(t *testing.T) { testModules(t, factory(t)) })
+	t.Run("ColorProfiles", func(t *testing.T) { testColorProfiles(t, factory(t)) })
+	t.Run("TooManyValues", func(t *testing.T) { testTooManyValues(t, factory(t)) })
+	t.Run("UnsetModulesOff", func(t *testing.T) { testUnsetModulesOff(t, factory(t)) })
+	t.Run("RoundTripDCS", func(t *testing.T) { testRoundTripDCS(t, factory(t)) })
+	t.Run("RoundTripXYZ", func(t *testing.T) { testRoundTripXYZ(t, factory(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, factory(t)) })
+}
+
+func testModules(t *testing.T, l light.Light) {
+	if modules := l.Modules(); modules < 1 {
+		t.Errorf("Modules() returned %d, want at least 1", modules)
+	}
+}
+
+func testColorProfiles(t *testing.T, l light.Light) {
+	colorProfiles := l.ColorProfiles()
+	if len(colorProfiles) != l.Modules() {
+		t.Fatalf("ColorProfiles() returned %d profiles, want %d", len(colorProfiles), l.Modules())
+	}
+
+	for i, colorProfile := range colorProfiles {
+		if colorProfile == nil {
+			t.Errorf("Color profile of module %d is nil", i)
+			continue
+		}
+		if channels := colorProfile.Channels(); channels < 1 {
+			t.Errorf("Color profile of module %d has %d channels, want at least 1", i, channels)
+		}
+		if whitePoint := colorProfile.WhitePoint(); whitePoint.Y <= 0 {
+			t.Errorf("Color profile of module %d has a white point %v with non positive luminance", i, whitePoint)
+		}
+	}
+}
+
+func testTooManyValues(t *testing.T, l light.Light) {
+	modules := l.Modules()
+
+	values := make([]emission.Value, 0, modules+1)
+	for _, colorProfile := range l.ColorProfiles() {
+		values = append(values, make(emission.DCSVector, colorProfile.Channels()))
+	}
+	values = append(values, values[0])
+
+	if err := l.SetColors(values...); err == nil {
+		t.Errorf("SetColors() with %d values on a device with %d modules didn't return an error", len(values), modules)
+	}
+
+	receivers := make([]emission.ValueReceiver, 0, modules+1)
+	for i := 0; i < modules+1; i++ {
+		receivers = append(receivers, &emission.DCSVector{})
+	}
+
+	if err := l.GetColors(receivers...); err == nil {
+		t.Errorf("GetColors() with %d values on a device with %d modules didn't return an error", len(receivers), modules)
+	}
+}
+
+func testUnsetModulesOff(t *testing.T, l light.Light) {
+	colorProfiles := l.ColorProfiles()
+
+	// Turn all modules on, then set only the first module.
+	// All other modules must be turned off.
+	for setModules := len(colorProfiles); setModules >= 0; setModules-- {
+		values := make([]emission.Value, 0, len(colorProfiles))
+		for _, colorProfile := range colorProfiles {
+			values = append(values, halfVector(colorProfile))
+		}
+		if err := l.SetColors(values...); err != nil {
+			t.Fatalf("SetColors() failed: %v", err)
+		}
+
+		if err := l.SetColors(values[:setModules]...); err != nil {
+			t.Fatalf("SetColors() with %d values failed: %v", setModules, err)
+		}
+
+		vectors := getVectors(t, l)
+		for i, vector := range vectors[setModules:] {
+			module := setModules + i
+			if !vectorNear(vector, make(emission.DCSVector, colorProfiles[module].Channels())) {
+				t.Errorf("Module %d is not turned off after setting %d values. Got %v", module, setModules, vector)
+			}
+		}
+	}
+}
+
+func testRoundTripDCS(t *testing.T, l light.Light) {
+	colorProfiles := l.ColorProfiles()
+
+	// Test every channel on its own, and all channels together.
+	for channel := -1; channel < maxChannels(colorProfiles); channel++ {
+		values := make([]emission.Value, 0, len(colorProfiles))
+		wants := make([]emission.DCSVector, 0, len(colorProfiles))
+		for _, colorProfile := range colorProfiles {
+			vector := make(emission.DCSVector, colorProfile.Channels())
+			for i := range vector {
+				if channel < 0 {
+					vector[i] = 0.25
+				} else if i == channel {
+					vector[i] = 0.5
+				}
+			}
+			values = append(values, vector)
+			wants = append(wants, vector)
+		}
+
+		if err := l.SetColors(values...); err != nil {
+			t.Fatalf("SetColors() failed: %v", err)
+		}
+
+		for i, vector := range getVectors(t, l) {
+			if !vectorNear(vector, wants[i]) {
+				t.Errorf("Module %d returned %v, want %v", i, vector, wants[i])
+			}
+		}
+	}
+}
+
+func testRoundTripXYZ(t *testing.T, l light.Light) {
+	colorProfiles := l.ColorProfiles()
+
+	values := make([]emission.Value, 0, len(colorProfiles))
+	receivers := make([]emission.ValueReceiver, 0, len(colorProfiles))
+	results := make([]emission.CIE1931XYZAbs, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		values = append(values, colorProfile.WhitePoint().Scaled(0.5))
+		receivers = append(receivers, &results[i])
+	}
+
+	if err := l.SetColors(values...); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := l.GetColors(receivers...); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+
+	for i, colorProfile := range colorProfiles {
+		whitePoint := colorProfile.WhitePoint()
+		want := values[i].(emission.CIE1931XYZAbs)
+
+		dist := results[i].Relative(whitePoint.Y).CIE1976LABDistance(want.Relative(whitePoint.Y), whitePoint.Relative(whitePoint.Y))
+		if dist > labTolerance {
+			t.Errorf("Module %d returned %v, want %v. ΔE* = %f", i, results[i], want, dist)
+		}
+	}
+}
+
+func testConcurrency(t *testing.T, l light.Light) {
+	colorProfiles := l.ColorProfiles()
+
+	const goroutines, iterations = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*iterations)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				values := make([]emission.Value, 0, len(colorProfiles))
+				receivers := make([]emission.ValueReceiver, 0, len(colorProfiles))
+				for _, colorProfile := range colorProfiles {
+					values = append(values, colorProfile.WhitePoint().Scaled(float64(g+1)/goroutines))
+					receivers = append(receivers, &emission.DCSVector{})
+				}
+
+				if err := l.SetColors(values...); err != nil {
+					errs <- fmt.Errorf("SetColors() failed: %w", err)
+				}
+				if err := l.GetColors(receivers...); err != nil {
+					errs <- fmt.Errorf("GetColors() failed: %w", err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// getVectors queries all modules of l as DCS vectors.
+func getVectors(t *testing.T, l light.Light) []emission.DCSVector {
+	vectors := make([]emission.DCSVector, l.Modules())
+	receivers := make([]emission.ValueReceiver, 0, len(vectors))
+	for i := range vectors {
+		receivers = append(receivers, &vectors[i])
+	}
+
+	if err := l.GetColors(receivers...); err != nil {
+		t.Fatalf("GetColors() failed: %v", err)
+	}
+
+	return vectors
+}
+
+// halfVector returns a DCS vector with all channels set to 0.5.
+func halfVector(colorProfile emission.ColorProfile) emission.DCSVector {
+	vector := make(emission.DCSVector, colorProfile.Channels())
+	for i := range vector {
+		vector[i] = 0.5
+	}
+	return vector
+}
+
+// maxChannels returns the highest number of channels of all color profiles.
+func maxChannels(colorProfiles []emission.ColorProfile) int {
+	result := 0
+	for _, colorProfile := range colorProfiles {
+		if channels := colorProfile.Channels(); result < channels {
+			result = channels
+		}
+	}
+	return result
+}
+
+// vectorNear returns whether a and b have the same number of channels, and all channels are within the tolerance.
+func vectorNear(a, b emission.DCSVector) bool {
+	if a.Channels() != b.Channels() {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > dcsTolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Factory creates a light device from the given URI.
+//
+// The scheme of the URI is the one the factory was registered with.
+// Any driver specific options are passed as query parameters.
+type Factory func(ctx context.Context, uri *url.URL) (Light, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{}
+)
+
+// Register makes a light driver available by the given URI scheme.
+// Drivers usually call this in their init function, so importing a driver is enough to use it with Open().
+//
+// This panics if the factory is nil, or if Register is called twice with the same scheme.
+func Register(scheme string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if factory == nil {
+		panic("light: Register factory is nil")
+	}
+	if _, ok := factories[scheme]; ok {
+		panic(fmt.Sprintf("light: Register called twice for scheme %q", scheme))
+	}
+
+	factories[scheme] = factory
+}
+
+// Schemes returns a sorted list of all registered URI schemes.
+func Schemes() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Open connects to the light device described by the given URI.
+// The driver is chosen by the scheme of the URI, see Register().
+//
+//	device, err := light.Open(ctx, "wiz://192.168.1.123:38899?retries=3")
+func Open(ctx context.Context, uri string) (Light, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URI %q: %w", uri, err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("URI %q doesn't contain a scheme", uri)
+	}
+
+	factoriesMutex.RLock()
+	factory, ok := factories[u.Scheme]
+	factoriesMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme %q. Available schemes: %v", u.Scheme, Schemes())
+	}
+
+	return factory(ctx, u)
+}
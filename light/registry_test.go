@@ -0,0 +1,115 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+)
+
+var testSchemeCounter int32
+
+// testScheme returns a scheme that is not registered yet.
+// Registrations are global, so every test run needs its own schemes.
+func testScheme(name string) string {
+	return fmt.Sprintf("registry-test-%s-%d", name, atomic.AddInt32(&testSchemeCounter, 1))
+}
+
+// expectPanic fails the test if f doesn't panic.
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s didn't panic", name)
+		}
+	}()
+	f()
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(ctx context.Context, uri *url.URL) (light.Light, error) { return nil, nil }
+
+	duplicateScheme, nilScheme := testScheme("duplicate"), testScheme("nil")
+
+	light.Register(duplicateScheme, factory)
+
+	expectPanic(t, "Register() with a duplicate scheme", func() { light.Register(duplicateScheme, factory) })
+	expectPanic(t, "Register() with a nil factory", func() { light.Register(nilScheme, nil) })
+
+	found := false
+	for _, scheme := range light.Schemes() {
+		if scheme == nilScheme {
+			t.Errorf("Schemes() contains %q, which failed to register", scheme)
+		}
+		if scheme == duplicateScheme {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Schemes() doesn't contain %q", duplicateScheme)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	var gotURI *url.URL
+	var gotCtx context.Context
+	scheme := testScheme("open")
+	light.Register(scheme, func(ctx context.Context, uri *url.URL) (light.Light, error) {
+		gotCtx, gotURI = ctx, uri
+		return virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	})
+
+	device, err := light.Open(ctx, scheme+"://host:1234/path?option=1")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if device == nil {
+		t.Errorf("Open() returned a nil device")
+	}
+	if gotCtx == nil || gotCtx.Value(ctxKey{}) != "value" {
+		t.Errorf("Factory wasn't called with the given context")
+	}
+	if gotURI == nil || gotURI.Host != "host:1234" || gotURI.Path != "/path" || gotURI.Query().Get("option") != "1" {
+		t.Errorf("Factory was called with URI %v, want the parsed URI", gotURI)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	scheme := testScheme("errors")
+	light.Register(scheme, func(ctx context.Context, uri *url.URL) (light.Light, error) {
+		t.Errorf("Factory was called with %v", uri)
+		return nil, nil
+	})
+
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"Unknown scheme", "registry-test-unknown://host"},
+		{"Missing scheme", "host:1234"},
+		{"Empty URI", ""},
+		{"Bad URI", scheme + "://host:port:%zz"},
+		{"Bad scheme", "://host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := light.Open(context.Background(), tt.uri); err == nil {
+				t.Errorf("Open(%q) succeeded, want error", tt.uri)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package server serves light devices over a REST/JSON HTTP API.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// Event is sent to all subscribers of the event stream when the state of a device was changed through the server.
+type Event struct {
+	Device string                 `json:"device"`
+	Values []emission.TaggedValue `json:"values,omitempty"` // The new state of all modules as DCS vectors.
+	Error  string                 `json:"error,omitempty"`  // Set if a transition failed. The values may still be set, if the device could be set to the target state directly.
+}
+
+// device is a light device that is served by the server.
+type device struct {
+	name  string
+	light light.Light
+
+	fadeMutex  sync.Mutex
+	fadeCancel context.CancelFunc // Cancels the currently running transition. Nil if there is none.
+	fadeDone   chan struct{}      // Closed when the currently running transition has stopped.
+}
+
+// Server serves a set of light devices over HTTP.
+// It implements http.Handler, the endpoints are relative to where the server is mounted:
+//
+//	GET /devices                   Lists all devices.
+//	GET /devices/{name}            Returns the module count and color profiles of a device.
+//	GET /devices/{name}/colors     Returns the current colors. The query parameter space selects the color space (dcs, xyz, xyY, lab, srgb).
+//	PUT /devices/{name}/colors     Sets the colors, optionally with a transition.
+//	GET /events                    Streams state changes as Server-Sent Events.
+//
+// Colors are encoded with the tagged JSON encoding of the emission package.
+type Server struct {
+	mutex   sync.RWMutex
+	devices map[string]*device
+
+	subscribersMutex sync.Mutex
+	subscribers      map[chan Event]struct{}
+}
+
+// New returns a server without any devices.
+func New() *Server {
+	return &Server{
+		devices:     make(map[string]*device),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Add makes the given light device available under the given name.
+// Names must be unique and must not contain slashes.
+func (s *Server) Add(name string, l light.Light) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid device name %q", name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.devices[name]; ok {
+		return fmt.Errorf("device %q already exists", name)
+	}
+	s.devices[name] = &device{name: name, light: l}
+
+	return nil
+}
+
+// Names returns the names of all devices in alphabetical order.
+func (s *Server) Names() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, 0, len(s.devices))
+	for name := range s.devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// device returns the device with the given name, or nil.
+func (s *Server) device(name string) *device {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.devices[name]
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "devices":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{http.MethodGet: s.handleListDevices})
+
+	case len(parts) == 2 && parts[0] == "devices":
+		d := s.device(parts[1])
+		if d == nil {
+			http.Error(w, fmt.Sprintf("device %q not found", parts[1]), http.StatusNotFound)
+			return
+		}
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.handleGetDevice(w, r, d) },
+		})
+
+	case len(parts) == 3 && parts[0] == "devices" && parts[2] == "colors":
+		d := s.device(parts[1])
+		if d == nil {
+			http.Error(w, fmt.Sprintf("device %q not found", parts[1]), http.StatusNotFound)
+			return
+		}
+		s.handleMethods(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { s.handleGetColors(w, r, d) },
+			http.MethodPut: func(w http.ResponseWriter, r *http.Request) { s.handleSetColors(w, r, d) },
+		})
+
+	case len(parts) == 1 && parts[0] == "events":
+		s.handleMethods(w, r, map[string]http.HandlerFunc{http.MethodGet: s.handleEvents})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleMethods calls the handler that matches the request method.
+func (s *Server) handleMethods(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		methods := make([]string, 0, len(handlers))
+		for method := range handlers {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	handler(w, r)
+}
+
+// writeJSON writes v as JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// DeviceSummary is the response of the device list endpoint.
+type DeviceSummary struct {
+	Name    string `json:"name"`
+	Modules int    `json:"modules"`
+}
+
+func (s *Server) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	result := []DeviceSummary{}
+	for _, name := range s.Names() {
+		if d := s.device(name); d != nil {
+			result = append(result, DeviceSummary{Name: name, Modules: d.light.Modules()})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ColorProfile is the JSON representation of the color profile of a module.
+type ColorProfile struct {
+	Channels      int                      `json:"channels"`
+	WhitePoint    emission.CIE1931XYZAbs   `json:"whitePoint"`
+	ChannelPoints []emission.CIE1931XYZAbs `json:"channelPoints"`
+}
+
+// DeviceDetails is the response of the device endpoint.
+type DeviceDetails struct {
+	Name          string         `json:"name"`
+	Modules       int            `json:"modules"`
+	ColorProfiles []ColorProfile `json:"colorProfiles"`
+}
+
+func (s *Server) handleGetDevice(w http.ResponseWriter, r *http.Request, d *device) {
+	result := DeviceDetails{
+		Name:    d.name,
+		Modules: d.light.Modules(),
+	}
+	for _, colorProfile := range d.light.ColorProfiles() {
+		result.ColorProfiles = append(result.ColorProfiles, ColorProfile{
+			Channels:      colorProfile.Channels(),
+			WhitePoint:    colorProfile.WhitePoint(),
+			ChannelPoints: colorProfile.ChannelPoints(),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Colors is the request and response body of the colors endpoint.
+type Colors struct {
+	Values []emission.TaggedValue `json:"values"`
+
+	// Only used when setting colors.
+	Transition string                   `json:"transition,omitempty"` // Duration of the transition, e.g. "1.5s". No transition if empty.
+	Space      light.InterpolationSpace `json:"space,omitempty"`      // The interpolation space of the transition.
+}
+
+// newReceiver returns an empty value receiver of the given color space.
+func newReceiver(space string) (emission.ValueReceiver, bool) {
+	switch space {
+	case "", "dcs":
+		return &emission.DCSVector{}, true
+	case "xyz":
+		return &emission.CIE1931XYZAbs{}, true
+	case "xyY":
+		return &emission.CIE1931xyYAbs{}, true
+	case "lab":
+		return &emission.CIE1976LAB{}, true
+	case "srgb":
+		return &emission.StandardRGB{}, true
+	}
+
+	return nil, false
+}
+
+func (s *Server) handleGetColors(w http.ResponseWriter, r *http.Request, d *device) {
+	space := r.URL.Query().Get("space")
+
+	receivers := make([]emission.ValueReceiver, 0, d.light.Modules())
+	for i := 0; i < d.light.Modules(); i++ {
+		receiver, ok := newReceiver(space)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported color space %q", space), http.StatusBadRequest)
+			return
+		}
+		receivers = append(receivers, receiver)
+	}
+
+	if err := d.light.GetColors(receivers...); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get colors: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	result := Colors{Values: make([]emission.TaggedValue, 0, len(receivers))}
+	for _, receiver := range receivers {
+		// The receivers are pointers, MarshalValue dereferences them.
+		result.Values = append(result.Values, emission.TaggedValue{Value: receiver.(emission.Value)})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleSetColors(w http.ResponseWriter, r *http.Request, d *device) {
+	var request Colors
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	values := make([]emission.Value, 0, len(request.Values))
+	for i, value := range request.Values {
+		if value.Value == nil {
+			http.Error(w, fmt.Sprintf("value %d is null", i), http.StatusBadRequest)
+			return
+		}
+		values = append(values, value.Value)
+	}
+	if len(values) > d.light.Modules() {
+		http.Error(w, fmt.Sprintf("got %d values, device %q has only %d modules", len(values), d.name, d.light.Modules()), http.StatusBadRequest)
+		return
+	}
+
+	var transition time.Duration
+	if request.Transition != "" {
+		var err error
+		if transition, err = time.ParseDuration(request.Transition); err != nil {
+			http.Error(w, fmt.Sprintf("invalid transition: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	d.fadeMutex.Lock()
+	defer d.fadeMutex.Unlock()
+
+	// Stop any running transition, and wait until it doesn't write to the device anymore.
+	if d.fadeCancel != nil {
+		d.fadeCancel()
+		<-d.fadeDone
+		d.fadeCancel, d.fadeDone = nil, nil
+	}
+
+	if transition <= 0 {
+		if err := d.light.SetColors(values...); err != nil {
+			http.Error(w, fmt.Sprintf("failed to set colors: %v", err), http.StatusBadGateway)
+			return
+		}
+		s.publish(d, values)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	d.fadeCancel, d.fadeDone = cancel, done
+	go func() {
+		defer close(done)
+
+		err := light.Fade(ctx, d.light, nil, values, transition, light.FadeOptions{Space: request.Space})
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			s.publishError(d, fmt.Errorf("transition failed: %w", err))
+
+			// The start state may not be representable, so just jump to the target.
+			if err := d.light.SetColors(values...); err != nil {
+				s.publishError(d, fmt.Errorf("failed to set colors: %w", err))
+				return
+			}
+		}
+		s.publish(d, values)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// publish sends an event with the given values to all subscribers.
+func (s *Server) publish(d *device, values []emission.Value) {
+	event := Event{Device: d.name}
+	for i, colorProfile := range d.light.ColorProfiles() {
+		var vector emission.DCSVector
+		if i < len(values) {
+			vector = values[i].IntoDCS(colorProfile)
+		} else {
+			vector = make(emission.DCSVector, colorProfile.Channels())
+		}
+		event.Values = append(event.Values, emission.TaggedValue{Value: vector})
+	}
+
+	s.send(event)
+}
+
+// publishError sends an event with the given error to all subscribers.
+func (s *Server) publishError(d *device, err error) {
+	s.send(Event{Device: d.name, Error: err.Error()})
+}
+
+// send sends the given event to all subscribers.
+func (s *Server) send(event Event) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Drop events for slow subscribers.
+		}
+	}
+}
+
+// Subscribe returns a channel that receives all state changes that are made through the server.
+// Call the returned function to unsubscribe.
+// Events are dropped if the channel is not read fast enough.
+func (s *Server) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	s.subscribersMutex.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subscribersMutex.Unlock()
+
+	return ch, func() {
+		s.subscribersMutex.Lock()
+		delete(s.subscribers, ch)
+		s.subscribersMutex.Unlock()
+	}
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
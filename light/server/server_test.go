@@ -0,0 +1,237 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *virtual.Light) {
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	s := New()
+	if err := s.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := s.Add("desk", l); err == nil {
+		t.Errorf("Add() with duplicate name returned no error")
+	}
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	return ts, l
+}
+
+func request(t *testing.T, method, url, body string, wantStatus int) []byte {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest() failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	var buf strings.Builder
+	bufio.NewReader(resp.Body).WriteTo(&buf)
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s returned status %d, want %d: %s", method, url, resp.StatusCode, wantStatus, buf.String())
+	}
+
+	return []byte(buf.String())
+}
+
+func TestDevices(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	var list []DeviceSummary
+	if err := json.Unmarshal(request(t, http.MethodGet, ts.URL+"/devices", "", http.StatusOK), &list); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "desk" || list[0].Modules != 2 {
+		t.Errorf("Device list is %v", list)
+	}
+
+	var details DeviceDetails
+	if err := json.Unmarshal(request(t, http.MethodGet, ts.URL+"/devices/desk", "", http.StatusOK), &details); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if len(details.ColorProfiles) != 2 || details.ColorProfiles[0].Channels != 3 || len(details.ColorProfiles[0].ChannelPoints) != 3 {
+		t.Errorf("Device details are %v", details)
+	}
+
+	request(t, http.MethodGet, ts.URL+"/devices/unknown", "", http.StatusNotFound)
+	request(t, http.MethodPost, ts.URL+"/devices", "", http.StatusMethodNotAllowed)
+}
+
+func TestColors(t *testing.T) {
+	ts, l := newTestServer(t)
+
+	request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["#ff0000", {"type":"dcs","value":[0,0,1]}]}`, http.StatusNoContent)
+	if vectors := l.Vectors(); vectors[0][0] < 0.99 || vectors[1][2] != 1 {
+		t.Errorf("Light is %v after setting colors", vectors)
+	}
+
+	var colors Colors
+	if err := json.Unmarshal(request(t, http.MethodGet, ts.URL+"/devices/desk/colors?space=srgb", "", http.StatusOK), &colors); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if rgb, ok := colors.Values[0].Value.(emission.StandardRGB); !ok || rgb.R < 0.99 || rgb.G > 0.01 {
+		t.Errorf("Got color %#v, want red in sRGB", colors.Values[0].Value)
+	}
+
+	request(t, http.MethodGet, ts.URL+"/devices/desk/colors?space=unknown", "", http.StatusBadRequest)
+	request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["#ff0000","#ff0000","#ff0000"]}`, http.StatusBadRequest)
+	request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["notacolor"]}`, http.StatusBadRequest)
+}
+
+func TestTransitionAndEvents(t *testing.T) {
+	ts, l := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("http.Get() failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Event stream has content type %q", contentType)
+	}
+
+	request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["dcs(0,1,0)"], "transition":"50ms", "space":"CIE1931xyY"}`, http.StatusAccepted)
+
+	// Wait for the event that signals the end of the transition.
+	events := make(chan Event)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data: "); data != scanner.Text() {
+				var event Event
+				if json.Unmarshal([]byte(data), &event) == nil {
+					events <- event
+				}
+			}
+		}
+	}()
+
+	select {
+	case event := <-events:
+		if event.Device != "desk" || len(event.Values) != 2 {
+			t.Errorf("Got event %v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Got no event")
+	}
+
+	if vectors := l.Vectors(); vectors[0][1] != 1 {
+		t.Errorf("Light is %v after the transition", vectors)
+	}
+}
+
+// readEvents returns a channel that receives all events of the event stream of the given test server.
+func readEvents(t *testing.T, ts *httptest.Server) <-chan Event {
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("http.Get() failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan Event, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data: "); data != scanner.Text() {
+				var event Event
+				if json.Unmarshal([]byte(data), &event) == nil {
+					events <- event
+				}
+			}
+		}
+	}()
+
+	return events
+}
+
+func TestTransitionReplaced(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{Latency: time.Millisecond}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	s := New()
+	if err := s.Add("desk", l); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for i := 0; i < 10; i++ {
+		request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["dcs(0,1,0)"], "transition":"10s"}`, http.StatusAccepted)
+		time.Sleep(5 * time.Millisecond)
+		request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["dcs(1,0,0)"]}`, http.StatusNoContent)
+
+		// The replaced transition must not write anything after the new values were set.
+		time.Sleep(5 * time.Millisecond)
+		if vectors := l.Vectors(); vectors[0][0] != 1 || vectors[0][1] != 0 {
+			t.Fatalf("Light is %v after the transition was replaced", vectors)
+		}
+	}
+}
+
+// unreadableLight is a light device whose state can't be queried.
+type unreadableLight struct {
+	*virtual.Light
+}
+
+func (u unreadableLight) GetColors(emissionValues ...emission.ValueReceiver) error {
+	return fmt.Errorf("can't read state")
+}
+
+func TestTransitionError(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+	s := New()
+	if err := s.Add("desk", unreadableLight{l}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close) // Registered before the event stream, so the stream is closed first.
+
+	events := readEvents(t, ts)
+	request(t, http.MethodPut, ts.URL+"/devices/desk/colors", `{"values":["dcs(0,1,0)"], "transition":"50ms"}`, http.StatusAccepted)
+
+	// The failed transition is reported, and the device is set to the target state directly.
+	for _, wantError := range []bool{true, false} {
+		select {
+		case event := <-events:
+			if gotError := event.Error != ""; gotError != wantError {
+				t.Errorf("Got event %v, want error %v", event, wantError)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Got no event")
+		}
+	}
+
+	if vectors := l.Vectors(); vectors[0][1] != 1 {
+		t.Errorf("Light is %v after the failed transition", vectors)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// NativeStater can be implemented by light devices that can capture and restore their state in a driver specific format.
+// This allows to restore state that can't be represented by emission values, like scenes that run on the device.
+type NativeStater interface {
+	NativeState() (json.RawMessage, error)          // NativeState returns the current state of the device in a driver specific JSON format.
+	RestoreNativeState(state json.RawMessage) error // RestoreNativeState sets the device to a state that was previously returned by NativeState.
+}
+
+// LightState contains the captured state of a single light device.
+type LightState struct {
+	Vectors []emission.DCSVector `json:"vectors,omitempty"` // The emission values of all modules. Nil if the state can't be represented by emission values.
+	Native  json.RawMessage      `json:"native,omitempty"`  // Driver specific state. Nil if the driver doesn't implement NativeStater.
+}
+
+// State is a snapshot of the state of several light devices.
+// It can be serialized to JSON.
+type State struct {
+	Lights []LightState `json:"lights"`
+
+	lights []Light // The light devices the state was captured from.
+}
+
+// Snapshot captures the state of all the given light devices concurrently.
+//
+// Drivers that implement NativeStater also store their native state, which is preferred when restoring.
+// If any device fails, or if ctx is cancelled, an error is returned.
+func Snapshot(ctx context.Context, lights ...Light) (*State, error) {
+	state := &State{
+		Lights: make([]LightState, len(lights)),
+		lights: append([]Light(nil), lights...),
+	}
+
+	err := forEachLight(ctx, lights, func(i int, l Light) error {
+		s, err := captureLight(l)
+		state.Lights[i] = s
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// captureLight returns the state of a single light device.
+func captureLight(l Light) (LightState, error) {
+	var state LightState
+
+	if nativeStater, ok := l.(NativeStater); ok {
+		native, err := nativeStater.NativeState()
+		if err != nil {
+			return LightState{}, fmt.Errorf("failed to capture native state: %w", err)
+		}
+		state.Native = native
+	}
+
+	vectors := make([]emission.DCSVector, l.Modules())
+	receivers := make([]emission.ValueReceiver, 0, len(vectors))
+	for i := range vectors {
+		receivers = append(receivers, &vectors[i])
+	}
+
+	if err := l.GetColors(receivers...); err != nil {
+		// The native state is enough, if there is one.
+		if state.Native != nil && errors.Is(err, ErrNotRepresentable) {
+			return state, nil
+		}
+		return LightState{}, err
+	}
+	state.Vectors = vectors
+
+	return state, nil
+}
+
+// Restore sets all light devices back to the captured state.
+//
+// This only works on snapshots that were returned by Snapshot.
+// Use RestoreTo for snapshots that were loaded from JSON.
+func (s *State) Restore(ctx context.Context) error {
+	if len(s.lights) != len(s.Lights) {
+		return fmt.Errorf("snapshot is not bound to any light devices, use RestoreTo instead")
+	}
+
+	return s.RestoreTo(ctx, s.lights...)
+}
+
+// RestoreTo sets the given light devices to the captured state.
+// The light devices have to be given in the same order as they were passed to Snapshot.
+//
+// The native state is used if there is one and the device supports it, otherwise the emission values are used.
+func (s *State) RestoreTo(ctx context.Context, lights ...Light) error {
+	if len(lights) != len(s.Lights) {
+		return fmt.Errorf("got %d light devices, the snapshot contains %d", len(lights), len(s.Lights))
+	}
+
+	return forEachLight(ctx, lights, func(i int, l Light) error {
+		return s.Lights[i].restore(l)
+	})
+}
+
+// restore sets the given light device to the captured state.
+func (s LightState) restore(l Light) error {
+	if nativeStater, ok := l.(NativeStater); ok && s.Native != nil {
+		return nativeStater.RestoreNativeState(s.Native)
+	}
+
+	if s.Vectors == nil {
+		return fmt.Errorf("snapshot contains no emission values: %w", ErrNotRepresentable)
+	}
+
+	values := make([]emission.Value, 0, len(s.Vectors))
+	for _, vector := range s.Vectors {
+		values = append(values, vector)
+	}
+
+	return l.SetColors(values...)
+}
+
+// forEachLight calls f concurrently for every light device.
+// This returns the first error in device order, or ctx.Err() if ctx is cancelled before all calls are done.
+//
+// Calls that haven't started when ctx is cancelled are skipped.
+// Calls that are already running can't be interrupted, they are waited for before this returns.
+func forEachLight(ctx context.Context, lights []Light, f func(i int, l Light) error) error {
+	errs := make([]error, len(lights))
+
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l Light) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = f(i, l)
+		}(i, l)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("light %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
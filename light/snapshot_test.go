@@ -0,0 +1,137 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+func TestSnapshot(t *testing.T) {
+	l1, l2 := newVirtualLight(t, 1), newVirtualLight(t, 2)
+
+	red, blue := emission.DCSVector{1, 0, 0}, emission.DCSVector{0, 0, 1}
+	if err := l1.SetColors(red); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	if err := l2.SetColors(blue, red); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	snapshot, err := light.Snapshot(context.Background(), l1, l2)
+	if err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+
+	l1.SetColors()
+	l2.SetColors()
+	if err := snapshot.Restore(context.Background()); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if !vectorIs(l1, red)() || !vectorIs(l2, blue)() {
+		t.Errorf("Lights are %v and %v, want %v and %v", l1.Vectors(), l2.Vectors(), red, blue)
+	}
+
+	// Snapshots loaded from JSON have to be restored to explicit devices.
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	var loaded light.State
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if err := loaded.Restore(context.Background()); err == nil {
+		t.Errorf("Restore() of an unbound snapshot returned no error")
+	}
+	if err := loaded.RestoreTo(context.Background(), l1); err == nil {
+		t.Errorf("RestoreTo() with the wrong number of devices returned no error")
+	}
+
+	l1.SetColors()
+	l2.SetColors()
+	if err := loaded.RestoreTo(context.Background(), l1, l2); err != nil {
+		t.Fatalf("RestoreTo() failed: %v", err)
+	}
+	if !vectorIs(l1, red)() || !vectorIs(l2, blue)() {
+		t.Errorf("Lights are %v and %v, want %v and %v", l1.Vectors(), l2.Vectors(), red, blue)
+	}
+}
+
+func TestSnapshotPartialFailure(t *testing.T) {
+	l1, l2 := newVirtualLight(t, 1), &failingLight{Light: newVirtualLight(t, 1)}
+
+	red := emission.DCSVector{1, 0, 0}
+	if err := l1.SetColors(red); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	snapshot, err := light.Snapshot(context.Background(), l1, l2)
+	if err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+
+	// The failing device is reported, the other devices are restored anyway.
+	l1.SetColors()
+	l2.mutex.Lock()
+	l2.fail = true
+	l2.mutex.Unlock()
+	err = snapshot.Restore(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "light 1:") {
+		t.Errorf("Restore() returned %v, want an error of light 1", err)
+	}
+	if !vectorIs(l1, red)() {
+		t.Errorf("Light is %v, want %v", l1.Vectors(), red)
+	}
+}
+
+func TestSnapshotCancel(t *testing.T) {
+	l, err := virtual.NewLight(virtual.Options{Latency: 50 * time.Millisecond}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	red := emission.DCSVector{1, 0, 0}
+	if err := l.SetColors(red); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	snapshot, err := light.Snapshot(context.Background(), l)
+	if err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+	l.SetColors()
+
+	// Cancelled contexts don't start any calls.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := snapshot.Restore(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Restore() returned %v, want %v", err, context.Canceled)
+	}
+	if _, err := light.Snapshot(ctx, l); !errors.Is(err, context.Canceled) {
+		t.Errorf("Snapshot() returned %v, want %v", err, context.Canceled)
+	}
+	if vectorIs(l, red)() {
+		t.Errorf("Restore() with a cancelled context changed the light")
+	}
+
+	// Running calls are waited for, nothing is written to the device afterwards.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := snapshot.Restore(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Restore() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !vectorIs(l, red)() {
+		t.Errorf("Light is %v after Restore() returned, want %v", l.Vectors(), red)
+	}
+}
@@ -6,40 +6,60 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"image"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/Dadido3/D3iot/light"
-	"github.com/Dadido3/D3iot/light/drivers/wiz"
+	_ "github.com/Dadido3/D3iot/light/drivers/all"
 	"github.com/Dadido3/D3iot/light/emission"
 	"golang.org/x/image/draw"
 )
 
-var flagDeviceWiZ = flag.String("device-wiz", "wiz-d47cf3:38899", "The address of the WiZ device to be profiled. Example: \"--device-wiz wiz-123abc:38899\" or \"--device-wiz 192.168.1.123:38899\".")
+// deviceList is a flag value that can be set several times.
+type deviceList []string
+
+func (d *deviceList) String() string {
+	return fmt.Sprint(*d)
+}
+
+func (d *deviceList) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+var flagDevices deviceList
 var flagMaxLuminance = flag.Float64("max-luminance", 0, "The maximum luminance that will be output for a fully white screen in lumens.")
 var flagNoWhiteOptimization = flag.Bool("no-white-optimization", false, "Disables optimization for high CRI and high luminance by disabling white emitters. This may help to get a lower latency with some light devices, due to weaker low-pass filtering in the light device's primary color emitters.")
 var flagBrighten = flag.Float64("brighten", 1, "Brightens up all colors by the given factor.")
 
 func main() {
+	flag.Var(&flagDevices, "device", "The URI of a light device to be controlled. Can be used several times to control multiple devices. Example: \"--device wiz://wiz-123abc:38899\" or \"--device wiz://192.168.1.123:38899?retries=3\".")
 	flag.Parse()
 
-	// Connect to light device.
-	var light light.Light
-	switch {
-	case *flagDeviceWiZ != "":
-		var err error
-		if light, err = wiz.NewLight(*flagDeviceWiZ); err != nil {
-			log.Printf("wiz.NewLight(%q) failed: %v", *flagDeviceWiZ, err)
+	// Connect to light devices.
+	if len(flagDevices) == 0 {
+		log.Printf("No device URI given. Start program with the \"--device\" parameter set.")
+		log.Printf("Example: bias-light --device wiz://wiz-123abc:38899")
+		return
+	}
+	var devices []light.Light
+	for _, uri := range flagDevices {
+		device, err := light.Open(context.Background(), uri)
+		if err != nil {
+			log.Printf("light.Open(%q) failed: %v", uri, err)
 			return
 		}
-
-	default:
-		log.Printf("No device address given. Start program with any \"--device-...\" parameter set.")
-		log.Printf("Example: bias-light --device-wiz wiz-123abc:38899")
-		return
+		devices = append(devices, device)
 	}
+	log.Printf("Controlling %s.", strings.Join(flagDevices, ", "))
+
+	// Treat all devices as a single device with many modules.
+	light := light.Compose(devices...)
 
 	for {
 		srcImg, err := takeScreenshot()
@@ -74,8 +94,12 @@ func main() {
 			emissionValue = emission.NoWhiteOptimization{EmissionValue: emissionValue}
 		}
 
-		// Set the first module's color.
-		light.SetColors(emissionValue) // TODO: Set all available modules.
+		// Set the color of all modules.
+		emissionValues := make([]emission.Value, light.Modules())
+		for i := range emissionValues {
+			emissionValues[i] = emissionValue
+		}
+		light.SetColors(emissionValues...)
 
 		time.Sleep(20 * time.Millisecond)
 	}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Dadido3/D3iot/light"
+	_ "github.com/Dadido3/D3iot/light/drivers/all"
+	"github.com/Dadido3/D3iot/light/server"
+)
+
+// deviceList is a flag value that can be set several times.
+type deviceList []string
+
+func (d *deviceList) String() string {
+	return fmt.Sprint(*d)
+}
+
+func (d *deviceList) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+var flagDevices deviceList
+var flagListen = flag.String("listen", "127.0.0.1:8080", "The address the HTTP server listens on.")
+
+func main() {
+	flag.Var(&flagDevices, "device", "A light device in the form of name=URI. Can be used several times. Example: \"--device desk=wiz://wiz-123abc:38899\".")
+	flag.Parse()
+
+	if len(flagDevices) == 0 {
+		log.Printf("No device given. Start program with the \"--device\" parameter set.")
+		log.Printf("Example: lightd --device desk=wiz://wiz-123abc:38899")
+		return
+	}
+
+	s := server.New()
+	for _, device := range flagDevices {
+		parts := strings.SplitN(device, "=", 2)
+		if len(parts) != 2 {
+			log.Printf("Invalid device %q, it must be in the form of name=URI.", device)
+			return
+		}
+		name, uri := parts[0], parts[1]
+
+		l, err := light.Open(context.Background(), uri)
+		if err != nil {
+			log.Printf("light.Open(%q) failed: %v", uri, err)
+			return
+		}
+		if err := s.Add(name, l); err != nil {
+			log.Printf("Failed to add device: %v", err)
+			return
+		}
+		log.Printf("Serving %q as %q.", uri, name)
+	}
+
+	http.Handle("/api/", http.StripPrefix("/api", s))
+
+	log.Printf("Listening on http://%s/api/", *flagListen)
+	if err := http.ListenAndServe(*flagListen, nil); err != nil {
+		log.Printf("http.ListenAndServe() failed: %v", err)
+	}
+}
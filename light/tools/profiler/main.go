@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"flag"
@@ -15,14 +16,14 @@ import (
 	"net/http"
 
 	"github.com/Dadido3/D3iot/light"
-	"github.com/Dadido3/D3iot/light/drivers/wiz"
+	_ "github.com/Dadido3/D3iot/light/drivers/all"
 	"github.com/Dadido3/D3iot/light/emission"
 )
 
 //go:embed static
 var staticFiles embed.FS
 
-var flagDeviceWiZ = flag.String("device-wiz", "wiz-d47cf3:38899", "The address of the WiZ device to be profiled. Example: \"--device-wiz wiz-123abc:38899\" or \"--device-wiz 192.168.1.123:38899\".")
+var flagDevice = flag.String("device", "wiz://wiz-d47cf3:38899", "The URI of the light device to be profiled. Example: \"--device wiz://wiz-123abc:38899\" or \"--device wiz://192.168.1.123:38899?retries=3\".")
 var flagServerPort = flag.Int("server-port", 8081, "The server port. Example: \"--server-port 8081\".")
 var flagModuleNumber = flag.Int("module", 0, "Number of the module that we want to profile.")
 var flagMaxLuminance = flag.Float64("max-luminance", 1521, "The maximum luminance value in lumen that the light can output.")
@@ -31,18 +32,14 @@ func main() {
 	flag.Parse()
 
 	// Connect to light device.
-	var light light.Light
-	switch {
-	case *flagDeviceWiZ != "":
-		var err error
-		if light, err = wiz.NewLight(*flagDeviceWiZ); err != nil {
-			log.Printf("wiz.NewLight(%q) failed: %v", *flagDeviceWiZ, err)
-			return
-		}
-
-	default:
-		log.Printf("No device address given. Start program with any \"--device-...\" parameter set.")
-		log.Printf("Example: profiler --device-wiz wiz-123abc:38899")
+	if *flagDevice == "" {
+		log.Printf("No device URI given. Start program with the \"--device\" parameter set.")
+		log.Printf("Example: profiler --device wiz://wiz-123abc:38899")
+		return
+	}
+	light, err := light.Open(context.Background(), *flagDevice)
+	if err != nil {
+		log.Printf("light.Open(%q) failed: %v", *flagDevice, err)
 		return
 	}
 
@@ -44,7 +44,7 @@ var api = (function () {
                 type: "POST",
                 url: "/api/addDataPoint",
                 cache: false,
-                data: JSON.stringify({LinDCSVector: dcsVector, L: labColor.L, A: labColor.A, B: labColor.B}),
+                data: JSON.stringify({LinDCSVector: dcsVector, l: labColor.l, a: labColor.a, b: labColor.b}),
                 contentType: 'application/json',
             });
         },
@@ -0,0 +1,248 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// WatchdogOptions contains the parameters of a Watchdog.
+type WatchdogOptions struct {
+	// The time between two state checks.
+	// Defaults to 5 seconds if zero.
+	PollInterval time.Duration
+
+	// The maximum allowed difference of any DCS channel between the last set and the queried state.
+	// Any bigger difference is seen as a change of state.
+	// Defaults to 0.02 if zero, which is enough to ignore rounding and quantization errors of most devices.
+	Tolerance float64
+
+	// If true, changes to the light state that were made by someone else (e.g. by an app or a remote) are kept.
+	// The watchdog will then stop to enforce the last state until SetColors is called again.
+	//
+	// Changes that are detected after a reboot are never seen as manual changes, so the last state is re-applied in any case.
+	// A reboot is detected if the device was offline, or if the uptime of devices that implement Uptimer went backwards.
+	// Devices without Uptimer that are power cycled between two checks can't be distinguished from manual changes.
+	ManualChangesWin bool
+}
+
+// Watchdog wraps a light device and remembers the last emission values that were sent through SetColors.
+//
+// The watchdog regularly queries the device state.
+// If the device rebooted (e.g. after it was power cycled by a wall switch), or if the state differs from the last set state, the last state is re-applied.
+//
+// Use Close() to stop the watchdog.
+type Watchdog struct {
+	light   Light
+	options WatchdogOptions
+
+	mutex      sync.Mutex
+	lastValues []emission.DCSVector // The last set state in DCS. Nil if there is nothing to enforce.
+	online     bool                 // The result of the last state check.
+	uptime     *time.Duration       // The uptime of the last state check. Nil if unknown.
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Check implementation of Light.
+var _ Light = &Watchdog{}
+
+// NewWatchdog returns a watchdog that wraps the given light device.
+// The watchdog starts polling the device immediately.
+func NewWatchdog(l Light, options WatchdogOptions) *Watchdog {
+	if options.PollInterval <= 0 {
+		options.PollInterval = 5 * time.Second
+	}
+	if options.Tolerance <= 0 {
+		options.Tolerance = 0.02
+	}
+
+	w := &Watchdog{
+		light:   l,
+		options: options,
+		online:  true,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// run polls the device until the watchdog is closed.
+func (w *Watchdog) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check queries the device state once, and re-applies the last state if necessary.
+func (w *Watchdog) check() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	rebooted := !w.online
+
+	// Detect restarts via the uptime, if the device supports it.
+	if uptimer, ok := w.light.(Uptimer); ok {
+		uptime, err := uptimer.Uptime()
+		if err != nil {
+			// Treat any error as the device being offline.
+			w.online = false
+			return
+		}
+		if w.uptime != nil && uptime < *w.uptime {
+			rebooted = true
+		}
+		w.uptime = &uptime
+	}
+
+	vectors := make([]emission.DCSVector, w.light.Modules())
+	receivers := make([]emission.ValueReceiver, 0, len(vectors))
+	for i := range vectors {
+		receivers = append(receivers, &vectors[i])
+	}
+
+	err := w.light.GetColors(receivers...)
+	if err != nil && !errors.Is(err, ErrNotRepresentable) {
+		// Treat any other error as the device being offline.
+		w.online = false
+		return
+	}
+
+	w.online = true
+
+	if w.lastValues == nil {
+		return
+	}
+
+	// A state that can't be represented by emission values never matches.
+	if err == nil && w.matches(vectors) {
+		return
+	}
+
+	if rebooted || !w.options.ManualChangesWin {
+		// Re-apply the last state. If this fails, the device is seen as offline, so it will be retried with the next check.
+		if err := w.setColors(w.lastValues); err != nil {
+			w.online = false
+		}
+		return
+	}
+
+	// The manual change wins, stop enforcing the last state.
+	w.lastValues = nil
+}
+
+// matches returns whether the given vectors match with the last set state.
+func (w *Watchdog) matches(vectors []emission.DCSVector) bool {
+	for i, last := range w.lastValues {
+		if i >= len(vectors) || vectors[i].Channels() != last.Channels() {
+			return false
+		}
+		for j, channel := range last {
+			if math.Abs(channel-vectors[i][j]) > w.options.Tolerance {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// setColors forwards the given vectors to the wrapped light device.
+func (w *Watchdog) setColors(vectors []emission.DCSVector) error {
+	values := make([]emission.Value, 0, len(vectors))
+	for _, vector := range vectors {
+		values = append(values, vector)
+	}
+
+	return w.light.SetColors(values...)
+}
+
+// Close stops the watchdog.
+// This will not change the state of the light device.
+// Calling Close more than once has no effect.
+func (w *Watchdog) Close() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Online returns false if the last state check failed.
+func (w *Watchdog) Online() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.online
+}
+
+// Unwrap returns the wrapped light device.
+func (w *Watchdog) Unwrap() Light {
+	return w.light
+}
+
+// SetColors sets the emission values of all the modules in the light device.
+// Values which are not set are assumed to equal a turned off module.
+// This will return an error if you try to set more values than there are modules in a light device.
+//
+// The values are remembered, so they can be re-applied later.
+func (w *Watchdog) SetColors(emissionValues ...emission.Value) error {
+	colorProfiles := w.light.ColorProfiles()
+	if len(emissionValues) > len(colorProfiles) {
+		return fmt.Errorf("got %d emission values, this device has only %d modules", len(emissionValues), len(colorProfiles))
+	}
+
+	// Transform everything into DCS, including the implicitly turned off modules.
+	vectors := make([]emission.DCSVector, 0, len(colorProfiles))
+	for i, colorProfile := range colorProfiles {
+		if i < len(emissionValues) {
+			vectors = append(vectors, emissionValues[i].IntoDCS(colorProfile))
+		} else {
+			vectors = append(vectors, make(emission.DCSVector, colorProfile.Channels()))
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.lastValues = vectors
+
+	return w.setColors(vectors)
+}
+
+// GetColors queries the light device for all emission values of its modules and writes them back into the given list emissionValues.
+// This will return an error if you try to get more values than there are modules in a light device.
+func (w *Watchdog) GetColors(emissionValues ...emission.ValueReceiver) error {
+	return w.light.GetColors(emissionValues...)
+}
+
+// Modules returns the number of modules.
+func (w *Watchdog) Modules() int {
+	return w.light.Modules()
+}
+
+// ColorProfiles returns the color profiles of every module in this device.
+func (w *Watchdog) ColorProfiles() []emission.ColorProfile {
+	return w.light.ColorProfiles()
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2022 David Vogel
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package light_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dadido3/D3iot/light"
+	"github.com/Dadido3/D3iot/light/drivers/virtual"
+	"github.com/Dadido3/D3iot/light/emission"
+)
+
+// rebootingLight is a virtual light that can be power cycled and taken offline.
+type rebootingLight struct {
+	*virtual.Light
+
+	mutex   sync.Mutex
+	started time.Time
+	offline bool
+}
+
+var _ light.Uptimer = &rebootingLight{}
+
+func newRebootingLight(t *testing.T) *rebootingLight {
+	l, err := virtual.NewLight(virtual.Options{}, virtual.DefaultColorProfile)
+	if err != nil {
+		t.Fatalf("virtual.NewLight() failed: %v", err)
+	}
+
+	return &rebootingLight{Light: l, started: time.Now()}
+}
+
+// reboot simulates a power cycle, after which the device is in its power-on state.
+func (r *rebootingLight) reboot() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.started = time.Now()
+	r.Light.SetColors(emission.DCSVector{1, 1, 1})
+}
+
+func (r *rebootingLight) setOffline(offline bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.offline = offline
+}
+
+func (r *rebootingLight) Uptime() (time.Duration, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.offline {
+		return 0, fmt.Errorf("device is offline")
+	}
+	return time.Since(r.started), nil
+}
+
+func (r *rebootingLight) GetColors(emissionValues ...emission.ValueReceiver) error {
+	r.mutex.Lock()
+	offline := r.offline
+	r.mutex.Unlock()
+
+	if offline {
+		return fmt.Errorf("device is offline")
+	}
+	return r.Light.GetColors(emissionValues...)
+}
+
+// waitFor polls cond until it returns true, or fails the test after a deadline.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// vectorIs returns a function that checks whether the first module of l has the given DCS vector.
+func vectorIs(l *virtual.Light, want emission.DCSVector) func() bool {
+	return func() bool {
+		v := l.Vectors()[0]
+		for i := range want {
+			if v[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestWatchdogReboot(t *testing.T) {
+	l := newRebootingLight(t)
+	w := light.NewWatchdog(l, light.WatchdogOptions{PollInterval: time.Millisecond, ManualChangesWin: true})
+	defer w.Close()
+
+	want := emission.DCSVector{0.5, 0.25, 0}
+	if err := w.SetColors(want); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+	waitFor(t, "a few checks", func() bool { time.Sleep(10 * time.Millisecond); return true })
+
+	// The reboot is detected by the uptime, even though the device never went offline.
+	l.reboot()
+	waitFor(t, "the last state to be re-applied after a reboot", vectorIs(l.Light, want))
+}
+
+func TestWatchdogOffline(t *testing.T) {
+	l := newRebootingLight(t)
+	w := light.NewWatchdog(l, light.WatchdogOptions{PollInterval: time.Millisecond, ManualChangesWin: true})
+	defer w.Close()
+
+	want := emission.DCSVector{0, 0.5, 0}
+	if err := w.SetColors(want); err != nil {
+		t.Fatalf("SetColors() failed: %v", err)
+	}
+
+	l.setOffline(true)
+	waitFor(t, "the device to be seen offline", func() bool { return !w.Online() })
+
+	// Change the state while the device is offline, without resetting the uptime.
+	l.Light.SetColors(emission.DCSVector{1, 1, 1})
+	l.setOffline(false)
+	waitFor(t, "the last state to be re-applied after the device came back", vectorIs(l.Light, want))
+}
+
+func TestWatchdogManualChanges(t *testing.T) {
+	for _, manualChangesWin := range []bool{false, true} {
+		l := newRebootingLight(t)
+		w := light.NewWatchdog(l, light.WatchdogOptions{PollInterval: time.Millisecond, ManualChangesWin: manualChangesWin})
+
+		want := emission.DCSVector{0, 0, 0.5}
+		if err := w.SetColors(want); err != nil {
+			t.Fatalf("SetColors() failed: %v", err)
+		}
+
+		manual := emission.DCSVector{0.75, 0, 0}
+		l.Light.SetColors(manual)
+
+		if manualChangesWin {
+			// Let the watchdog see the change, then check that it's kept.
+			time.Sleep(20 * time.Millisecond)
+			if !vectorIs(l.Light, manual)() {
+				t.Errorf("Manual change was overridden, got %v", l.Light.Vectors())
+			}
+
+			// A reboot after the manual change is not enforced, as there is nothing to enforce anymore.
+			l.reboot()
+			time.Sleep(20 * time.Millisecond)
+			if !vectorIs(l.Light, emission.DCSVector{1, 1, 1})() {
+				t.Errorf("Watchdog enforced a state after the manual change won, got %v", l.Light.Vectors())
+			}
+		} else {
+			waitFor(t, "the manual change to be overridden", vectorIs(l.Light, want))
+		}
+
+		w.Close()
+	}
+}
+
+func TestWatchdogClose(t *testing.T) {
+	w := light.NewWatchdog(newRebootingLight(t), light.WatchdogOptions{})
+	w.Close()
+	w.Close()
+}